@@ -0,0 +1,244 @@
+// Package app wires the API together. An App holds the dependencies that
+// handlers and background workers share, built once from the
+// configuration, and builds the router and workers from them. Nothing is
+// kept in package globals, so tests can build an App around fakes.
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"gorm.io/gorm"
+
+	"github.com/TobiAdeniji94/ecommerce_api/config"
+	"github.com/TobiAdeniji94/ecommerce_api/controllers"
+	"github.com/TobiAdeniji94/ecommerce_api/health"
+	"github.com/TobiAdeniji94/ecommerce_api/inventory"
+	"github.com/TobiAdeniji94/ecommerce_api/logging"
+	"github.com/TobiAdeniji94/ecommerce_api/metrics"
+	"github.com/TobiAdeniji94/ecommerce_api/middleware"
+	"github.com/TobiAdeniji94/ecommerce_api/notifications"
+	"github.com/TobiAdeniji94/ecommerce_api/outbox"
+	"github.com/TobiAdeniji94/ecommerce_api/payments"
+	"github.com/TobiAdeniji94/ecommerce_api/refunds"
+	"github.com/TobiAdeniji94/ecommerce_api/repository"
+	"github.com/TobiAdeniji94/ecommerce_api/routes"
+	"github.com/TobiAdeniji94/ecommerce_api/tax"
+	"github.com/TobiAdeniji94/ecommerce_api/tracing"
+	"github.com/TobiAdeniji94/ecommerce_api/utils"
+	"github.com/TobiAdeniji94/ecommerce_api/webhooks"
+)
+
+// App is the application container.
+type App struct {
+	Config   *config.Config
+	DB       *gorm.DB
+	Store    *repository.Store
+	Tokens   utils.TokenService
+	Payments payments.Provider
+	Tax      tax.Calculator
+	Sender   notifications.Sender
+	Bus      *outbox.Bus
+	Sinks    []outbox.Sink
+	Clock    utils.Clock
+	// Inventory applies stock changes with the configured low-stock
+	// threshold and allocation strategy.
+	Inventory *inventory.Service
+	// Log is the application log; requests log through a child of it.
+	Log *slog.Logger
+	// Health runs the readiness checks. Dependencies register their own
+	// checks; the database is always checked.
+	Health *health.Checker
+	// Metrics is nil when metrics are disabled.
+	Metrics *metrics.Metrics
+	// Tracer traces requests; call its Shutdown to flush buffered spans.
+	Tracer tracing.Provider
+}
+
+// New builds an App from cfg on an open database. Webhook deliveries are
+// queued from the App's bus when webhooks are enabled.
+func New(cfg *config.Config, db *gorm.DB) (*App, error) {
+	store, err := repository.New(db)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := payments.New(cfg.Payments)
+	if err != nil {
+		return nil, fmt.Errorf("configuring payments: %w", err)
+	}
+	calculator, err := tax.New(cfg.Tax)
+	if err != nil {
+		return nil, fmt.Errorf("configuring tax rates: %w", err)
+	}
+	sender, err := notifications.NewSender(cfg.Notifications)
+	if err != nil {
+		return nil, fmt.Errorf("configuring notifications: %w", err)
+	}
+	sinks, err := outbox.NewSinks(cfg.Outbox)
+	if err != nil {
+		return nil, fmt.Errorf("configuring the outbox: %w", err)
+	}
+	stock, err := inventory.New(cfg.Inventory)
+	if err != nil {
+		return nil, fmt.Errorf("configuring inventory: %w", err)
+	}
+	if err := inventory.Setup(db); err != nil {
+		return nil, fmt.Errorf("configuring inventory: %w", err)
+	}
+	tracer, err := tracing.New(cfg.Tracing)
+	if err != nil {
+		return nil, fmt.Errorf("configuring tracing: %w", err)
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, fmt.Errorf("configuring tracing: %w", err)
+	}
+	if stripe, ok := provider.(*payments.Stripe); ok {
+		stripe.HTTPClient.Transport = &tracing.Transport{Base: stripe.HTTPClient.Transport}
+	}
+
+	clock := utils.SystemClock{}
+	a := &App{
+		Config:    cfg,
+		DB:        db,
+		Store:     store,
+		Tokens:    utils.NewJWT(cfg.JWT, clock),
+		Payments:  provider,
+		Tax:       calculator,
+		Sender:    sender,
+		Bus:       outbox.NewBus(),
+		Sinks:     sinks,
+		Clock:     clock,
+		Inventory: stock,
+		Log:       slog.Default(),
+		Health:    health.New(cfg.Health.CheckTimeout),
+		Tracer:    tracer,
+	}
+	a.Health.Register("database", func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+	if cfg.Features.Metrics {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, fmt.Errorf("configuring metrics: %w", err)
+		}
+		dbName := cfg.Database.Name
+		if cfg.Database.Driver == "sqlite" {
+			dbName = "sqlite"
+		}
+		a.Metrics = metrics.New(sqlDB, dbName)
+	}
+	if cfg.Features.Webhooks {
+		webhooks.Register(a.Bus, db)
+	}
+	return a, nil
+}
+
+// Handler returns the API handlers, sharing the App's dependencies.
+func (a *App) Handler() *controllers.Handler {
+	return &controllers.Handler{
+		DB:        a.DB,
+		Store:     a.Store,
+		Tokens:    a.Tokens,
+		Payments:  a.Payments,
+		Tax:       a.Tax,
+		Clock:     a.Clock,
+		Inventory: a.Inventory,
+		Log:       a.Log,
+		Health:    a.Health,
+		Metrics:   a.Metrics,
+		Currency:  strings.ToLower(a.Config.Payments.Currency),
+	}
+}
+
+// Router returns the HTTP handler serving the API with its middleware.
+func (a *App) Router() http.Handler {
+	r := gin.New()
+	h := a.Handler()
+
+	// Request IDs, then the request span, then the request's logger and
+	// access log, so all three cover the whole request
+	r.Use(logging.RequestID(), tracing.Middleware(a.Tracer), logging.Middleware(a.Log, "/healthz", "/readyz", "/metrics"))
+
+	// Request metrics come next so rejected requests are counted too
+	if a.Metrics != nil {
+		r.Use(a.Metrics.Middleware())
+	}
+
+	// Panics become 500s inside the span and metrics
+	r.Use(logging.Recovery())
+
+	if a.Metrics != nil {
+		r.GET("/metrics", middleware.StaticToken(a.Config.Metrics.Token), gin.WrapH(a.Metrics.Handler()))
+	}
+
+	// Probes: liveness and readiness for load balancers and orchestrators.
+	// They are registered before the rate limiter, which would otherwise
+	// fail them whenever the prober's address is throttled.
+	r.GET("/healthz", h.Healthz)
+	r.GET("/readyz", h.Readyz)
+
+	// CORS middleware
+	r.Use(cors.New(cors.Config{
+		AllowOrigins:     a.Config.CORS.AllowedOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowHeaders:     []string{"Authorization", "Content-Type", "If-Match", "If-None-Match", "Idempotency-Key", "traceparent", "tracestate", logging.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", "ETag", "Idempotent-Replayed", logging.RequestIDHeader},
+		AllowCredentials: a.Config.CORS.AllowCredentials,
+		MaxAge:           a.Config.CORS.MaxAge,
+	}))
+
+	// Rate Limiting middleware
+	if a.Config.RateLimit.Enabled {
+		r.Use(utils.PerClientRateLimiter(a.Config.RateLimit, a.Metrics.RateLimited))
+	}
+
+	// Swagger docs
+	if a.Config.Features.Swagger {
+		r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	}
+
+	routes.InitializeRoutes(r, a.Config, h)
+	return r
+}
+
+// RunWorkers starts the background workers, which relay outbox events,
+// retry pending refunds and deliver queued notifications and webhooks
+// until ctx is canceled. It does
+// nothing when workers are disabled.
+func (a *App) RunWorkers(ctx context.Context) {
+	if !a.Config.Features.Workers {
+		return
+	}
+
+	relay := &outbox.Relay{DB: a.DB, Bus: a.Bus, Sinks: a.Sinks, PollInterval: a.Config.Outbox.PollInterval}
+	go relay.Run(ctx)
+
+	refunder := &refunds.Worker{DB: a.DB, Provider: a.Payments}
+	go refunder.Run(ctx)
+
+	purger := &middleware.IdempotencyPurger{DB: a.DB, Clock: a.Clock}
+	go purger.Run(ctx)
+
+	notifier := &notifications.Worker{DB: a.DB, Sender: a.Sender, PollInterval: a.Config.Notifications.PollInterval}
+	go notifier.Run(ctx)
+
+	if a.Config.Features.Webhooks {
+		deliverer := &webhooks.Worker{
+			DB:           a.DB,
+			Client:       &http.Client{Timeout: a.Config.Webhooks.Timeout},
+			PollInterval: a.Config.Webhooks.PollInterval,
+		}
+		go deliverer.Run(ctx)
+	}
+}
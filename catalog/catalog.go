@@ -0,0 +1,110 @@
+// Package catalog filters, imports and exports products in bulk.
+package catalog
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/tax"
+)
+
+// Filter narrows a product listing. Zero values match everything.
+type Filter struct {
+	Category string
+	SKU      string
+	Query    string
+	MinPrice *float64
+	MaxPrice *float64
+	InStock  *bool
+}
+
+// Apply adds the filter's conditions to a product query.
+func (f Filter) Apply(db *gorm.DB) *gorm.DB {
+	if f.Category != "" {
+		db = db.Where("category = ?", f.Category)
+	}
+	if f.SKU != "" {
+		db = db.Where("sku = ?", f.SKU)
+	}
+	if f.Query != "" {
+		pattern := "%" + strings.ToLower(f.Query) + "%"
+		db = db.Where("LOWER(name) LIKE ? OR LOWER(description) LIKE ?", pattern, pattern)
+	}
+	if f.MinPrice != nil {
+		db = db.Where("price >= ?", *f.MinPrice)
+	}
+	if f.MaxPrice != nil {
+		db = db.Where("price <= ?", *f.MaxPrice)
+	}
+	if f.InStock != nil {
+		if *f.InStock {
+			db = db.Where("stock > 0")
+		} else {
+			db = db.Where("stock = 0")
+		}
+	}
+	return db
+}
+
+// ApplyPatch copies the fields set in patch onto product, leaving the rest
+// unchanged. A zero release date clears it, and empty tax classes and
+// backorder modes fall back to their defaults.
+func ApplyPatch(product *models.Product, patch models.ProductPatchInput) {
+	if patch.SKU != nil {
+		product.SKU = strings.TrimSpace(*patch.SKU)
+	}
+	if patch.Name != nil {
+		product.Name = *patch.Name
+	}
+	if patch.Description != nil {
+		product.Description = *patch.Description
+	}
+	if patch.Category != nil {
+		product.Category = *patch.Category
+	}
+	if patch.TaxClass != nil {
+		product.TaxClass = *patch.TaxClass
+	}
+	if patch.Price != nil {
+		product.Price = *patch.Price
+	}
+	if patch.Stock != nil {
+		product.Stock = *patch.Stock
+	}
+	if patch.LowStockThreshold != nil {
+		product.LowStockThreshold = *patch.LowStockThreshold
+	}
+	if patch.BackorderMode != nil {
+		product.BackorderMode = *patch.BackorderMode
+	}
+	if patch.ReleaseDate != nil {
+		product.ReleaseDate = patch.ReleaseDate
+		if patch.ReleaseDate.IsZero() {
+			product.ReleaseDate = nil
+		}
+	}
+	if patch.MaxBackorder != nil {
+		product.MaxBackorder = *patch.MaxBackorder
+	}
+	if patch.Weight != nil {
+		product.Weight = *patch.Weight
+	}
+	if patch.Length != nil {
+		product.Length = *patch.Length
+	}
+	if patch.Width != nil {
+		product.Width = *patch.Width
+	}
+	if patch.Height != nil {
+		product.Height = *patch.Height
+	}
+
+	if product.TaxClass == "" {
+		product.TaxClass = tax.DefaultTaxClass
+	}
+	if product.BackorderMode == "" {
+		product.BackorderMode = models.BackorderNone
+	}
+}
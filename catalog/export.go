@@ -0,0 +1,143 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// exportBatchSize is the number of products read from the database at once.
+const exportBatchSize = 500
+
+// flusher is implemented by writers that buffer output, such as HTTP
+// response writers.
+type flusher interface {
+	Flush()
+}
+
+// hasSKU matches products with a SKU. Imports match rows to products by
+// SKU, so products without one are left out of exports.
+const hasSKU = "COALESCE(sku, '') <> ''"
+
+// WithoutSKU counts the products matching filter that Export leaves out
+// because they have no SKU.
+func WithoutSKU(db *gorm.DB, filter Filter) (int64, error) {
+	var count int64
+	err := filter.Apply(db.Model(&models.Product{})).Where("NOT (" + hasSKU + ")").Count(&count).Error
+	return count, err
+}
+
+// Export writes the products matching filter to w, one batch at a time so
+// large catalogs are never held in memory. The output can be imported again;
+// products without a SKU are skipped, see WithoutSKU.
+func Export(db *gorm.DB, w io.Writer, format string, filter Filter) error {
+	var write func(models.Product) error
+	var csvWriter *csv.Writer
+	switch format {
+	case FormatCSV:
+		csvWriter = csv.NewWriter(w)
+		if err := csvWriter.Write(Columns); err != nil {
+			return err
+		}
+		write = func(product models.Product) error {
+			return csvWriter.Write(csvRecord(product))
+		}
+	case FormatNDJSON:
+		encoder := json.NewEncoder(w)
+		write = func(product models.Product) error {
+			return encoder.Encode(exportObject(product))
+		}
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+
+	var products []models.Product
+	var writeErr error
+	result := filter.Apply(db.Model(&models.Product{})).Where(hasSKU).
+		FindInBatches(&products, exportBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, product := range products {
+				if writeErr = write(product); writeErr != nil {
+					return writeErr
+				}
+			}
+			if csvWriter != nil {
+				csvWriter.Flush()
+				if writeErr = csvWriter.Error(); writeErr != nil {
+					return writeErr
+				}
+			}
+			if f, ok := w.(flusher); ok {
+				f.Flush()
+			}
+			return nil
+		})
+	if writeErr != nil {
+		return writeErr
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if csvWriter != nil {
+		csvWriter.Flush()
+		return csvWriter.Error()
+	}
+	return nil
+}
+
+// csvRecord formats a product as a row in Columns order.
+func csvRecord(product models.Product) []string {
+	releaseDate := ""
+	if product.ReleaseDate != nil {
+		releaseDate = product.ReleaseDate.UTC().Format(time.RFC3339)
+	}
+	return []string{
+		product.SKU,
+		product.Name,
+		product.Description,
+		product.Category,
+		product.TaxClass,
+		formatFloat(product.Price),
+		strconv.Itoa(product.Stock),
+		strconv.Itoa(product.LowStockThreshold),
+		product.BackorderMode,
+		releaseDate,
+		strconv.Itoa(product.MaxBackorder),
+		formatFloat(product.Weight),
+		formatFloat(product.Length),
+		formatFloat(product.Width),
+		formatFloat(product.Height),
+	}
+}
+
+// exportObject formats a product as an NDJSON object with Columns keys.
+func exportObject(product models.Product) map[string]interface{} {
+	return map[string]interface{}{
+		"sku":                 product.SKU,
+		"name":                product.Name,
+		"description":         product.Description,
+		"category":            product.Category,
+		"tax_class":           product.TaxClass,
+		"price":               product.Price,
+		"stock":               product.Stock,
+		"low_stock_threshold": product.LowStockThreshold,
+		"backorder_mode":      product.BackorderMode,
+		"release_date":        product.ReleaseDate,
+		"max_backorder":       product.MaxBackorder,
+		"weight":              product.Weight,
+		"length":              product.Length,
+		"width":               product.Width,
+		"height":              product.Height,
+	}
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,392 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/TobiAdeniji94/ecommerce_api/inventory"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/outbox"
+)
+
+// File formats for import and export.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// DefaultBatchSize is the number of rows committed per transaction.
+const DefaultBatchSize = 500
+
+// Columns lists the product fields in CSV column order. NDJSON objects use
+// the same keys.
+var Columns = []string{
+	"sku", "name", "description", "category", "tax_class", "price", "stock",
+	"low_stock_threshold", "backorder_mode", "release_date", "max_backorder",
+	"weight", "length", "width", "height",
+}
+
+// numericColumns are written to JSON without quotes.
+var numericColumns = map[string]bool{
+	"price": true, "stock": true, "low_stock_threshold": true, "max_backorder": true,
+	"weight": true, "length": true, "width": true, "height": true,
+}
+
+// ErrInvalidFile wraps errors in the structure of an import file, such as
+// an unknown CSV column or a line that is too long.
+var ErrInvalidFile = errors.New("invalid file")
+
+// errDryRun rolls back a batch after a dry run.
+var errDryRun = errors.New("dry run")
+
+// ImportOptions configures an import.
+type ImportOptions struct {
+	Format    string
+	DryRun    bool
+	BatchSize int
+	ActorID   *uuid.UUID
+}
+
+// RowError describes why one row was not imported.
+type RowError struct {
+	Row     int    `json:"row"`
+	SKU     string `json:"sku,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report summarises an import. In a dry run the counts are what would have
+// happened; nothing is saved.
+type Report struct {
+	DryRun  bool       `json:"dry_run"`
+	Created int        `json:"created"`
+	Updated int        `json:"updated"`
+	Failed  int        `json:"failed"`
+	Errors  []RowError `json:"errors"`
+}
+
+// row is one decoded input line.
+type row struct {
+	number int
+	sku    string
+	patch  models.ProductPatchInput
+}
+
+// Import reads products from r and upserts them by SKU. Only the fields
+// present in a row are changed; new products need a name and price. Rows
+// are saved in batches, each in its own transaction, and a row that fails
+// is reported without affecting the others. Errors in the file structure,
+// such as an unknown CSV column, abort the import. Stock is set through inv.
+func Import(db *gorm.DB, inv *inventory.Service, r io.Reader, opts ImportOptions) (*Report, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	var next func() (map[string]json.RawMessage, int, error)
+	switch opts.Format {
+	case FormatCSV:
+		reader, err := newCSVReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+		}
+		next = reader.next
+	case FormatNDJSON:
+		next = newNDJSONReader(r).next
+	default:
+		return nil, fmt.Errorf("unsupported format %q", opts.Format)
+	}
+
+	report := &Report{DryRun: opts.DryRun, Errors: []RowError{}}
+	batch := make([]row, 0, opts.BatchSize)
+	for {
+		fields, number, err := next()
+		if err == io.EOF {
+			break
+		}
+		var invalid *invalidRowError
+		if errors.As(err, &invalid) {
+			report.fail(number, "", err)
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+		}
+
+		decoded, err := decodeRow(fields)
+		if err != nil {
+			report.fail(number, decoded.sku, err)
+			continue
+		}
+		decoded.number = number
+
+		batch = append(batch, decoded)
+		if len(batch) == opts.BatchSize {
+			if err := importBatch(db, inv, batch, opts, report); err != nil {
+				return report, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err := importBatch(db, inv, batch, opts, report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// fail records a row error.
+func (r *Report) fail(number int, sku string, err error) {
+	r.Failed++
+	r.Errors = append(r.Errors, RowError{Row: number, SKU: sku, Message: err.Error()})
+}
+
+// importBatch upserts a batch in one transaction. Each row runs in a
+// savepoint so a failing row is rolled back on its own.
+func importBatch(db *gorm.DB, inv *inventory.Service, batch []row, opts ImportOptions, report *Report) error {
+	var created, updated int
+	var failures []RowError
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, r := range batch {
+			savepoint := "import_row_" + strconv.Itoa(r.number)
+			if err := tx.SavePoint(savepoint).Error; err != nil {
+				return err
+			}
+			isNew, err := upsert(tx, inv, r, opts.ActorID)
+			if err != nil {
+				if rollbackErr := tx.RollbackTo(savepoint).Error; rollbackErr != nil {
+					return rollbackErr
+				}
+				failures = append(failures, RowError{Row: r.number, SKU: r.sku, Message: err.Error()})
+				continue
+			}
+			if isNew {
+				created++
+			} else {
+				updated++
+			}
+		}
+
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return err
+	}
+
+	report.Created += created
+	report.Updated += updated
+	report.Failed += len(failures)
+	report.Errors = append(report.Errors, failures...)
+	return nil
+}
+
+// upsert creates or updates the product with the row's SKU and sets its
+// stock through the inventory ledger. Updates publish product.updated.
+func upsert(tx *gorm.DB, inv *inventory.Service, r row, actorID *uuid.UUID) (bool, error) {
+	var product models.Product
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("sku = ?", r.sku).Limit(1).Find(&product).Error
+	if err != nil {
+		return false, err
+	}
+
+	isNew := product.ID == uuid.Nil
+	if isNew && (r.patch.Name == nil || r.patch.Price == nil) {
+		return false, errors.New("name and price are required for new products")
+	}
+
+	previousStock := product.Stock
+	ApplyPatch(&product, r.patch)
+	product.SKU = r.sku
+	stock := product.Stock
+	product.Stock = previousStock
+
+	adj := inventory.Adjustment{
+		Type:    models.StockMovementAdjustment,
+		Reason:  "Catalog import",
+		ActorID: actorID,
+	}
+	if isNew {
+		adj.Type = models.StockMovementReceipt
+		if err := tx.Create(&product).Error; err != nil {
+			return false, err
+		}
+	} else {
+		product.Version++
+		err := tx.Model(&product).Select("*").Omit("id", "stock", "backordered", "rating_average", "rating_count", "rating_total", "created_at").Updates(&product).Error
+		if err != nil {
+			return false, err
+		}
+	}
+
+	adj.ProductID = product.ID
+	if err := inv.SetStock(tx, adj, previousStock, stock); err != nil {
+		return false, fmt.Errorf("setting stock: %w", err)
+	}
+	if isNew {
+		return true, nil
+	}
+
+	if err := tx.Select("stock", "backordered", "version").First(&product, "id = ?", product.ID).Error; err != nil {
+		return false, err
+	}
+	return false, outbox.Record(tx, outbox.AggregateProduct, product.ID, outbox.EventProductUpdated, product)
+}
+
+// decodeRow turns the fields of one line into a validated row.
+func decodeRow(fields map[string]json.RawMessage) (row, error) {
+	var r row
+	if raw, ok := fields["sku"]; ok {
+		if err := json.Unmarshal(raw, &r.sku); err != nil {
+			return r, errors.New("sku must be a string")
+		}
+		r.sku = strings.TrimSpace(r.sku)
+		delete(fields, "sku")
+	}
+	if r.sku == "" {
+		return r, errors.New("sku is required")
+	}
+	if len(r.sku) > 64 {
+		return r, errors.New("sku must be at most 64 characters")
+	}
+
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return r, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&r.patch); err != nil {
+		return r, err
+	}
+	if err := binding.Validator.ValidateStruct(&r.patch); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// invalidRowError marks a line that cannot be read; it fails that row only.
+type invalidRowError struct {
+	message string
+}
+
+func (e *invalidRowError) Error() string {
+	return e.message
+}
+
+// csvReader yields CSV rows as JSON fields keyed by the header.
+type csvReader struct {
+	reader *csv.Reader
+	header []string
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(Columns))
+	for _, column := range Columns {
+		known[column] = true
+	}
+	for i, column := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(column))
+		if !known[header[i]] {
+			return nil, fmt.Errorf("unknown CSV column %q", column)
+		}
+	}
+
+	reader.FieldsPerRecord = len(header)
+	return &csvReader{reader: reader, header: header}, nil
+}
+
+// next returns the next row and its line number. Empty cells are left out
+// so the field keeps its current value.
+func (r *csvReader) next() (map[string]json.RawMessage, int, error) {
+	record, err := r.reader.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, parseErr.StartLine, &invalidRowError{parseErr.Err.Error()}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	line, _ := r.reader.FieldPos(0)
+
+	fields := make(map[string]json.RawMessage, len(record))
+	for i, value := range record {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		column := r.header[i]
+		switch {
+		case numericColumns[column]:
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return nil, line, &invalidRowError{fmt.Sprintf("%s: invalid number %q", column, value)}
+			}
+			fields[column] = json.RawMessage(value)
+		case column == "release_date" && len(value) == len("2006-01-02"):
+			// Dates without a time are taken as midnight UTC
+			fields[column] = json.RawMessage(strconv.Quote(value + "T00:00:00Z"))
+		default:
+			quoted, _ := json.Marshal(value)
+			fields[column] = quoted
+		}
+	}
+	return fields, line, nil
+}
+
+// ndjsonReader yields one JSON object per non-blank line.
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &ndjsonReader{scanner: scanner}
+}
+
+// next returns the next object and its line number.
+func (r *ndjsonReader) next() (map[string]json.RawMessage, int, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(line, &fields); err != nil || fields == nil {
+			return nil, r.line, &invalidRowError{"line is not a JSON object"}
+		}
+		return fields, r.line, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+	return nil, 0, io.EOF
+}
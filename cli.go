@@ -0,0 +1,248 @@
+package main
+
+import (
+    "encoding/json"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "time"
+
+    "github.com/TobiAdeniji94/ecommerce_api/app"
+    "github.com/TobiAdeniji94/ecommerce_api/catalog"
+    "github.com/TobiAdeniji94/ecommerce_api/config"
+    "github.com/TobiAdeniji94/ecommerce_api/migrations"
+)
+
+// setupCommand runs a command that needs only the configuration, before
+// the application is built, and returns the process exit code.
+func setupCommand(cfg *config.Config, args []string) int {
+    if args[0] == "config" {
+        fmt.Print(cfg)
+        return 0
+    }
+    return migrateCommand(cfg, args[1:])
+}
+
+// runCommand runs a command-line task and returns the process exit code.
+func runCommand(a *app.App, args []string) int {
+    switch args[0] {
+    case "import-products":
+        return importProducts(a, args[1:])
+    case "export-products":
+        return exportProducts(a, args[1:])
+    default:
+        fmt.Fprintf(os.Stderr, "unknown command %q\ncommands: config, migrate, import-products, export-products\n", args[0])
+        return 2
+    }
+}
+
+// migrateCommand manages the database schema: up, down, status or create.
+// It runs before the schema check at startup, and create works without a
+// database.
+func migrateCommand(cfg *config.Config, args []string) int {
+    usage := func() {
+        fmt.Fprintln(os.Stderr, "usage: migrate up [-steps N] | down [-steps N] | status | create [-dir DIR] NAME")
+    }
+    if len(args) == 0 {
+        usage()
+        return 2
+    }
+
+    flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+    steps := flags.Int("steps", 0, "number of migrations to apply or roll back (up: all, down: 1)")
+    dir := flags.String("dir", "migrations", "directory to create migration files in")
+    if err := flags.Parse(args[1:]); err != nil {
+        return 2
+    }
+
+    if args[0] == "create" {
+        if flags.NArg() != 1 {
+            usage()
+            return 2
+        }
+        up, down, err := migrations.Create(*dir, flags.Arg(0))
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            return 1
+        }
+        fmt.Printf("created %s\ncreated %s\n", up, down)
+        return 0
+    }
+    if flags.NArg() != 0 {
+        usage()
+        return 2
+    }
+
+    db, err := config.ConnectDatabase(cfg.Database)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "failed to connect to database: %v\n", err)
+        return 1
+    }
+    switch args[0] {
+    case "up":
+        applied, err := migrations.Up(db, *steps)
+        for _, migration := range applied {
+            fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+        }
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "migrate up failed: %v\n", err)
+            return 1
+        }
+        if len(applied) == 0 {
+            fmt.Println("no pending migrations")
+        }
+    case "down":
+        reverted, err := migrations.Down(db, *steps)
+        for _, migration := range reverted {
+            fmt.Printf("rolled back %04d_%s\n", migration.Version, migration.Name)
+        }
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "migrate down failed: %v\n", err)
+            return 1
+        }
+        if len(reverted) == 0 {
+            fmt.Println("no applied migrations")
+        }
+    case "status":
+        states, err := migrations.Status(db)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "migrate status failed: %v\n", err)
+            return 1
+        }
+        for _, state := range states {
+            switch {
+            case state.AppliedAt == nil:
+                fmt.Printf("%04d_%s  pending\n", state.Version, state.Name)
+            case state.Up == "":
+                fmt.Printf("%04d_%s  applied %s (unknown to this build)\n", state.Version, state.Name, state.AppliedAt.Format(time.RFC3339))
+            default:
+                fmt.Printf("%04d_%s  applied %s\n", state.Version, state.Name, state.AppliedAt.Format(time.RFC3339))
+            }
+        }
+    default:
+        usage()
+        return 2
+    }
+    return 0
+}
+
+// importProducts upserts products from a CSV or NDJSON file, or standard
+// input when the file is "-". It prints the import report and fails if any
+// row was rejected.
+func importProducts(a *app.App, args []string) int {
+    flags := flag.NewFlagSet("import-products", flag.ContinueOnError)
+    format := flags.String("format", catalog.FormatCSV, "file format: csv or ndjson")
+    dryRun := flags.Bool("dry-run", false, "validate and report without saving")
+    batchSize := flags.Int("batch-size", catalog.DefaultBatchSize, "rows committed per transaction")
+    flags.Usage = func() {
+        fmt.Fprintln(flags.Output(), "usage: import-products [flags] FILE|-")
+        flags.PrintDefaults()
+    }
+    if err := flags.Parse(args); err != nil {
+        return 2
+    }
+    if flags.NArg() != 1 {
+        flags.Usage()
+        return 2
+    }
+
+    var input io.Reader = os.Stdin
+    if path := flags.Arg(0); path != "-" {
+        file, err := os.Open(path)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            return 1
+        }
+        defer file.Close()
+        input = file
+    }
+
+    report, err := catalog.Import(a.DB, a.Inventory, input, catalog.ImportOptions{
+        Format:    *format,
+        DryRun:    *dryRun,
+        BatchSize: *batchSize,
+    })
+    if report != nil {
+        encoder := json.NewEncoder(os.Stdout)
+        encoder.SetIndent("", "  ")
+        encoder.Encode(report)
+    }
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "import failed: %v\n", err)
+        return 1
+    }
+    if report.Failed > 0 {
+        return 1
+    }
+    return 0
+}
+
+// exportProducts writes the products matching the filters to a file, or
+// standard output when no file is given.
+func exportProducts(a *app.App, args []string) int {
+    flags := flag.NewFlagSet("export-products", flag.ContinueOnError)
+    format := flags.String("format", catalog.FormatCSV, "file format: csv or ndjson")
+    var filter catalog.Filter
+    flags.StringVar(&filter.Category, "category", "", "only products in this category")
+    flags.StringVar(&filter.SKU, "sku", "", "only the product with this SKU")
+    flags.StringVar(&filter.Query, "q", "", "search in name and description")
+    flags.Func("min-price", "minimum price", floatFlag(&filter.MinPrice))
+    flags.Func("max-price", "maximum price", floatFlag(&filter.MaxPrice))
+    flags.Func("in-stock", "true for products with stock, false for products without", func(value string) error {
+        var inStock bool
+        if _, err := fmt.Sscan(value, &inStock); err != nil {
+            return err
+        }
+        filter.InStock = &inStock
+        return nil
+    })
+    flags.Usage = func() {
+        fmt.Fprintln(flags.Output(), "usage: export-products [flags] [FILE]")
+        flags.PrintDefaults()
+    }
+    if err := flags.Parse(args); err != nil {
+        return 2
+    }
+    if flags.NArg() > 1 {
+        flags.Usage()
+        return 2
+    }
+
+    var output io.Writer = os.Stdout
+    if flags.NArg() == 1 {
+        file, err := os.Create(flags.Arg(0))
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            return 1
+        }
+        defer file.Close()
+        output = file
+    }
+
+    skipped, err := catalog.WithoutSKU(a.DB, filter)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "export failed: %v\n", err)
+        return 1
+    }
+    if err := catalog.Export(a.DB, output, *format, filter); err != nil {
+        fmt.Fprintf(os.Stderr, "export failed: %v\n", err)
+        return 1
+    }
+    if skipped > 0 {
+        fmt.Fprintf(os.Stderr, "skipped %d products without a SKU; give them one to export them\n", skipped)
+    }
+    return 0
+}
+
+// floatFlag parses a flag value into target.
+func floatFlag(target **float64) func(string) error {
+    return func(value string) error {
+        var parsed float64
+        if _, err := fmt.Sscan(value, &parsed); err != nil {
+            return err
+        }
+        *target = &parsed
+        return nil
+    }
+}
@@ -1,50 +1,68 @@
 package config
 
 import (
-    "log"
-	"fmt"
-	"os"
-
-    "github.com/TobiAdeniji94/ecommerce_api/models"
+    "fmt"
+    "log/slog"
+    "strings"
 
     "gorm.io/driver/postgres"
+    "gorm.io/driver/sqlite"
     "gorm.io/gorm"
 )
 
-var DB *gorm.DB
-
-func ConnectDatabase() {
-	// get environment variables
-	host := os.Getenv("DB_HOST")
-    user := os.Getenv("DB_USER")
-    password := os.Getenv("DB_PASSWORD")
-    dbName := os.Getenv("DB_NAME")
-    port := os.Getenv("DB_PORT")
+// ConnectDatabase opens the connection pool described by cfg.
+func ConnectDatabase(cfg DatabaseConfig) (*gorm.DB, error) {
+    if cfg.Driver == "sqlite" {
+        return connectSQLite(cfg)
+    }
 
-	// data source name
-    dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=require",
-		host, user, password, dbName, port,
-	)
+    // data source name; values are quoted so passwords may contain spaces
+    dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
+        dsnValue(cfg.Host), dsnValue(cfg.User), dsnValue(cfg.Password), dsnValue(cfg.Name), cfg.Port, dsnValue(cfg.SSLMode),
+    )
 
-	// connect to db
+    // connect to db
     database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
     if err != nil {
-        log.Fatalf("Failed to connect to database: %v", err)
+        return nil, err
     }
 
-    // db instance to global DB
-    DB = database
+    // connection pool
+    sqlDB, err := database.DB()
+    if err != nil {
+        return nil, err
+    }
+    sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+    sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+    sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+    sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
-    // migrate all models
-    err = DB.AutoMigrate(
-        &models.User{},
-        &models.Product{},
-        &models.Order{},
-        &models.OrderItem{},
-    )
+    slog.Info("Database connected")
+    return database, nil
+}
+
+// connectSQLite opens the SQLite database at cfg.Path with foreign keys
+// enforced. SQLite allows one writer at a time, so the pool holds a single
+// connection that is never recycled, which also keeps an in-memory database
+// alive; the pool settings do not apply.
+func connectSQLite(cfg DatabaseConfig) (*gorm.DB, error) {
+    database, err := gorm.Open(sqlite.Open("file:"+cfg.Path+"?_foreign_keys=on&_busy_timeout=5000"), &gorm.Config{})
     if err != nil {
-        log.Fatalf("Failed to auto-migrate: %v", err)
+        return nil, err
     }
 
-    log.Println("Database connected and migrated successfully!")
+    sqlDB, err := database.DB()
+    if err != nil {
+        return nil, err
+    }
+    sqlDB.SetMaxOpenConns(1)
+    sqlDB.SetMaxIdleConns(1)
+
+    slog.Info("SQLite database opened", "path", cfg.Path)
+    return database, nil
+}
+
+// dsnValue quotes a connection string value.
+func dsnValue(value string) string {
+    return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
 }
@@ -0,0 +1,259 @@
+package config
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "reflect"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/pelletier/go-toml/v2"
+    "gopkg.in/yaml.v3"
+)
+
+// setting is one leaf field of Config.
+type setting struct {
+    key    string // section.key in the file
+    env    string
+    flag   string
+    secret bool
+    value  reflect.Value
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load builds the configuration from the defaults, the file named by the
+// -config flag or CONFIG_FILE, the environment and the flags in args, then
+// validates it. All problems are reported together as a ValidationError.
+// Load returns the arguments left after the flags, such as a command name.
+func Load(args []string) (*Config, []string, error) {
+    cfg := Defaults()
+    settings := settingsOf(cfg)
+
+    flags := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+    file := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML configuration `file` (CONFIG_FILE)")
+    flagged := make(map[string]string)
+    for _, s := range settings {
+        name := s.flag
+        set := func(value string) error {
+            flagged[name] = value
+            return nil
+        }
+        if s.value.Kind() == reflect.Bool {
+            flags.BoolFunc(name, s.env, set)
+        } else {
+            flags.Func(name, s.env, set)
+        }
+    }
+    if err := flags.Parse(args); err != nil {
+        return nil, nil, err
+    }
+
+    var problems ValidationError
+    if *file != "" {
+        problems = append(problems, loadFile(*file, settings)...)
+    }
+    for _, s := range settings {
+        if value := os.Getenv(s.env); value != "" {
+            if err := setValue(s.value, value); err != nil {
+                problems = append(problems, fmt.Sprintf("%s: %v", s.env, err))
+            }
+        }
+    }
+    for _, s := range settings {
+        if value, ok := flagged[s.flag]; ok {
+            if err := setValue(s.value, value); err != nil {
+                problems = append(problems, fmt.Sprintf("-%s: %v", s.flag, err))
+            }
+        }
+    }
+
+    if err := cfg.Validate(); err != nil {
+        problems = append(problems, err.(ValidationError)...)
+    }
+    if len(problems) > 0 {
+        return nil, nil, problems
+    }
+    return cfg, flags.Args(), nil
+}
+
+// String lists every setting as ENV=value, with secrets redacted.
+func (c *Config) String() string {
+    var b strings.Builder
+    for _, s := range settingsOf(c) {
+        value := formatValue(s.value)
+        if s.secret && value != "" {
+            value = "[redacted]"
+        }
+        fmt.Fprintf(&b, "%s=%s\n", s.env, value)
+    }
+    return b.String()
+}
+
+// settingsOf returns the leaf fields of cfg, addressable so they can be set.
+func settingsOf(cfg *Config) []setting {
+    var settings []setting
+    sections := reflect.ValueOf(cfg).Elem()
+    for i := 0; i < sections.NumField(); i++ {
+        section := sections.Field(i)
+        sectionKey := sections.Type().Field(i).Tag.Get("key")
+        for j := 0; j < section.NumField(); j++ {
+            field := section.Type().Field(j)
+            env := field.Tag.Get("env")
+            settings = append(settings, setting{
+                key:    sectionKey + "." + field.Tag.Get("key"),
+                env:    env,
+                flag:   strings.ReplaceAll(strings.ToLower(env), "_", "-"),
+                secret: field.Tag.Get("secret") == "true",
+                value:  section.Field(j),
+            })
+        }
+    }
+    return settings
+}
+
+// loadFile applies a YAML or TOML file of sections, e.g.
+//
+//  database:
+//    host: localhost
+//
+// Unknown sections and keys are reported, to catch typos.
+func loadFile(path string, settings []setting) ValidationError {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return ValidationError{fmt.Sprintf("config file: %v", err)}
+    }
+
+    var document map[string]interface{}
+    switch ext := strings.ToLower(filepath.Ext(path)); ext {
+    case ".yaml", ".yml":
+        err = yaml.Unmarshal(data, &document)
+    case ".toml":
+        err = toml.Unmarshal(data, &document)
+    default:
+        return ValidationError{fmt.Sprintf("config file: unsupported extension %q, use .yaml, .yml or .toml", ext)}
+    }
+    if err != nil {
+        return ValidationError{fmt.Sprintf("config file %s: %v", path, err)}
+    }
+
+    byKey := make(map[string]setting, len(settings))
+    for _, s := range settings {
+        byKey[s.key] = s
+    }
+
+    var problems ValidationError
+    for _, sectionKey := range sortedKeys(document) {
+        values, ok := document[sectionKey].(map[string]interface{})
+        if !ok {
+            problems = append(problems, fmt.Sprintf("config file: %s must be a section of settings", sectionKey))
+            continue
+        }
+        for _, key := range sortedKeys(values) {
+            raw := values[key]
+            s, ok := byKey[sectionKey+"."+key]
+            if !ok {
+                problems = append(problems, fmt.Sprintf("config file: unknown setting %s.%s", sectionKey, key))
+                continue
+            }
+
+            value := fmt.Sprint(raw)
+            if list, ok := raw.([]interface{}); ok {
+                items := make([]string, len(list))
+                for i, item := range list {
+                    items[i] = fmt.Sprint(item)
+                }
+                value = strings.Join(items, ",")
+            }
+            if err := setValue(s.value, value); err != nil {
+                problems = append(problems, fmt.Sprintf("config file: %s: %v", s.key, err))
+            }
+        }
+    }
+    return problems
+}
+
+// sortedKeys returns the keys of m in order, so problems are reported in a
+// stable order.
+func sortedKeys(m map[string]interface{}) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+// setValue parses value into the field v.
+func setValue(v reflect.Value, value string) error {
+    value = strings.TrimSpace(value)
+    if v.Type() == durationType {
+        parsed, err := time.ParseDuration(value)
+        if err != nil {
+            return fmt.Errorf("invalid duration %q, e.g. 30s or 5m", value)
+        }
+        v.SetInt(int64(parsed))
+        return nil
+    }
+
+    switch v.Kind() {
+    case reflect.String:
+        v.SetString(value)
+    case reflect.Int:
+        parsed, err := strconv.Atoi(value)
+        if err != nil {
+            return fmt.Errorf("invalid integer %q", value)
+        }
+        v.SetInt(int64(parsed))
+    case reflect.Float64:
+        parsed, err := strconv.ParseFloat(value, 64)
+        if err != nil {
+            return fmt.Errorf("invalid number %q", value)
+        }
+        v.SetFloat(parsed)
+    case reflect.Bool:
+        parsed, err := strconv.ParseBool(value)
+        if err != nil {
+            return fmt.Errorf("invalid boolean %q, use true or false", value)
+        }
+        v.SetBool(parsed)
+    case reflect.Ptr:
+        target := reflect.New(v.Type().Elem())
+        if err := setValue(target.Elem(), value); err != nil {
+            return err
+        }
+        v.Set(target)
+    case reflect.Slice:
+        var items []string
+        for _, item := range strings.Split(value, ",") {
+            if item = strings.TrimSpace(item); item != "" {
+                items = append(items, item)
+            }
+        }
+        v.Set(reflect.ValueOf(items))
+    default:
+        return fmt.Errorf("unsupported setting type %s", v.Type())
+    }
+    return nil
+}
+
+// formatValue formats a field the way setValue parses it.
+func formatValue(v reflect.Value) string {
+    switch {
+    case v.Type() == durationType:
+        return time.Duration(v.Int()).String()
+    case v.Kind() == reflect.Ptr:
+        if v.IsNil() {
+            return ""
+        }
+        return formatValue(v.Elem())
+    case v.Kind() == reflect.Slice:
+        return strings.Join(v.Interface().([]string), ",")
+    default:
+        return fmt.Sprint(v.Interface())
+    }
+}
@@ -0,0 +1,384 @@
+package config
+
+import (
+    "fmt"
+    "net/url"
+    "strings"
+    "time"
+)
+
+// Config is the application configuration. Every setting has a default and
+// can be overridden, in increasing order of precedence, by a YAML or TOML
+// file, an environment variable and a command-line flag. The key tag names
+// the setting in the file, the env tag names the variable, and the flag is
+// the variable in lower case with dashes, e.g. -db-host for DB_HOST.
+// Settings tagged secret are redacted when the configuration is printed.
+type Config struct {
+    Server        ServerConfig        `key:"server"`
+    Database      DatabaseConfig      `key:"database"`
+    CORS          CORSConfig          `key:"cors"`
+    RateLimit     RateLimitConfig     `key:"rate_limit"`
+    JWT           JWTConfig           `key:"jwt"`
+    Idempotency   IdempotencyConfig   `key:"idempotency"`
+    Features      FeaturesConfig      `key:"features"`
+    Payments      PaymentsConfig      `key:"payments"`
+    Tax           TaxConfig           `key:"tax"`
+    Inventory     InventoryConfig     `key:"inventory"`
+    Notifications NotificationsConfig `key:"notifications"`
+    Webhooks      WebhooksConfig      `key:"webhooks"`
+    Outbox        OutboxConfig        `key:"outbox"`
+    Health        HealthConfig        `key:"health"`
+    Metrics       MetricsConfig       `key:"metrics"`
+    Tracing       TracingConfig       `key:"tracing"`
+    Log           LogConfig           `key:"log"`
+}
+
+// ServerConfig configures the HTTP server. ReadTimeout and WriteTimeout
+// cover the whole request and response, so they are off by default to
+// allow large imports and exports; zero means no limit. ShutdownDelay is
+// how long the server keeps serving after readiness starts failing, so
+// load balancers can stop routing to it before connections are closed.
+// Environment is development, test or production; development-only
+// conveniences such as the fake payment provider are refused in
+// production, which is the default.
+type ServerConfig struct {
+    Environment       string        `key:"environment" env:"APP_ENV"`
+    Port              int           `key:"port" env:"PORT"`
+    ReadHeaderTimeout time.Duration `key:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+    ReadTimeout       time.Duration `key:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+    WriteTimeout      time.Duration `key:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+    IdleTimeout       time.Duration `key:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+    ShutdownTimeout   time.Duration `key:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+    ShutdownDelay     time.Duration `key:"shutdown_delay" env:"SERVER_SHUTDOWN_DELAY"`
+}
+
+// DatabaseConfig configures the database connection and its pool. Driver
+// is postgres, configured by the host settings, or sqlite, which stores the
+// database in the file at Path (":memory:" for a throwaway one) and is meant
+// for local development and tests.
+type DatabaseConfig struct {
+    Driver          string        `key:"driver" env:"DB_DRIVER"`
+    Path            string        `key:"path" env:"DB_PATH"`
+    Host            string        `key:"host" env:"DB_HOST"`
+    Port            int           `key:"port" env:"DB_PORT"`
+    User            string        `key:"user" env:"DB_USER"`
+    Password        string        `key:"password" env:"DB_PASSWORD" secret:"true"`
+    Name            string        `key:"name" env:"DB_NAME"`
+    SSLMode         string        `key:"sslmode" env:"DB_SSLMODE"`
+    MaxOpenConns    int           `key:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+    MaxIdleConns    int           `key:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+    ConnMaxLifetime time.Duration `key:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+    ConnMaxIdleTime time.Duration `key:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+    MigrateOnStart  bool          `key:"migrate_on_start" env:"MIGRATE_ON_START"`
+}
+
+// CORSConfig configures which browser origins may call the API.
+type CORSConfig struct {
+    AllowedOrigins   []string      `key:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+    AllowCredentials bool          `key:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+    MaxAge           time.Duration `key:"max_age" env:"CORS_MAX_AGE"`
+}
+
+// RateLimitConfig configures the per-client rate limiter.
+type RateLimitConfig struct {
+    Enabled           bool    `key:"enabled" env:"RATE_LIMIT_ENABLED"`
+    RequestsPerSecond float64 `key:"requests_per_second" env:"RATE_LIMIT_RPS"`
+    Burst             int     `key:"burst" env:"RATE_LIMIT_BURST"`
+}
+
+// JWTConfig configures the tokens issued at login.
+type JWTConfig struct {
+    Secret string        `key:"secret" env:"JWT_SECRET" secret:"true"`
+    TTL    time.Duration `key:"ttl" env:"JWT_TTL"`
+}
+
+// IdempotencyConfig configures stored Idempotency-Key responses.
+type IdempotencyConfig struct {
+    TTL time.Duration `key:"ttl" env:"IDEMPOTENCY_TTL"`
+}
+
+// FeaturesConfig switches optional parts of the application on or off.
+type FeaturesConfig struct {
+    // Swagger serves the API documentation at /swagger.
+    Swagger bool `key:"swagger" env:"FEATURE_SWAGGER"`
+    // Workers runs the outbox relay and the notification and webhook
+    // workers in this instance. Turn it off to run API-only replicas.
+    Workers bool `key:"workers" env:"FEATURE_WORKERS"`
+    // Webhooks serves the webhook admin routes and queues deliveries.
+    Webhooks bool `key:"webhooks" env:"FEATURE_WEBHOOKS"`
+    // Metrics serves Prometheus metrics at /metrics.
+    Metrics bool `key:"metrics" env:"FEATURE_METRICS"`
+}
+
+// PaymentsConfig configures the payment provider.
+type PaymentsConfig struct {
+    Provider            string `key:"provider" env:"PAYMENT_PROVIDER"`
+    Currency            string `key:"currency" env:"PAYMENT_CURRENCY"`
+    WebhookSecret       string `key:"webhook_secret" env:"PAYMENT_WEBHOOK_SECRET" secret:"true"`
+    StripeAPIKey        string `key:"stripe_api_key" env:"STRIPE_API_KEY" secret:"true"`
+    StripeWebhookSecret string `key:"stripe_webhook_secret" env:"STRIPE_WEBHOOK_SECRET" secret:"true"`
+    StripeAPIURL        string `key:"stripe_api_url" env:"STRIPE_API_URL"`
+}
+
+// TaxConfig configures the tax rate table. PricesIncludeTax, when set,
+// overrides the setting in the rates file.
+type TaxConfig struct {
+    RatesFile        string `key:"rates_file" env:"TAX_RATES_FILE"`
+    PricesIncludeTax *bool  `key:"prices_include_tax" env:"TAX_PRICES_INCLUDE_TAX"`
+}
+
+// InventoryConfig configures stock reporting and order allocation.
+type InventoryConfig struct {
+    LowStockThreshold  int    `key:"low_stock_threshold" env:"LOW_STOCK_THRESHOLD"`
+    AllocationStrategy string `key:"allocation_strategy" env:"ALLOCATION_STRATEGY"`
+}
+
+// NotificationsConfig configures how notifications are sent.
+type NotificationsConfig struct {
+    Sender       string        `key:"sender" env:"NOTIFICATION_SENDER"`
+    PollInterval time.Duration `key:"poll_interval" env:"NOTIFICATION_POLL_INTERVAL"`
+    SMTPHost     string        `key:"smtp_host" env:"SMTP_HOST"`
+    SMTPPort     int           `key:"smtp_port" env:"SMTP_PORT"`
+    SMTPUsername string        `key:"smtp_username" env:"SMTP_USERNAME"`
+    SMTPPassword string        `key:"smtp_password" env:"SMTP_PASSWORD" secret:"true"`
+    SMTPFrom     string        `key:"smtp_from" env:"SMTP_FROM"`
+}
+
+// WebhooksConfig configures outbound webhook delivery.
+type WebhooksConfig struct {
+    PollInterval time.Duration `key:"poll_interval" env:"WEBHOOK_POLL_INTERVAL"`
+    Timeout      time.Duration `key:"timeout" env:"WEBHOOK_TIMEOUT"`
+}
+
+// OutboxConfig configures the outbox relay and its external sinks.
+type OutboxConfig struct {
+    PollInterval      time.Duration `key:"poll_interval" env:"OUTBOX_POLL_INTERVAL"`
+    Sinks             []string      `key:"sinks" env:"OUTBOX_SINKS"`
+    NATSURL           string        `key:"nats_url" env:"NATS_URL" secret:"true"`
+    NATSSubjectPrefix string        `key:"nats_subject_prefix" env:"NATS_SUBJECT_PREFIX"`
+    KafkaRESTURL      string        `key:"kafka_rest_url" env:"KAFKA_REST_URL"`
+    KafkaTopic        string        `key:"kafka_topic" env:"KAFKA_TOPIC"`
+}
+
+// HealthConfig configures the readiness checks.
+type HealthConfig struct {
+    CheckTimeout time.Duration `key:"check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+}
+
+// MetricsConfig configures the Prometheus endpoint. When Token is set,
+// scrapers must send it as a bearer token.
+type MetricsConfig struct {
+    Token string `key:"token" env:"METRICS_TOKEN" secret:"true"`
+}
+
+// TracingConfig configures OpenTelemetry tracing. Exporter is none, which
+// only assigns trace IDs, stdout, which prints spans for local use, or
+// otlp, which sends them over OTLP/HTTP to OTLPEndpoint. OTLPHeaders are
+// name=value pairs, e.g. an API key for a hosted collector. SampleRatio is
+// the share of new traces recorded; traces started by a caller follow the
+// caller's decision.
+type TracingConfig struct {
+    Exporter     string   `key:"exporter" env:"TRACING_EXPORTER"`
+    ServiceName  string   `key:"service_name" env:"TRACING_SERVICE_NAME"`
+    SampleRatio  float64  `key:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+    OTLPEndpoint string   `key:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT"`
+    OTLPHeaders  []string `key:"otlp_headers" env:"TRACING_OTLP_HEADERS" secret:"true"`
+}
+
+// LogConfig configures the application log. Level is the lowest level
+// written: debug, info, warn or error. Format is json, one object per
+// line for log collectors, or text, key=value pairs for reading locally.
+type LogConfig struct {
+    Level  string `key:"level" env:"LOG_LEVEL"`
+    Format string `key:"format" env:"LOG_FORMAT"`
+}
+
+// Defaults returns the configuration used when nothing is overridden.
+func Defaults() *Config {
+    return &Config{
+        Server: ServerConfig{
+            Environment:       "production",
+            Port:              3001,
+            ReadHeaderTimeout: 10 * time.Second,
+            IdleTimeout:       2 * time.Minute,
+            ShutdownTimeout:   5 * time.Second,
+        },
+        Database: DatabaseConfig{
+            Driver:          "postgres",
+            Port:            5432,
+            SSLMode:         "require",
+            MaxOpenConns:    25,
+            MaxIdleConns:    10,
+            ConnMaxLifetime: 30 * time.Minute,
+            ConnMaxIdleTime: 5 * time.Minute,
+        },
+        CORS: CORSConfig{
+            AllowedOrigins:   []string{"http://localhost:3000", "https://ecommerce-api-vkui.onrender.com"},
+            AllowCredentials: true,
+            MaxAge:           12 * time.Hour,
+        },
+        RateLimit: RateLimitConfig{
+            Enabled:           true,
+            RequestsPerSecond: 10,
+            Burst:             20,
+        },
+        JWT:         JWTConfig{TTL: 24 * time.Hour},
+        Idempotency: IdempotencyConfig{TTL: 24 * time.Hour},
+        Features:    FeaturesConfig{Swagger: true, Workers: true, Webhooks: true, Metrics: true},
+        Payments:    PaymentsConfig{Provider: "fake", Currency: "usd"},
+        Inventory:   InventoryConfig{LowStockThreshold: 5, AllocationStrategy: "priority"},
+        Notifications: NotificationsConfig{
+            Sender:       "log",
+            PollInterval: 5 * time.Second,
+            SMTPPort:     587,
+        },
+        Webhooks: WebhooksConfig{
+            PollInterval: 5 * time.Second,
+            Timeout:      10 * time.Second,
+        },
+        Outbox: OutboxConfig{
+            PollInterval:      time.Second,
+            NATSSubjectPrefix: "ecommerce",
+            KafkaTopic:        "ecommerce.events",
+        },
+        Health: HealthConfig{CheckTimeout: 2 * time.Second},
+        Tracing: TracingConfig{
+            Exporter:     "none",
+            ServiceName:  "ecommerce-api",
+            SampleRatio:  1,
+            OTLPEndpoint: "http://localhost:4318",
+        },
+        Log: LogConfig{Level: "info", Format: "json"},
+    }
+}
+
+// ValidationError lists every problem found in a configuration.
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+    return "invalid configuration:\n  - " + strings.Join(e, "\n  - ")
+}
+
+// Validate checks the configuration and reports all problems at once.
+func (c *Config) Validate() error {
+    var problems ValidationError
+    check := func(ok bool, format string, args ...interface{}) {
+        if !ok {
+            problems = append(problems, fmt.Sprintf(format, args...))
+        }
+    }
+    positive := func(name string, value time.Duration) {
+        check(value > 0, "%s must be positive, got %s", name, value)
+    }
+    oneOf := func(name, value string, allowed ...string) {
+        for _, a := range allowed {
+            if value == a {
+                return
+            }
+        }
+        problems = append(problems, fmt.Sprintf("%s must be one of %s, got %q", name, strings.Join(allowed, ", "), value))
+    }
+
+    oneOf("APP_ENV", c.Server.Environment, "development", "test", "production")
+    check(c.Server.Port > 0 && c.Server.Port < 65536, "PORT must be between 1 and 65535, got %d", c.Server.Port)
+    positive("SERVER_READ_HEADER_TIMEOUT", c.Server.ReadHeaderTimeout)
+    check(c.Server.ReadTimeout >= 0, "SERVER_READ_TIMEOUT must not be negative, got %s", c.Server.ReadTimeout)
+    check(c.Server.WriteTimeout >= 0, "SERVER_WRITE_TIMEOUT must not be negative, got %s", c.Server.WriteTimeout)
+    positive("SERVER_IDLE_TIMEOUT", c.Server.IdleTimeout)
+    positive("SERVER_SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout)
+    check(c.Server.ShutdownDelay >= 0, "SERVER_SHUTDOWN_DELAY must not be negative, got %s", c.Server.ShutdownDelay)
+
+    oneOf("DB_DRIVER", c.Database.Driver, "postgres", "sqlite")
+    if c.Database.Driver == "sqlite" {
+        check(c.Database.Path != "", "DB_PATH is required when DB_DRIVER is sqlite")
+    } else {
+        check(c.Database.Host != "", "DB_HOST is required")
+        check(c.Database.User != "", "DB_USER is required")
+        check(c.Database.Name != "", "DB_NAME is required")
+        check(c.Database.Port > 0 && c.Database.Port < 65536, "DB_PORT must be between 1 and 65535, got %d", c.Database.Port)
+        oneOf("DB_SSLMODE", c.Database.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+    }
+    check(c.Database.MaxOpenConns > 0, "DB_MAX_OPEN_CONNS must be positive, got %d", c.Database.MaxOpenConns)
+    check(c.Database.MaxIdleConns >= 0 && c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+        "DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS, got %d", c.Database.MaxIdleConns)
+    positive("DB_CONN_MAX_LIFETIME", c.Database.ConnMaxLifetime)
+    positive("DB_CONN_MAX_IDLE_TIME", c.Database.ConnMaxIdleTime)
+
+    for _, origin := range c.CORS.AllowedOrigins {
+        parsed, err := url.Parse(origin)
+        check(origin == "*" || (err == nil && parsed.Scheme != "" && parsed.Host != "" && parsed.Path == ""),
+            "CORS_ALLOWED_ORIGINS entry %q must be * or a scheme and host, e.g. https://shop.example.com", origin)
+        check(origin != "*" || !c.CORS.AllowCredentials, "CORS_ALLOWED_ORIGINS cannot be * when CORS_ALLOW_CREDENTIALS is true")
+    }
+    check(c.CORS.MaxAge >= 0, "CORS_MAX_AGE must not be negative, got %s", c.CORS.MaxAge)
+
+    if c.RateLimit.Enabled {
+        check(c.RateLimit.RequestsPerSecond > 0, "RATE_LIMIT_RPS must be positive, got %g", c.RateLimit.RequestsPerSecond)
+        check(c.RateLimit.Burst > 0, "RATE_LIMIT_BURST must be positive, got %d", c.RateLimit.Burst)
+    }
+
+    check(c.JWT.Secret != "", "JWT_SECRET is required")
+    positive("JWT_TTL", c.JWT.TTL)
+    positive("IDEMPOTENCY_TTL", c.Idempotency.TTL)
+
+    oneOf("PAYMENT_PROVIDER", c.Payments.Provider, "fake", "stripe")
+    check(len(c.Payments.Currency) == 3, "PAYMENT_CURRENCY must be a 3-letter ISO code, got %q", c.Payments.Currency)
+    if c.Payments.Provider == "fake" {
+        // Anyone holding the fake's secret can mark orders paid
+        check(c.Server.Environment != "production", "PAYMENT_PROVIDER=fake is only allowed when APP_ENV is development or test")
+        check(c.Payments.WebhookSecret != "" && c.Payments.WebhookSecret != "whsec_fake",
+            "PAYMENT_WEBHOOK_SECRET is required for the fake provider and must not be the public default whsec_fake")
+    }
+    if c.Payments.Provider == "stripe" {
+        check(c.Payments.StripeAPIKey != "", "STRIPE_API_KEY is required for the stripe provider")
+        check(c.Payments.StripeWebhookSecret != "", "STRIPE_WEBHOOK_SECRET is required for the stripe provider")
+    }
+
+    check(c.Inventory.LowStockThreshold >= 0, "LOW_STOCK_THRESHOLD must not be negative, got %d", c.Inventory.LowStockThreshold)
+    oneOf("ALLOCATION_STRATEGY", c.Inventory.AllocationStrategy, "priority", "nearest")
+
+    oneOf("NOTIFICATION_SENDER", c.Notifications.Sender, "log", "smtp")
+    positive("NOTIFICATION_POLL_INTERVAL", c.Notifications.PollInterval)
+    if c.Notifications.Sender == "smtp" {
+        check(c.Notifications.SMTPHost != "", "SMTP_HOST is required for the smtp sender")
+        check(c.Notifications.SMTPFrom != "", "SMTP_FROM is required for the smtp sender")
+        check(c.Notifications.SMTPPort > 0 && c.Notifications.SMTPPort < 65536, "SMTP_PORT must be between 1 and 65535, got %d", c.Notifications.SMTPPort)
+    }
+
+    positive("WEBHOOK_POLL_INTERVAL", c.Webhooks.PollInterval)
+    positive("WEBHOOK_TIMEOUT", c.Webhooks.Timeout)
+
+    positive("OUTBOX_POLL_INTERVAL", c.Outbox.PollInterval)
+    for _, sink := range c.Outbox.Sinks {
+        oneOf("OUTBOX_SINKS entry", sink, "nats", "kafka")
+        if sink == "nats" {
+            check(c.Outbox.NATSURL != "", "NATS_URL is required for the nats sink")
+        }
+        if sink == "kafka" {
+            check(c.Outbox.KafkaRESTURL != "", "KAFKA_REST_URL is required for the kafka sink")
+        }
+    }
+
+    positive("HEALTH_CHECK_TIMEOUT", c.Health.CheckTimeout)
+
+    oneOf("TRACING_EXPORTER", c.Tracing.Exporter, "none", "stdout", "otlp")
+    check(c.Tracing.ServiceName != "", "TRACING_SERVICE_NAME is required")
+    check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+    if c.Tracing.Exporter == "otlp" {
+        parsed, err := url.Parse(c.Tracing.OTLPEndpoint)
+        check(err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "",
+            "TRACING_OTLP_ENDPOINT must be an http or https URL, got %q", c.Tracing.OTLPEndpoint)
+        for _, header := range c.Tracing.OTLPHeaders {
+            name, _, found := strings.Cut(header, "=")
+            check(found && strings.TrimSpace(name) != "", "TRACING_OTLP_HEADERS entries must be name=value")
+        }
+    }
+
+    oneOf("LOG_LEVEL", c.Log.Level, "debug", "info", "warn", "error")
+    oneOf("LOG_FORMAT", c.Log.Format, "json", "text")
+
+    if len(problems) > 0 {
+        return problems
+    }
+    return nil
+}
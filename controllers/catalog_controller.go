@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TobiAdeniji94/ecommerce_api/catalog"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// maxImportSize limits the size of an uploaded catalog file.
+const maxImportSize = 50 << 20
+
+// catalogContentTypes maps the content types of catalog files to formats.
+var catalogContentTypes = map[string]string{
+	"text/csv":             catalog.FormatCSV,
+	"application/x-ndjson": catalog.FormatNDJSON,
+	"application/jsonl":    catalog.FormatNDJSON,
+}
+
+// ImportProducts creates and updates products from a CSV or NDJSON file (admin only)
+// ImportProducts godoc
+// @Summary Import products
+// @Description Upserts products by SKU from a CSV file (header row with the export columns) or JSON Lines. Only the columns present in a row are changed; new products need a name and price. Rows are saved in batches and a failing row is reported without affecting the others. With dry_run=true the import is validated and counted but nothing is saved.
+// @Tags Products
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Param format query string false "File format (csv or ndjson); defaults to the Content-Type"
+// @Param dry_run query bool false "Validate without saving"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Import report"
+// @Failure 400 {object} models.ErrorResponse "Malformed file; rows before the error may have been saved"
+// @Failure 413 {object} models.ErrorResponse "File too large"
+// @Failure 415 {object} models.ErrorResponse "Unsupported format"
+// @Failure 500 {object} models.ErrorResponse "Failed to import products"
+// @Router /products/import [post]
+func (h *Handler) ImportProducts(c *gin.Context) {
+	format := c.Query("format")
+	if format == "" {
+		format = catalogContentTypes[c.ContentType()]
+	}
+	if format != catalog.FormatCSV && format != catalog.FormatNDJSON {
+		c.JSON(http.StatusUnsupportedMediaType, models.ErrorResponse{Message: "Send text/csv or application/x-ndjson, or set format to csv or ndjson"})
+		return
+	}
+
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	report, err := catalog.Import(h.db(c), h.Inventory, body, catalog.ImportOptions{
+		Format:  format,
+		DryRun:  c.Query("dry_run") == "true",
+		ActorID: &adminID,
+	})
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{Message: "File too large"})
+		return
+	}
+	if errors.Is(err, catalog.ErrInvalidFile) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to import products"})
+		return
+	}
+
+	message := "Products imported"
+	if report.DryRun {
+		message = "Dry run completed; nothing was saved"
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: message,
+		Data:    report,
+	})
+}
+
+// ExportProducts streams the product catalog as CSV or NDJSON (admin only)
+// ExportProducts godoc
+// @Summary Export products
+// @Description Streams the products matching the same filters as the product list, in a format that can be imported again. Products without a SKU cannot be imported again, so they are left out and counted in the Skipped-Products header.
+// @Tags Products
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "File format (csv or ndjson)" default(csv)
+// @Param category query string false "Only products in this category"
+// @Param sku query string false "Only the product with this SKU"
+// @Param q query string false "Case-insensitive search in name and description"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param in_stock query bool false "Only products with (true) or without (false) stock"
+// @Security BearerAuth
+// @Success 200 {file} file "Product catalog"
+// @Header 200 {integer} Skipped-Products "Matching products left out because they have no SKU"
+// @Failure 400 {object} models.ErrorResponse "Invalid format or filter"
+// @Failure 500 {object} models.ErrorResponse "Failed to export products"
+// @Router /products/export [get]
+func (h *Handler) ExportProducts(c *gin.Context) {
+	format := c.DefaultQuery("format", catalog.FormatCSV)
+	contentType := "text/csv"
+	switch format {
+	case catalog.FormatCSV:
+	case catalog.FormatNDJSON:
+		contentType = "application/x-ndjson"
+	default:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "format must be csv or ndjson"})
+		return
+	}
+
+	filter, err := productFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	skipped, err := catalog.WithoutSKU(h.db(c), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to export products"})
+		return
+	}
+
+	filename := "products-" + h.Clock.Now().UTC().Format("20060102") + "." + format
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Skipped-Products", strconv.FormatInt(skipped, 10))
+	c.Status(http.StatusOK)
+
+	// The status is already sent, so a failure can only cut the stream short
+	if err := catalog.Export(h.db(c), c.Writer, format, filter); err != nil {
+		c.Error(err)
+	}
+}
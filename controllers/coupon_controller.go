@@ -0,0 +1,354 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// CreateCoupon allows an admin user to add a new coupon
+// CreateCoupon godoc
+// @Summary Create a coupon
+// @Description Allows an admin user to create a percentage or fixed amount discount code
+// @Tags Coupons
+// @Accept json
+// @Produce json
+// @Param coupon body models.CouponInput true "Coupon payload"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Coupon created successfully"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid coupon payload or duplicate code"
+// @Failure 500 {object} models.ErrorResponse "Failed to create coupon"
+// @Router /coupons [post]
+func (h *Handler) CreateCoupon(c *gin.Context) {
+	var input models.CouponInput
+	if !bindCouponInput(c, &input) {
+		return
+	}
+
+	var coupon models.Coupon
+	applyCouponInput(&coupon, input)
+
+	var existing models.Coupon
+	if err := h.db(c).Where("code = ?", coupon.Code).First(&existing).Error; err == nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "A coupon with this code already exists"})
+		return
+	}
+
+	if err := h.db(c).Create(&coupon).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create coupon"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Coupon created successfully",
+		Data:    coupon,
+	})
+}
+
+// GetCoupons lists all coupons (admin only)
+// GetCoupons godoc
+// @Summary Get all coupons
+// @Description Allows an admin user to list all coupons
+// @Tags Coupons
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Coupon(s) retrieved successfully"
+// @Failure 500 {object} models.ErrorResponse "Failed to retrieve coupons"
+// @Router /coupons [get]
+func (h *Handler) GetCoupons(c *gin.Context) {
+	var coupons []models.Coupon
+	if err := h.db(c).Order("created_at DESC").Find(&coupons).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve coupons"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Coupon(s) retrieved successfully",
+		Data:    coupons,
+	})
+}
+
+// GetCouponByID retrieves a single coupon by ID (admin only)
+// GetCouponByID godoc
+// @Summary Get coupon by ID
+// @Description Allows an admin user to retrieve a coupon by its ID
+// @Tags Coupons
+// @Produce json
+// @Param id path string true "Coupon ID"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Coupon retrieved successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid coupon ID"
+// @Failure 404 {object} models.ErrorResponse "Coupon not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to retrieve coupon"
+// @Router /coupons/{id} [get]
+func (h *Handler) GetCouponByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid coupon ID"})
+		return
+	}
+
+	var coupon models.Coupon
+	err = h.db(c).First(&coupon, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Coupon not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve coupon"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Coupon retrieved successfully",
+		Data:    coupon,
+	})
+}
+
+// UpdateCoupon modifies an existing coupon (admin only)
+// UpdateCoupon godoc
+// @Summary Update a coupon
+// @Description Allows an admin user to update a coupon by ID. The usage count is kept.
+// @Tags Coupons
+// @Accept json
+// @Produce json
+// @Param id path string true "Coupon ID"
+// @Param coupon body models.CouponInput true "Updated coupon payload"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Coupon updated successfully"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid coupon ID, payload or duplicate code"
+// @Failure 404 {object} models.ErrorResponse "Coupon not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to update coupon"
+// @Router /coupons/{id} [put]
+func (h *Handler) UpdateCoupon(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid coupon ID"})
+		return
+	}
+
+	var coupon models.Coupon
+	err = h.db(c).First(&coupon, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Coupon not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update coupon"})
+		return
+	}
+
+	var input models.CouponInput
+	if !bindCouponInput(c, &input) {
+		return
+	}
+	applyCouponInput(&coupon, input)
+
+	var existing models.Coupon
+	if err := h.db(c).Where("code = ? AND id <> ?", coupon.Code, coupon.ID).First(&existing).Error; err == nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "A coupon with this code already exists"})
+		return
+	}
+
+	if err := h.db(c).Save(&coupon).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update coupon"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Coupon updated successfully",
+		Data:    coupon,
+	})
+}
+
+// DeleteCoupon deletes a coupon by ID (admin only)
+// DeleteCoupon godoc
+// @Summary Delete a coupon
+// @Description Allows an admin user to delete a coupon by ID
+// @Tags Coupons
+// @Param id path string true "Coupon ID"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Coupon deleted successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid coupon ID"
+// @Failure 404 {object} models.ErrorResponse "Coupon not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to delete coupon"
+// @Router /coupons/{id} [delete]
+func (h *Handler) DeleteCoupon(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid coupon ID"})
+		return
+	}
+
+	result := h.db(c).Delete(&models.Coupon{}, "id = ?", id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to delete coupon"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Coupon not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Coupon deleted successfully",
+	})
+}
+
+// bindCouponInput binds and validates a coupon payload, writing a
+// validation error response and returning false if it is invalid.
+func bindCouponInput(c *gin.Context, input *models.CouponInput) bool {
+	if err := c.ShouldBindJSON(input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+			Errors: []models.ValidationError{
+				{Field: "payload", Message: err.Error()},
+			},
+		})
+		return false
+	}
+
+	var validationErrors []models.ValidationError
+	if input.Type == models.CouponTypePercentage && input.Value > 100 {
+		validationErrors = append(validationErrors, models.ValidationError{
+			Field:   "value",
+			Message: "Percentage discounts cannot exceed 100",
+		})
+	}
+	if input.StartsAt != nil && input.ExpiresAt != nil && !input.ExpiresAt.After(*input.StartsAt) {
+		validationErrors = append(validationErrors, models.ValidationError{
+			Field:   "expires_at",
+			Message: "Expiry must be after the start date",
+		})
+	}
+
+	if len(validationErrors) > 0 {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+			Errors: validationErrors,
+		})
+		return false
+	}
+	return true
+}
+
+// applyCouponInput copies a validated payload onto the coupon.
+func applyCouponInput(coupon *models.Coupon, input models.CouponInput) {
+	coupon.Code = normalizeCouponCode(input.Code)
+	coupon.Type = input.Type
+	coupon.Value = input.Value
+	coupon.MinOrderValue = input.MinOrderValue
+	coupon.Category = input.Category
+	coupon.StartsAt = input.StartsAt
+	coupon.ExpiresAt = input.ExpiresAt
+	coupon.UsageLimit = input.UsageLimit
+	coupon.PerUserLimit = input.PerUserLimit
+
+	coupon.ProductID = nil
+	if input.ProductID != "" {
+		productID := uuid.MustParse(input.ProductID)
+		coupon.ProductID = &productID
+	}
+
+	coupon.Active = true
+	if input.Active != nil {
+		coupon.Active = *input.Active
+	}
+}
+
+// normalizeCouponCode makes coupon codes case-insensitive.
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// applyCoupon validates the coupon for the order, counts its use and sets
+// the discount on the order and its items. It must run inside the order
+// transaction so the usage count is rolled back if the order fails.
+func (h *Handler) applyCoupon(tx *gorm.DB, userID uuid.UUID, order *models.Order, products []models.Product, code string) (*models.Coupon, error) {
+	// Lock the coupon row so concurrent orders are checked against the same usage counts
+	var coupon models.Coupon
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code = ?", normalizeCouponCode(code)).First(&coupon).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &requestError{http.StatusBadRequest, "Invalid coupon code"}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !coupon.IsValidAt(h.Clock.Now()) {
+		return nil, &requestError{http.StatusBadRequest, "Coupon is not currently valid"}
+	}
+	if order.Subtotal < coupon.MinOrderValue {
+		return nil, &requestError{http.StatusBadRequest, "Order does not meet the coupon's minimum value"}
+	}
+
+	if coupon.PerUserLimit > 0 {
+		var used int64
+		if err := tx.Model(&models.CouponRedemption{}).
+			Where("coupon_id = ? AND user_id = ?", coupon.ID, userID).Count(&used).Error; err != nil {
+			return nil, err
+		}
+		if used >= int64(coupon.PerUserLimit) {
+			return nil, &requestError{http.StatusBadRequest, "You have already used this coupon the maximum number of times"}
+		}
+	}
+
+	var eligible float64
+	for i, item := range order.Items {
+		if coupon.AppliesTo(products[i]) {
+			eligible += item.UnitPrice * float64(item.Quantity)
+		}
+	}
+	if eligible == 0 {
+		return nil, &requestError{http.StatusBadRequest, "Coupon does not apply to any items in this order"}
+	}
+	order.CouponCode = coupon.Code
+	order.DiscountAmount = coupon.Discount(roundMoney(eligible))
+
+	// Spread the discount over eligible lines so refunds can account for it
+	remaining := order.DiscountAmount
+	last := -1
+	for i := range order.Items {
+		if !coupon.AppliesTo(products[i]) {
+			continue
+		}
+		line := order.Items[i].UnitPrice * float64(order.Items[i].Quantity)
+		order.Items[i].DiscountAmount = roundMoney(order.DiscountAmount * line / eligible)
+		remaining -= order.Items[i].DiscountAmount
+		last = i
+	}
+	order.Items[last].DiscountAmount = roundMoney(order.Items[last].DiscountAmount + remaining)
+
+	result := tx.Model(&models.Coupon{}).
+		Where("id = ? AND (usage_limit = 0 OR times_used < usage_limit)", coupon.ID).
+		UpdateColumn("times_used", gorm.Expr("times_used + 1"))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, &requestError{http.StatusBadRequest, "Coupon usage limit reached"}
+	}
+
+	return &coupon, nil
+}
+
+// releaseCoupon gives back the coupon use of a canceled order.
+func releaseCoupon(tx *gorm.DB, orderID uuid.UUID) error {
+	var redemption models.CouponRedemption
+	err := tx.Where("order_id = ?", orderID).Limit(1).Find(&redemption).Error
+	if err != nil || redemption.ID == uuid.Nil {
+		return err
+	}
+
+	if err := tx.Delete(&redemption).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Coupon{}).Where("id = ? AND times_used > 0", redemption.CouponID).
+		UpdateColumn("times_used", gorm.Expr("times_used - 1")).Error
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"log/slog"
+
+	"gorm.io/gorm"
+
+	"github.com/TobiAdeniji94/ecommerce_api/health"
+	"github.com/TobiAdeniji94/ecommerce_api/inventory"
+	"github.com/TobiAdeniji94/ecommerce_api/metrics"
+	"github.com/TobiAdeniji94/ecommerce_api/payments"
+	"github.com/TobiAdeniji94/ecommerce_api/repository"
+	"github.com/TobiAdeniji94/ecommerce_api/tax"
+	"github.com/TobiAdeniji94/ecommerce_api/utils"
+)
+
+// Handler serves the API's endpoints. Its dependencies are passed in rather
+// than read from package globals, so handlers can be tested against an
+// in-memory database with fakes for the interface-typed dependencies.
+type Handler struct {
+	// DB is Store's database, for the queries that have no repository yet.
+	DB       *gorm.DB
+	Store    *repository.Store
+	Tokens   utils.TokenService
+	Payments payments.Provider
+	Tax      tax.Calculator
+	Clock    utils.Clock
+	// Inventory applies stock changes.
+	Inventory *inventory.Service
+	Log       *slog.Logger
+	Health    *health.Checker
+	// Metrics counts business events; it may be nil.
+	Metrics *metrics.Metrics
+	// Currency is the ISO currency code orders are charged in.
+	Currency string
+}
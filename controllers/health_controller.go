@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TobiAdeniji94/ecommerce_api/health"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// Healthz reports that the process is alive. It checks nothing else, so a
+// failing dependency never gets a healthy process restarted.
+func (h *Handler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusUp})
+}
+
+// Readyz reports whether the server should receive traffic: every check
+// passes and the server is not shutting down. Check errors are left out;
+// admins see them in the health details.
+func (h *Handler) Readyz(c *gin.Context) {
+	report := h.Health.Run(c.Request.Context())
+
+	checks := make(map[string]string, len(report.Checks))
+	for _, result := range report.Checks {
+		checks[result.Name] = result.Status
+	}
+
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, gin.H{
+		"status":        report.Status,
+		"shutting_down": report.ShuttingDown,
+		"checks":        checks,
+	})
+}
+
+// GetHealthDetails runs every check and reports its status, latency and
+// error (admin only)
+// GetHealthDetails godoc
+// @Summary Health check details
+// @Description Runs every readiness check, such as the database ping, and reports each one's status, latency and error. Responds 503 when any check fails or the server is shutting down.
+// @Tags Health
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "All checks passed"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 503 {object} models.SuccessResponse "A check failed"
+// @Router /health/details [get]
+func (h *Handler) GetHealthDetails(c *gin.Context) {
+	report := h.Health.Run(c.Request.Context())
+
+	if report.Status != health.StatusUp {
+		c.JSON(http.StatusServiceUnavailable, models.SuccessResponse{
+			Message: "Some health checks failed",
+			Data:    report,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "All health checks passed",
+		Data:    report,
+	})
+}
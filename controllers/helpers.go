@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/TobiAdeniji94/ecommerce_api/logging"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/utils"
+)
+
+// requestError aborts a transaction with a specific HTTP status and message.
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// log returns the logger of the request ctx belongs to, which carries its
+// request ID, trace ID, route and user, or h.Log outside a request.
+func (h *Handler) log(ctx context.Context) *slog.Logger {
+	if logger, ok := logging.Lookup(ctx); ok {
+		return logger
+	}
+	return h.Log
+}
+
+// db returns the database bound to the request's context, so queries are
+// traced as part of the request. The client going away does not cancel
+// them: writes that follow a payment provider call must still happen.
+func (h *Handler) db(c *gin.Context) *gorm.DB {
+	return h.DB.WithContext(context.WithoutCancel(c.Request.Context()))
+}
+
+// respondError writes err as an ErrorResponse. Errors that are not
+// requestErrors are reported as 500 with the fallback message.
+func respondError(c *gin.Context, err error, fallback string) {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		c.JSON(reqErr.status, models.ErrorResponse{Message: reqErr.message})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: fallback})
+}
+
+// currentUserID returns the authenticated user's ID, writing an error
+// response and returning false if it is missing.
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userData, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Message: "Unauthorized"})
+		return uuid.Nil, false
+	}
+
+	userUUID, ok := userData.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid user ID"})
+		return uuid.Nil, false
+	}
+	return userUUID, true
+}
+
+// roundMoney rounds an amount to cents.
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// checkIfMatch enforces an If-Match header against the resource's current
+// version, writing 412 Precondition Failed and returning false on mismatch.
+// Requests without If-Match are allowed through.
+func checkIfMatch(c *gin.Context, version int) bool {
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" || utils.ETagMatches(ifMatch, utils.ETag(version), false) {
+		return true
+	}
+	c.JSON(http.StatusPreconditionFailed, models.ErrorResponse{Message: "Resource has been modified; fetch it again and retry"})
+	return false
+}
+
+// versionConflict writes the response for an update that lost a race with
+// another writer: 412 if the client sent If-Match, 409 otherwise.
+func versionConflict(c *gin.Context) {
+	if c.GetHeader("If-Match") != "" {
+		c.JSON(http.StatusPreconditionFailed, models.ErrorResponse{Message: "Resource has been modified; fetch it again and retry"})
+		return
+	}
+	c.JSON(http.StatusConflict, models.ErrorResponse{Message: "Resource was modified concurrently; fetch it again and retry"})
+}
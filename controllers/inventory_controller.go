@@ -0,0 +1,400 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/TobiAdeniji94/ecommerce_api/inventory"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// Movement history page sizes.
+const (
+	defaultMovementLimit = 50
+	maxMovementLimit     = 200
+)
+
+// AdjustStock records a manual stock change (admin only)
+// AdjustStock godoc
+// @Summary Adjust stock
+// @Description Allows an admin to add or remove stock in a warehouse (the default warehouse if none is given) with a reason. Use type "receipt" for incoming goods and "adjustment" (the default) for corrections such as damage or a stock count. Stock can never go below zero. Added stock is allocated to backordered order items, oldest first.
+// @Tags Inventory
+// @Accept json
+// @Produce json
+// @Param adjustment body models.StockAdjustmentInput true "Stock adjustment payload"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Stock adjusted successfully"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid payload or insufficient stock"
+// @Failure 404 {object} models.ErrorResponse "Product or warehouse not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to adjust stock"
+// @Router /inventory/adjustments [post]
+func (h *Handler) AdjustStock(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input models.StockAdjustmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+			Errors: []models.ValidationError{
+				{Field: "payload", Message: err.Error()},
+			},
+		})
+		return
+	}
+
+	movementType := input.Type
+	if movementType == "" {
+		movementType = models.StockMovementAdjustment
+	}
+
+	var movement *models.StockMovement
+	err := h.db(c).Transaction(func(tx *gorm.DB) error {
+		adj := inventory.Adjustment{
+			ProductID: uuid.MustParse(input.ProductID),
+			Quantity:  input.Quantity,
+			Type:      movementType,
+			Reason:    input.Reason,
+			ActorID:   &adminID,
+		}
+		if input.WarehouseID != "" {
+			adj.WarehouseID = uuid.MustParse(input.WarehouseID)
+		} else {
+			warehouse, err := inventory.DefaultWarehouse(tx)
+			if err != nil {
+				return err
+			}
+			adj.WarehouseID = warehouse.ID
+		}
+
+		var err error
+		if movement, err = h.Inventory.Adjust(tx, adj); err != nil {
+			return err
+		}
+		if adj.Quantity < 0 {
+			return nil
+		}
+		return h.Inventory.FulfilBackorders(tx, adj.ProductID, &adminID)
+	})
+	switch {
+	case errors.Is(err, inventory.ErrProductNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Product not found"})
+		return
+	case errors.Is(err, inventory.ErrWarehouseNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Warehouse not found"})
+		return
+	case errors.Is(err, inventory.ErrInsufficientStock):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Adjustment would take stock below zero"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to adjust stock"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Stock adjusted successfully",
+		Data:    movement,
+	})
+}
+
+// TransferStock moves stock between warehouses (admin only)
+// TransferStock godoc
+// @Summary Transfer stock
+// @Description Allows an admin to move stock of a product from one warehouse to another. Total stock is unchanged; a transfer movement is recorded for each warehouse.
+// @Tags Inventory
+// @Accept json
+// @Produce json
+// @Param transfer body models.StockTransferInput true "Stock transfer payload"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Stock transferred successfully"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid payload or insufficient stock in the source warehouse"
+// @Failure 404 {object} models.ErrorResponse "Product or warehouse not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to transfer stock"
+// @Router /inventory/transfers [post]
+func (h *Handler) TransferStock(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input models.StockTransferInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+			Errors: []models.ValidationError{
+				{Field: "payload", Message: err.Error()},
+			},
+		})
+		return
+	}
+
+	reason := input.Reason
+	if reason == "" {
+		reason = "Transfer between warehouses"
+	}
+
+	var movements []models.StockMovement
+	err := h.db(c).Transaction(func(tx *gorm.DB) error {
+		var err error
+		movements, err = h.Inventory.Transfer(tx, uuid.MustParse(input.ProductID),
+			uuid.MustParse(input.FromWarehouseID), uuid.MustParse(input.ToWarehouseID),
+			input.Quantity, reason, &adminID)
+		return err
+	})
+	switch {
+	case errors.Is(err, inventory.ErrProductNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Product not found"})
+		return
+	case errors.Is(err, inventory.ErrWarehouseNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Warehouse not found"})
+		return
+	case errors.Is(err, inventory.ErrInsufficientStock):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Not enough stock in the source warehouse"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to transfer stock"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Stock transferred successfully",
+		Data:    movements,
+	})
+}
+
+// GetProductStock lists a product's stock per warehouse (admin only)
+// GetProductStock godoc
+// @Summary Get stock by warehouse
+// @Description Lists how much of a product each warehouse holds
+// @Tags Inventory
+// @Produce json
+// @Param id path string true "Product ID"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Stock levels retrieved successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid product ID"
+// @Failure 404 {object} models.ErrorResponse "Product not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to fetch stock levels"
+// @Router /products/{id}/stock [get]
+func (h *Handler) GetProductStock(c *gin.Context) {
+	productUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid product ID"})
+		return
+	}
+
+	var product models.Product
+	if err := h.db(c).Select("id").First(&product, "id = ?", productUUID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Product not found"})
+		return
+	}
+
+	var levels []models.StockLevel
+	err = h.db(c).Preload("Warehouse").
+		Joins("JOIN warehouses ON warehouses.id = stock_levels.warehouse_id").
+		Where("stock_levels.product_id = ?", productUUID).
+		Order("warehouses.priority, warehouses.name").
+		Find(&levels).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch stock levels"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Stock levels retrieved successfully",
+		Data:    levels,
+	})
+}
+
+// GetStockMovements lists the stock movements of a product (admin only)
+// GetStockMovements godoc
+// @Summary Get stock movements
+// @Description Lists a product's stock movements, newest first
+// @Tags Inventory
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param type query string false "Filter by type (receipt, sale, cancellation, return, adjustment, reconciliation, transfer)"
+// @Param limit query int false "Maximum number of movements (default 50, max 200)"
+// @Param offset query int false "Number of movements to skip"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Stock movements retrieved successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid product ID, limit or offset"
+// @Failure 404 {object} models.ErrorResponse "Product not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to fetch stock movements"
+// @Router /products/{id}/movements [get]
+func (h *Handler) GetStockMovements(c *gin.Context) {
+	productUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid product ID"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultMovementLimit)))
+	if err != nil || limit < 1 || limit > maxMovementLimit {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "limit must be between 1 and " + strconv.Itoa(maxMovementLimit)})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "offset must not be negative"})
+		return
+	}
+
+	var product models.Product
+	if err := h.db(c).Select("id").First(&product, "id = ?", productUUID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Product not found"})
+		return
+	}
+
+	query := h.db(c).Where("product_id = ?", productUUID).Order("created_at DESC").Limit(limit).Offset(offset)
+	if movementType := c.Query("type"); movementType != "" {
+		query = query.Where("type = ?", movementType)
+	}
+
+	var movements []models.StockMovement
+	if err := query.Find(&movements).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch stock movements"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Stock movements retrieved successfully",
+		Data:    movements,
+	})
+}
+
+// GetLowStockProducts lists products at or below their low-stock threshold (admin only)
+// GetLowStockProducts godoc
+// @Summary Get low-stock products
+// @Description Lists products whose stock is at or below their low_stock_threshold, or the LOW_STOCK_THRESHOLD default for products without one. Pass threshold to use a single threshold for every product.
+// @Tags Inventory
+// @Produce json
+// @Param threshold query int false "Threshold applied to every product"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Low-stock products retrieved successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid threshold"
+// @Failure 500 {object} models.ErrorResponse "Failed to fetch low-stock products"
+// @Router /inventory/low-stock [get]
+func (h *Handler) GetLowStockProducts(c *gin.Context) {
+	query := h.db(c).Order("stock ASC, name ASC")
+	if value := c.Query("threshold"); value != "" {
+		threshold, err := strconv.Atoi(value)
+		if err != nil || threshold < 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid threshold"})
+			return
+		}
+		query = query.Where("stock <= ?", threshold)
+	} else {
+		query = query.Where("stock <= CASE WHEN low_stock_threshold > 0 THEN low_stock_threshold ELSE ? END", h.Inventory.LowStockThreshold)
+	}
+
+	var products []models.Product
+	if err := query.Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch low-stock products"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Low-stock products retrieved successfully",
+		Data:    products,
+	})
+}
+
+// GetBackorders lists order items waiting for stock (admin only)
+// GetBackorders godoc
+// @Summary Get backorders
+// @Description Lists backordered order items in the order they will be fulfilled (oldest order first). Filter by product with product_id.
+// @Tags Inventory
+// @Produce json
+// @Param product_id query string false "Only backorders of this product"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Backorders retrieved successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid product ID"
+// @Failure 500 {object} models.ErrorResponse "Failed to fetch backorders"
+// @Router /inventory/backorders [get]
+func (h *Handler) GetBackorders(c *gin.Context) {
+	query := h.db(c).Preload("Product").Select("order_items.*").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("order_items.backordered_quantity > 0").
+		Where("orders.status NOT IN ?", []string{models.OrderStatusCanceled, models.OrderStatusRefunded}).
+		Order("orders.created_at, order_items.id")
+	if value := c.Query("product_id"); value != "" {
+		productUUID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid product ID"})
+			return
+		}
+		query = query.Where("order_items.product_id = ?", productUUID)
+	}
+
+	var items []models.OrderItem
+	if err := query.Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch backorders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Backorders retrieved successfully",
+		Data:    items,
+	})
+}
+
+// GetStockDiscrepancies reports products whose stock disagrees with the ledger (admin only)
+// GetStockDiscrepancies godoc
+// @Summary Check stock against the ledger
+// @Description Lists products whose stock differs from the sum of their stock movements, for example products created before the ledger existed
+// @Tags Inventory
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Stock discrepancies retrieved successfully"
+// @Failure 500 {object} models.ErrorResponse "Failed to check stock"
+// @Router /inventory/reconciliation [get]
+func (h *Handler) GetStockDiscrepancies(c *gin.Context) {
+	discrepancies, err := inventory.Discrepancies(h.db(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to check stock"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Stock discrepancies retrieved successfully",
+		Data:    discrepancies,
+	})
+}
+
+// ReconcileStock brings the ledger in line with current stock (admin only)
+// ReconcileStock godoc
+// @Summary Reconcile stock with the ledger
+// @Description Records a reconciliation movement for every product whose stock differs from its ledger, treating current stock as the physical count
+// @Tags Inventory
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Stock reconciled successfully"
+// @Failure 500 {object} models.ErrorResponse "Failed to reconcile stock"
+// @Router /inventory/reconciliation [post]
+func (h *Handler) ReconcileStock(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var movements []models.StockMovement
+	err := h.db(c).Transaction(func(tx *gorm.DB) error {
+		var err error
+		movements, err = inventory.Reconcile(tx, &adminID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to reconcile stock"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Stock reconciled successfully",
+		Data:    movements,
+	})
+}
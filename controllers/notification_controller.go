@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// GetNotifications lists notifications sent or queued for users
+// GetNotifications godoc
+// @Summary List notifications
+// @Description Lists the authenticated user's notifications, newest first. Admins see every notification and can filter by status, e.g. status=failed to find undeliverable ones.
+// @Tags Notifications
+// @Produce json
+// @Param status query string false "Filter by status (pending, sent, failed)"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Notifications retrieved successfully"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 500 {object} models.ErrorResponse "Failed to fetch notifications"
+// @Router /notifications [get]
+func (h *Handler) GetNotifications(c *gin.Context) {
+	userUUID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	query := h.db(c).Order("created_at DESC")
+	if role, _ := c.Get("role"); role != "admin" {
+		query = query.Where("user_id = ?", userUUID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var notifications []models.Notification
+	if err := query.Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Notifications retrieved successfully",
+		Data:    notifications,
+	})
+}
@@ -1,30 +1,42 @@
 package controllers
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 
-	"github.com/TobiAdeniji94/ecommerce_api/config"
+	"github.com/TobiAdeniji94/ecommerce_api/inventory"
 	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/outbox"
+	"github.com/TobiAdeniji94/ecommerce_api/repository"
+	"github.com/TobiAdeniji94/ecommerce_api/tax"
+	"github.com/TobiAdeniji94/ecommerce_api/utils"
 )
 
 // PlaceOrder allows an authenticated user to create a new order
 // PlaceOrder godoc
 // @Summary Place a new order
-// @Description Allows an authenticated user to place an order with one or more products
+// @Description Allows an authenticated user to place an order with one or more products, a shipping address, an optional shipping method and an optional coupon code. Tax is calculated for the shipping address. Items of products that accept backorders or pre-orders are backordered when out of stock.
 // @Tags Orders
 // @Accept json
 // @Produce json
 // @Param order body models.PlaceOrderInput true "Order payload"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
 // @Security BearerAuth
 // @Success 200 {object} models.SuccessResponse "Order created successfully"
-// @Failure 400 {object} models.ValidationErrorResponse "Invalid order payload"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid order payload, unknown product or shipping method, insufficient stock or invalid coupon"
 // @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 409 {object} models.ErrorResponse "Idempotency-Key reused for a different request or still in progress"
 // @Failure 500 {object} models.ErrorResponse "Failed to create order"
+// @Failure 502 {object} models.ErrorResponse "Tax calculation failed"
 // @Router /orders [post]
-func PlaceOrder(c *gin.Context) {
+func (h *Handler) PlaceOrder(c *gin.Context) {
 	userData, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Message: "Unauthorized"})
@@ -48,31 +60,133 @@ func PlaceOrder(c *gin.Context) {
 	}
 
 	newOrder := models.Order{
+		ID:     uuid.New(),
 		UserID: userUUID,
-		Status: "Pending",
-	}
-	if err := config.DB.Create(&newOrder).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create order"})
-		return
+		Status: models.OrderStatusPending,
+		ShippingAddress: models.Address{
+			Line1:      orderRequest.ShippingAddress.Line1,
+			Line2:      orderRequest.ShippingAddress.Line2,
+			City:       orderRequest.ShippingAddress.City,
+			Region:     orderRequest.ShippingAddress.Region,
+			PostalCode: orderRequest.ShippingAddress.PostalCode,
+			Country:    strings.ToUpper(orderRequest.ShippingAddress.Country),
+		},
 	}
+	ctx := c.Request.Context()
+	err := h.Store.Transaction(ctx, func(tx *repository.Store) error {
+		products := make([]models.Product, 0, len(orderRequest.Items))
+		for _, item := range orderRequest.Items {
+			prodUUID, err := uuid.Parse(item.ProductID)
+			if err != nil {
+				return &requestError{http.StatusBadRequest, "Invalid product ID"}
+			}
 
-	for _, item := range orderRequest.Items {
-		prodUUID, err := uuid.Parse(item.ProductID)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid product ID"})
-			return
+			product, err := tx.Products.ByID(ctx, prodUUID)
+			if errors.Is(err, repository.ErrNotFound) {
+				return &requestError{http.StatusBadRequest, "Product not found: " + item.ProductID}
+			}
+			if err != nil {
+				return err
+			}
+
+			// Products that accept backorders take what is in stock and
+			// backorder the rest
+			allocated := item.Quantity
+			if product.AcceptsBackorders() {
+				available, err := inventory.Available(tx.DB, product.ID)
+				if err != nil {
+					return err
+				}
+				allocated = min(available, item.Quantity)
+			}
+
+			// Reserve stock from the warehouses chosen by the allocation strategy
+			var allocations []models.OrderAllocation
+			if allocated > 0 {
+				allocations, err = h.Inventory.Allocate(tx.DB, inventory.Adjustment{
+					ProductID: product.ID,
+					Type:      models.StockMovementSale,
+					Reason:    "Order placed",
+					ActorID:   &userUUID,
+					OrderID:   &newOrder.ID,
+				}, allocated, newOrder.ShippingAddress)
+				if errors.Is(err, inventory.ErrInsufficientStock) {
+					return &requestError{http.StatusBadRequest, "Insufficient stock for product: " + item.ProductID}
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+			backordered := item.Quantity - allocated
+			if backordered > 0 {
+				err := inventory.Backorder(tx.DB, product.ID, backordered)
+				if errors.Is(err, inventory.ErrBackorderLimit) {
+					return &requestError{http.StatusBadRequest, "Backorder limit reached for product: " + item.ProductID}
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+			products = append(products, *product)
+			newOrder.Items = append(newOrder.Items, models.OrderItem{
+				ProductID:           product.ID,
+				Quantity:            item.Quantity,
+				UnitPrice:           product.Price,
+				BackorderedQuantity: backordered,
+				Preorder:            product.IsPreorder(h.Clock.Now()),
+				Allocations:         allocations,
+			})
+			newOrder.Subtotal += product.Price * float64(item.Quantity)
 		}
+		newOrder.Subtotal = roundMoney(newOrder.Subtotal)
 
-		orderItem := models.OrderItem{
-			OrderID:   newOrder.ID,
-			ProductID: prodUUID,
-			Quantity:  item.Quantity,
+		var coupon *models.Coupon
+		if orderRequest.CouponCode != "" {
+			var err error
+			if coupon, err = h.applyCoupon(tx.DB, userUUID, &newOrder, products, orderRequest.CouponCode); err != nil {
+				return err
+			}
 		}
-		if err := config.DB.Create(&orderItem).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create order item"})
-			return
+
+		if err := h.applyTax(ctx, &newOrder, products); err != nil {
+			return err
+		}
+
+		if orderRequest.ShippingMethodID != "" {
+			if err := applyShipping(tx.DB, &newOrder, products, orderRequest.ShippingMethodID); err != nil {
+				return err
+			}
+		}
+
+		newOrder.Total = roundMoney(newOrder.Subtotal - newOrder.DiscountAmount + newOrder.ShippingCost)
+		if !newOrder.PricesIncludeTax {
+			newOrder.Total = roundMoney(newOrder.Total + newOrder.TaxTotal)
+		}
+
+		if err := tx.Orders.Create(ctx, &newOrder); err != nil {
+			return err
 		}
+		if err := outbox.Record(tx.DB, outbox.AggregateOrder, newOrder.ID, outbox.EventOrderCreated, outbox.NewOrderData(&newOrder, "")); err != nil {
+			return err
+		}
+
+		if coupon != nil {
+			return tx.DB.Create(&models.CouponRedemption{
+				CouponID: coupon.ID,
+				UserID:   userUUID,
+				OrderID:  newOrder.ID,
+				Discount: newOrder.DiscountAmount,
+			}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, err, "Failed to create order")
+		return
 	}
+	h.Metrics.OrderPlaced()
 
 	c.JSON(http.StatusOK, models.SuccessResponse{
 		Message: "Order created successfully",
@@ -83,7 +197,7 @@ func PlaceOrder(c *gin.Context) {
 // GetUserOrders lists all orders for the authenticated user
 // GetUserOrders godoc
 // @Summary Get all orders for a user
-// @Description Retrieve a list of all orders placed by the authenticated user
+// @Description Retrieve a list of all orders placed by the authenticated user, including shipment tracking details
 // @Tags Orders
 // @Produce json
 // @Security BearerAuth
@@ -91,7 +205,7 @@ func PlaceOrder(c *gin.Context) {
 // @Failure 401 {object} models.ErrorResponse "Unauthorized"
 // @Failure 500 {object} models.ErrorResponse "Failed to fetch orders"
 // @Router /orders [get]
-func GetUserOrders(c *gin.Context) {
+func (h *Handler) GetUserOrders(c *gin.Context) {
 	userData, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Message: "Unauthorized"})
@@ -104,8 +218,8 @@ func GetUserOrders(c *gin.Context) {
 		return
 	}
 
-	var orders []models.Order
-	if err := config.DB.Preload("User").Preload("Items.Product").Where("user_id = ?", userUUID).Find(&orders).Error; err != nil {
+	orders, err := h.Store.Orders.ListByUser(c.Request.Context(), userUUID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch orders"})
 		return
 	}
@@ -120,10 +234,68 @@ func GetUserOrders(c *gin.Context) {
 	})
 }
 
+// GetOrderByID retrieves a single order of the authenticated user
+// GetOrderByID godoc
+// @Summary Get order by ID
+// @Description Retrieves one of the authenticated user's orders (any order for admins). The response carries an ETag; send it in If-None-Match to get 304 Not Modified when the order is unchanged.
+// @Tags Orders
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param If-None-Match header string false "ETag from a previous response"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Order retrieved successfully"
+// @Success 304 "Order not modified"
+// @Failure 400 {object} models.ErrorResponse "Invalid order ID"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 404 {object} models.ErrorResponse "Order not found"
+// @Router /orders/{id} [get]
+func (h *Handler) GetOrderByID(c *gin.Context) {
+	userUUID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	orderUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid order ID"})
+		return
+	}
+
+	// Other users' orders are reported missing, except to admins
+	order, err := h.Store.Orders.ByID(c.Request.Context(), orderUUID)
+	if err == nil && order.UserID != userUUID {
+		if role, _ := c.Get("role"); role != "admin" {
+			err = repository.ErrNotFound
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Order not found"})
+		return
+	}
+
+	etag := utils.ETag(order.Version)
+	c.Header("ETag", etag)
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" && utils.ETagMatches(ifNoneMatch, etag, true) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	// Warehouse allocations are internal
+	order.User.Password = ""
+	for i := range order.Items {
+		order.Items[i].Allocations = nil
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Order retrieved successfully",
+		Data:    order,
+	})
+}
+
 // CancelOrder cancels the order if it's still pending
 // CancelOrder godoc
 // @Summary Cancel an order
-// @Description Allows an authenticated user to cancel an order if it is in "Pending" status
+// @Description Allows an authenticated user to cancel an order if it is in "Pending" or "PaymentFailed" status
 // @Tags Orders
 // @Param id path string true "Order ID"
 // @Security BearerAuth
@@ -131,9 +303,12 @@ func GetUserOrders(c *gin.Context) {
 // @Failure 400 {object} models.ErrorResponse "Invalid order ID or status"
 // @Failure 401 {object} models.ErrorResponse "Unauthorized"
 // @Failure 404 {object} models.ErrorResponse "Order not found"
+// @Failure 409 {object} models.ErrorResponse "Order was modified concurrently"
+// @Failure 412 {object} models.ErrorResponse "If-Match does not match the current version"
 // @Failure 500 {object} models.ErrorResponse "Failed to cancel order"
+// @Param If-Match header string false "ETag of the version being canceled"
 // @Router /orders/{id}/cancel [put]
-func CancelOrder(c *gin.Context) {
+func (h *Handler) CancelOrder(c *gin.Context) {
 	userData, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Message: "Unauthorized"})
@@ -153,25 +328,53 @@ func CancelOrder(c *gin.Context) {
 		return
 	}
 
-	var order models.Order
-	if err := config.DB.Where("id = ? AND user_id = ?", orderUUID, userUUID).First(&order).Error; err != nil {
+	ctx := c.Request.Context()
+	order, err := h.Store.Orders.ByID(ctx, orderUUID)
+	if err != nil || order.UserID != userUUID {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Order not found"})
 		return
 	}
 
-	if order.Status != "Pending" {
+	if !checkIfMatch(c, order.Version) {
+		return
+	}
+
+	if order.Status != models.OrderStatusPending && order.Status != models.OrderStatusPaymentFailed {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Message: "Order cannot be canceled. Current status: " + order.Status,
 		})
 		return
 	}
 
-	order.Status = "Canceled"
-	if err := config.DB.Save(&order).Error; err != nil {
+	err = h.Store.Transaction(ctx, func(tx *repository.Store) error {
+		if err := tx.Orders.Lock(ctx, order); err != nil {
+			return err
+		}
+		if err := setOrderStatus(ctx, tx, order, models.OrderStatusCanceled); err != nil {
+			return err
+		}
+		if err := releaseCoupon(tx.DB, order.ID); err != nil {
+			return err
+		}
+
+		return h.restockItems(tx.DB, order.Items, inventory.Adjustment{
+			Type:    models.StockMovementCancellation,
+			Reason:  "Order canceled by customer",
+			ActorID: &userUUID,
+			OrderID: &order.ID,
+		})
+	})
+	if errors.Is(err, repository.ErrVersionConflict) {
+		versionConflict(c)
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to cancel order"})
 		return
 	}
+	h.Metrics.OrderCanceled("customer")
 
+	c.Header("ETag", utils.ETag(order.Version))
 	c.JSON(http.StatusOK, models.SuccessResponse{
 		Message: "Order canceled successfully",
 	})
@@ -180,18 +383,26 @@ func CancelOrder(c *gin.Context) {
 // UpdateOrderStatus allows an admin to update an order status
 // UpdateOrderStatus godoc
 // @Summary Update order status
-// @Description Allows an admin to update the status of an order
+// @Description Allows an admin to move an order to the next status in its lifecycle (Shipped, Delivered, or Canceled). Paid and PaymentFailed follow the order's payments, and approving returns sets Refunded, so none of them can be set here. Marking an order "Shipped" requires a shipment, no backordered items and released pre-orders. Canceling a paid order refunds it and restocks its items; if the payment provider is unavailable the refund is retried in the background.
 // @Tags Orders
 // @Param id path string true "Order ID"
 // @Param status body models.UpdateOrderStatusInput true "Update order status payload"
+// @Param If-Match header string false "ETag of the version being updated"
 // @Security BearerAuth
 // @Success 200 {object} models.SuccessResponse "Order status updated successfully"
-// @Failure 400 {object} models.ValidationErrorResponse "Invalid order ID or payload"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid order ID, payload or status transition"
 // @Failure 401 {object} models.ErrorResponse "Unauthorized"
 // @Failure 404 {object} models.ErrorResponse "Order not found"
+// @Failure 409 {object} models.ErrorResponse "Order was modified concurrently"
+// @Failure 412 {object} models.ErrorResponse "If-Match does not match the current version"
 // @Failure 500 {object} models.ErrorResponse "Failed to update order status"
 // @Router /orders/{id}/status [put]
-func UpdateOrderStatus(c *gin.Context) {
+func (h *Handler) UpdateOrderStatus(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
 	orderIDStr := c.Param("id")
 	orderUUID, err := uuid.Parse(orderIDStr)
 	if err != nil {
@@ -209,22 +420,168 @@ func UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	var order models.Order
-	if err := config.DB.Preload("User").Preload("Items.Product").First(&order, "id = ?", orderUUID).Error; err != nil {
+	ctx := c.Request.Context()
+	order, err := h.Store.Orders.ByID(ctx, orderUUID)
+	if errors.Is(err, repository.ErrNotFound) {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Order not found"})
 		return
 	}
-
-	order.Status = requestBody.Status
-	if err := config.DB.Save(&order).Error; err != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update order status"})
 		return
 	}
 
+	if !checkIfMatch(c, order.Version) {
+		return
+	}
+
+	if !models.CanAdminSetOrderStatus(order.Status, requestBody.Status) {
+		message := "Order cannot move from " + order.Status + " to " + requestBody.Status
+		switch requestBody.Status {
+		case models.OrderStatusPaid, models.OrderStatusPaymentFailed:
+			message = "Orders move to " + requestBody.Status + " through their payments"
+		case models.OrderStatusRefunded:
+			message = "Orders move to Refunded when their returns are approved"
+		}
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: message})
+		return
+	}
+
+	if requestBody.Status == models.OrderStatusShipped {
+		if len(order.Shipments) == 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Create a shipment before marking the order as Shipped"})
+			return
+		}
+		for _, item := range order.Items {
+			if item.BackorderedQuantity > 0 {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Order has backordered items waiting for stock"})
+				return
+			}
+			if item.Preorder && item.Product.IsPreorder(h.Clock.Now()) {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Order has pre-ordered items that are not released yet"})
+				return
+			}
+		}
+	}
+
+	var refund *models.Refund
+	err = h.Store.Transaction(ctx, func(tx *repository.Store) error {
+		if err := tx.Orders.Lock(ctx, order); err != nil {
+			return err
+		}
+
+		if requestBody.Status == models.OrderStatusCanceled {
+			// Canceling a paid order refunds whatever has not been refunded yet
+			if order.Status == models.OrderStatusPaid {
+				var err error
+				if refund, err = h.refundOrder(tx.DB, order, order.Total-order.RefundedAmount, nil); err != nil {
+					return err
+				}
+			}
+			err := h.restockItems(tx.DB, order.Items, inventory.Adjustment{
+				Type:    models.StockMovementCancellation,
+				Reason:  "Order canceled by admin",
+				ActorID: &adminID,
+				OrderID: &order.ID,
+			})
+			if err != nil {
+				return err
+			}
+			if err := releaseCoupon(tx.DB, order.ID); err != nil {
+				return err
+			}
+		}
+
+		return setOrderStatus(ctx, tx, order, requestBody.Status)
+	})
+	if errors.Is(err, repository.ErrVersionConflict) {
+		versionConflict(c)
+		return
+	}
+	if err != nil {
+		respondError(c, err, "Failed to update order status")
+		return
+	}
+	h.sendRefund(c, refund)
+	if requestBody.Status == models.OrderStatusCanceled {
+		h.Metrics.OrderCanceled("admin")
+	}
+
 	order.User.Password = ""
 
+	c.Header("ETag", utils.ETag(order.Version))
+
 	c.JSON(http.StatusOK, models.SuccessResponse{
 		Message: "Order status updated successfully",
 		Data:    order,
 	})
 }
+
+// setOrderStatus saves a new status, bumps the order version and publishes
+// an order.status_changed event.
+func setOrderStatus(ctx context.Context, tx *repository.Store, order *models.Order, status string) error {
+	previous := order.Status
+	if err := tx.Orders.SetStatus(ctx, order, status); err != nil {
+		return err
+	}
+	return outbox.Record(tx.DB, outbox.AggregateOrder, order.ID, outbox.EventOrderStatusChanged, outbox.NewOrderData(order, previous))
+}
+
+// restockItems returns the stock reserved by the given order items to the
+// warehouses it was allocated from, recording movements based on movement,
+// and drops their outstanding backorders. The returned stock then goes to
+// other backorders. Products deleted since the order was placed are skipped.
+func (h *Handler) restockItems(tx *gorm.DB, items []models.OrderItem, movement inventory.Adjustment) error {
+	// Release every backorder first so returned stock cannot go back to these items
+	allocated := make([]int, len(items))
+	for i := range items {
+		allocated[i] = items[i].Quantity - items[i].BackorderedQuantity
+		if err := inventory.ReleaseBackorder(tx, &items[i]); err != nil {
+			return err
+		}
+	}
+
+	for i, item := range items {
+		movement.ProductID = item.ProductID
+		err := h.Inventory.Restock(tx, movement, allocated[i], item.Allocations)
+		if errors.Is(err, inventory.ErrProductNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := h.Inventory.FulfilBackorders(tx, item.ProductID, movement.ActorID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyTax calculates tax on each line, net of discounts, for the order's
+// shipping address and stores it on the items and the order.
+func (h *Handler) applyTax(ctx context.Context, order *models.Order, products []models.Product) error {
+	req := tax.Request{
+		Address: tax.Address{Country: order.ShippingAddress.Country, Region: order.ShippingAddress.Region},
+	}
+	for i, item := range order.Items {
+		req.Lines = append(req.Lines, tax.Line{
+			ID:       strconv.Itoa(i),
+			Amount:   roundMoney(item.UnitPrice*float64(item.Quantity) - item.DiscountAmount),
+			TaxClass: products[i].TaxClass,
+		})
+	}
+
+	result, err := h.Tax.Calculate(ctx, req)
+	if err != nil {
+		h.log(ctx).Error("Tax calculation failed", "order_id", order.ID, "error", err)
+		return &requestError{http.StatusBadGateway, "Tax calculation failed"}
+	}
+
+	for i, line := range result.Lines {
+		order.Items[i].TaxRate = line.Rate
+		order.Items[i].TaxAmount = line.Tax
+	}
+	order.TaxTotal = result.TotalTax
+	order.PricesIncludeTax = result.PricesIncludeTax
+	return nil
+}
@@ -0,0 +1,382 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/payments"
+	"github.com/TobiAdeniji94/ecommerce_api/refunds"
+)
+
+// CreatePayment starts a payment for one of the user's orders
+// CreatePayment godoc
+// @Summary Start a payment for an order
+// @Description Creates a payment intent with the payment provider for an order in "Pending" or "PaymentFailed" status. The order's earlier intents that are still open are canceled first, so only the newest can be paid. The client secret is used by the client to confirm the payment with the provider.
+// @Tags Payments
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Payment created successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid order ID or status"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 404 {object} models.ErrorResponse "Order not found"
+// @Failure 409 {object} models.ErrorResponse "Idempotency-Key reused for a different request or still in progress"
+// @Failure 500 {object} models.ErrorResponse "Failed to create payment"
+// @Failure 502 {object} models.ErrorResponse "Payment provider error"
+// @Router /orders/{id}/payments [post]
+func (h *Handler) CreatePayment(c *gin.Context) {
+	userUUID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	orderUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid order ID"})
+		return
+	}
+
+	var order models.Order
+	if err := h.db(c).Where("id = ? AND user_id = ?", orderUUID, userUUID).First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Order not found"})
+		return
+	}
+
+	if !models.CanTransitionOrderStatus(order.Status, models.OrderStatusPaid) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Order cannot be paid. Current status: " + order.Status,
+		})
+		return
+	}
+
+	var attempts int64
+	if err := h.db(c).Model(&models.Payment{}).Where("order_id = ?", order.ID).Count(&attempts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create payment"})
+		return
+	}
+
+	// Only one intent per order may be open, or the customer could be
+	// charged twice
+	if err := h.cancelOpenPayments(c.Request.Context(), h.db(c), order.ID); err != nil {
+		respondError(c, err, "Failed to create payment")
+		return
+	}
+
+	provider := h.Payments
+	intent, err := provider.CreateIntent(c.Request.Context(), payments.CreateIntentParams{
+		OrderID:        order.ID.String(),
+		Amount:         payments.ToMinorUnits(order.Total),
+		Currency:       h.Currency,
+		IdempotencyKey: fmt.Sprintf("order-%s-%d", order.ID, attempts+1),
+	})
+	if err != nil {
+		h.log(c.Request.Context()).Error("Payment provider failed to create intent", "order_id", order.ID, "error", err)
+		c.JSON(http.StatusBadGateway, models.ErrorResponse{Message: "Payment provider error"})
+		return
+	}
+
+	payment := models.Payment{
+		OrderID:     order.ID,
+		Provider:    provider.Name(),
+		ProviderRef: intent.ID,
+		Amount:      order.Total,
+		Currency:    intent.Currency,
+		Status:      paymentStatusFromIntent(intent.Status),
+	}
+	if err := h.db(c).Create(&payment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create payment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Payment created successfully",
+		Data: gin.H{
+			"payment":       payment,
+			"client_secret": intent.ClientSecret,
+		},
+	})
+}
+
+// CapturePayment captures an authorized payment (admin only)
+// CapturePayment godoc
+// @Summary Capture a payment
+// @Description Allows an admin to capture an authorized payment. A successful capture moves the order to "Paid". A payment cannot be captured once its order has been paid or canceled.
+// @Tags Payments
+// @Produce json
+// @Param id path string true "Payment ID"
+// @Param Idempotency-Key header string false "Key that makes retries of this request safe"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Payment captured successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid payment ID or status"
+// @Failure 404 {object} models.ErrorResponse "Payment not found"
+// @Failure 409 {object} models.ErrorResponse "Idempotency-Key reused for a different request or still in progress"
+// @Failure 500 {object} models.ErrorResponse "Failed to capture payment"
+// @Failure 502 {object} models.ErrorResponse "Payment provider error"
+// @Router /payments/{id}/capture [post]
+func (h *Handler) CapturePayment(c *gin.Context) {
+	paymentUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid payment ID"})
+		return
+	}
+
+	var payment models.Payment
+	if err := h.db(c).First(&payment, "id = ?", paymentUUID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Payment not found"})
+		return
+	}
+
+	// The order stays locked while the provider captures, so two payments
+	// of one order cannot both be captured. If the commit fails after the
+	// capture, the provider's payment_intent.succeeded webhook records it.
+	err = h.db(c).Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", payment.OrderID).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&payment, "id = ?", payment.ID).Error; err != nil {
+			return err
+		}
+
+		if payment.Status != models.PaymentStatusRequiresCapture {
+			return &requestError{http.StatusBadRequest, "Payment cannot be captured. Current status: " + payment.Status}
+		}
+		if !models.CanTransitionOrderStatus(order.Status, models.OrderStatusPaid) {
+			return &requestError{http.StatusBadRequest, "Payment cannot be captured. Order status: " + order.Status}
+		}
+
+		intent, err := h.Payments.Capture(c.Request.Context(), payment.ProviderRef)
+		if err != nil {
+			h.log(c.Request.Context()).Error("Payment provider failed to capture", "payment_id", payment.ID, "provider_ref", payment.ProviderRef, "error", err)
+			return &requestError{http.StatusBadGateway, "Payment provider error"}
+		}
+		return h.applyPaymentOutcome(c.Request.Context(), tx, &payment, intent.Status == payments.IntentSucceeded, "")
+	})
+	if err != nil {
+		respondError(c, err, "Failed to capture payment")
+		return
+	}
+	if payment.Status == models.PaymentStatusSucceeded {
+		h.Metrics.PaymentSucceeded(payment.Amount, payment.Currency)
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Payment captured successfully",
+		Data:    payment,
+	})
+}
+
+// HandlePaymentWebhook processes signed events from the payment provider
+// HandlePaymentWebhook godoc
+// @Summary Payment provider webhook
+// @Description Receives signed payment events from the provider. Events are processed once per event ID and move the order to "Paid" or "PaymentFailed".
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param Stripe-Signature header string true "Webhook signature"
+// @Success 200 {object} models.SuccessResponse "Webhook processed"
+// @Failure 400 {object} models.ErrorResponse "Invalid webhook signature or payload"
+// @Failure 404 {object} models.ErrorResponse "Payment not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to process webhook"
+// @Router /payments/webhook [post]
+func (h *Handler) HandlePaymentWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid webhook payload"})
+		return
+	}
+
+	provider := h.Payments
+	event, err := provider.ParseWebhook(payload, c.GetHeader(payments.SignatureHeader))
+	if errors.Is(err, payments.ErrInvalidSignature) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid webhook signature"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid webhook payload"})
+		return
+	}
+
+	if event.Type != payments.EventPaymentSucceeded && event.Type != payments.EventPaymentFailed {
+		c.JSON(http.StatusOK, models.SuccessResponse{Message: "Event ignored"})
+		return
+	}
+
+	// Providers also report payments captured through the API, which must
+	// not be counted as revenue twice
+	var (
+		payment      models.Payment
+		duplicate    bool
+		wasSucceeded bool
+	)
+	err = h.db(c).Transaction(func(tx *gorm.DB) error {
+		// Recording the event first makes redeliveries a no-op
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.PaymentEvent{
+			ID:       event.ID,
+			Provider: provider.Name(),
+			Type:     event.Type,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			duplicate = true
+			return nil
+		}
+
+		if err := tx.Where("provider = ? AND provider_ref = ?", provider.Name(), event.IntentID).First(&payment).Error; err != nil {
+			return &requestError{http.StatusNotFound, "Payment not found"}
+		}
+		wasSucceeded = payment.Status == models.PaymentStatusSucceeded
+
+		return h.applyPaymentOutcome(c.Request.Context(), tx, &payment, event.Type == payments.EventPaymentSucceeded, event.FailureMessage)
+	})
+	if err != nil {
+		respondError(c, err, "Failed to process webhook")
+		return
+	}
+
+	if duplicate {
+		c.JSON(http.StatusOK, models.SuccessResponse{Message: "Event already processed"})
+		return
+	}
+	if payment.Status == models.PaymentStatusSucceeded && !wasSucceeded {
+		h.Metrics.PaymentSucceeded(payment.Amount, payment.Currency)
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{Message: "Webhook processed"})
+}
+
+// applyPaymentOutcome records the payment result and moves the order to
+// Paid or PaymentFailed if its current status allows it. Outcomes for a
+// payment that is already succeeded or canceled arrived out of order and
+// are ignored.
+func (h *Handler) applyPaymentOutcome(ctx context.Context, tx *gorm.DB, payment *models.Payment, succeeded bool, failureMessage string) error {
+	if models.PaymentStatusIsFinal(payment.Status) {
+		h.log(ctx).Warn("Payment outcome ignored for a settled payment",
+			"payment_id", payment.ID, "payment_status", payment.Status, "succeeded", succeeded)
+		return nil
+	}
+
+	target := models.OrderStatusPaid
+	payment.Status = models.PaymentStatusSucceeded
+	if !succeeded {
+		target = models.OrderStatusPaymentFailed
+		payment.Status = models.PaymentStatusFailed
+		payment.FailureMessage = failureMessage
+	}
+	if err := tx.Save(payment).Error; err != nil {
+		return err
+	}
+
+	var order models.Order
+	if err := tx.First(&order, "id = ?", payment.OrderID).Error; err != nil {
+		return err
+	}
+
+	if !models.CanTransitionOrderStatus(order.Status, target) {
+		h.log(ctx).Warn("Order status left unchanged by payment",
+			"order_id", order.ID, "order_status", order.Status, "payment_id", payment.ID, "payment_status", payment.Status)
+		return nil
+	}
+
+	return setOrderStatus(ctx, h.Store.On(tx), &order, target)
+}
+
+// cancelOpenPayments cancels the order's payments that have not been paid
+// or captured, with the provider and then in db.
+func (h *Handler) cancelOpenPayments(ctx context.Context, db *gorm.DB, orderID uuid.UUID) error {
+	var open []models.Payment
+	err := db.Where("order_id = ? AND status IN ?", orderID,
+		[]string{models.PaymentStatusRequiresPayment, models.PaymentStatusRequiresCapture}).Find(&open).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range open {
+		if _, err := h.Payments.Cancel(ctx, open[i].ProviderRef); err != nil {
+			h.log(ctx).Error("Payment provider failed to cancel", "payment_id", open[i].ID, "provider_ref", open[i].ProviderRef, "error", err)
+			return &requestError{http.StatusBadGateway, "Payment provider error"}
+		}
+		if err := db.Model(&open[i]).Update("status", models.PaymentStatusCanceled).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refundOrder records a pending refund of amount of the order's captured
+// payment and adds it to the payment's and the order's refunded amounts.
+// The refund is only sent to the provider by sendRefund, once tx has
+// committed. It returns nil when there is nothing to refund.
+func (h *Handler) refundOrder(tx *gorm.DB, order *models.Order, amount float64, returnID *uuid.UUID) (*models.Refund, error) {
+	amount = roundMoney(amount)
+	if amount <= 0 {
+		return nil, nil
+	}
+
+	var payment models.Payment
+	if err := tx.Where("order_id = ? AND status = ?", order.ID, models.PaymentStatusSucceeded).First(&payment).Error; err != nil {
+		return nil, &requestError{http.StatusBadRequest, "Order has no captured payment to refund"}
+	}
+	if amount > roundMoney(payment.Amount-payment.AmountRefunded) {
+		return nil, &requestError{http.StatusBadRequest, "Refund exceeds the amount paid"}
+	}
+
+	refund := refunds.Pending(payment, amount, returnID, h.Clock.Now())
+	if err := tx.Create(refund).Error; err != nil {
+		return nil, err
+	}
+
+	err := tx.Model(&payment).UpdateColumn("amount_refunded", gorm.Expr("amount_refunded + ?", amount)).Error
+	if err != nil {
+		return nil, err
+	}
+
+	order.RefundedAmount = roundMoney(order.RefundedAmount + amount)
+	order.Version++
+	err = tx.Model(order).Updates(map[string]interface{}{
+		"refunded_amount": order.RefundedAmount,
+		"version":         gorm.Expr("version + 1"),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// paymentStatusFromIntent maps a provider intent status to a payment status.
+func paymentStatusFromIntent(status string) string {
+	switch status {
+	case payments.IntentRequiresCapture:
+		return models.PaymentStatusRequiresCapture
+	case payments.IntentSucceeded:
+		return models.PaymentStatusSucceeded
+	case payments.IntentCanceled:
+		return models.PaymentStatusCanceled
+	default:
+		return models.PaymentStatusRequiresPayment
+	}
+}
+
+// sendRefund sends a refund recorded by refundOrder to the provider. When
+// the provider fails, the refund stays pending and the refunds worker
+// retries it, so the change that caused it still stands.
+func (h *Handler) sendRefund(c *gin.Context, refund *models.Refund) {
+	if refund == nil {
+		return
+	}
+	if err := refunds.Send(c.Request.Context(), h.db(c), h.Payments, refund, h.Clock.Now()); err != nil {
+		h.log(c.Request.Context()).Error("Failed to record refund outcome", "refund_id", refund.ID, "error", err)
+	}
+}
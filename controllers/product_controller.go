@@ -1,13 +1,26 @@
 package controllers
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 
-	"github.com/TobiAdeniji94/ecommerce_api/config"
+	"github.com/TobiAdeniji94/ecommerce_api/catalog"
+	"github.com/TobiAdeniji94/ecommerce_api/inventory"
 	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/outbox"
+	"github.com/TobiAdeniji94/ecommerce_api/repository"
+	"github.com/TobiAdeniji94/ecommerce_api/tax"
+	"github.com/TobiAdeniji94/ecommerce_api/utils"
 )
 
 // CreateProduct allows an admin user to add a new product
@@ -23,7 +36,7 @@ import (
 // @Failure 400 {object} models.ValidationErrorResponse "Invalid product payload"
 // @Failure 500 {object} models.ErrorResponse "Failed to create product"
 // @Router /products [post]
-func CreateProduct(c *gin.Context) {
+func (h *Handler) CreateProduct(c *gin.Context) {
     var input models.ProductInput
     if err := c.ShouldBindJSON(&input); err != nil {
         c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
@@ -34,16 +47,68 @@ func CreateProduct(c *gin.Context) {
         return
     }
 
-    // Map the input to the Product model
+    adminID, ok := currentUserID(c)
+    if !ok {
+        return
+    }
+
+    // Map the input to the Product model; stock is added through the ledger
     product := models.Product{
-        Name:        input.Name,
-        Description: input.Description,
-        Price:       input.Price,
-        Stock:       input.Stock,
+        SKU:               strings.TrimSpace(input.SKU),
+        Name:              input.Name,
+        Description:       input.Description,
+        Category:          input.Category,
+        TaxClass:          input.TaxClass,
+        Price:             input.Price,
+        LowStockThreshold: input.LowStockThreshold,
+        BackorderMode:     input.BackorderMode,
+        ReleaseDate:       input.ReleaseDate,
+        MaxBackorder:      input.MaxBackorder,
+        Weight:            input.Weight,
+        Length:            input.Length,
+        Width:             input.Width,
+        Height:            input.Height,
+    }
+
+    if product.TaxClass == "" {
+        product.TaxClass = tax.DefaultTaxClass
+    }
+    if product.BackorderMode == "" {
+        product.BackorderMode = models.BackorderNone
+    }
+    ctx := c.Request.Context()
+    if h.skuTaken(ctx, product.SKU, uuid.Nil) {
+        c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "A product with this SKU already exists"})
+        return
     }
 
-    // Insert the Product model into the database
-    if err := config.DB.Create(&product).Error; err != nil {
+    // Insert the Product model and its opening stock into the database
+    err := h.Store.Transaction(ctx, func(tx *repository.Store) error {
+        if err := tx.Products.Create(ctx, &product); err != nil {
+            return err
+        }
+        err := h.Inventory.SetStock(tx.DB, inventory.Adjustment{
+            ProductID: product.ID,
+            Type:      models.StockMovementReceipt,
+            Reason:    "Opening stock",
+            ActorID:   &adminID,
+        }, 0, input.Stock)
+        if err != nil {
+            return err
+        }
+
+        created, err := tx.Products.ByID(ctx, product.ID)
+        if err != nil {
+            return err
+        }
+        product.Stock, product.Version = created.Stock, created.Version
+        return nil
+    })
+    if errors.Is(err, repository.ErrDuplicate) {
+        c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "A product with this SKU already exists"})
+        return
+    }
+    if err != nil {
         c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create product"})
         return
     }
@@ -54,19 +119,32 @@ func CreateProduct(c *gin.Context) {
     })
 }
 
-// GetProducts lists all products
+// GetProducts lists all products, optionally filtered
 // GetProducts godoc
 // @Summary Get all products
-// @Description Retrieves a list of all products available in the store
+// @Description Retrieves a list of the products available in the store, optionally filtered
 // @Tags Products
 // @Produce json
+// @Param category query string false "Only products in this category"
+// @Param sku query string false "Only the product with this SKU"
+// @Param q query string false "Case-insensitive search in name and description"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param in_stock query bool false "Only products with (true) or without (false) stock"
 // @Security BearerAuth
 // @Success 200 {object} models.SuccessResponse "Product(s) retrieved successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid filter"
 // @Failure 500 {object} models.ErrorResponse "Failed to retrieve products"
 // @Router /products [get]
-func GetProducts(c *gin.Context) {
-	var products []models.Product
-	if err := config.DB.Find(&products).Error; err != nil {
+func (h *Handler) GetProducts(c *gin.Context) {
+	filter, err := productFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	products, err := h.Store.Products.List(c.Request.Context(), filter)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve products"})
 		return
 	}
@@ -80,16 +158,18 @@ func GetProducts(c *gin.Context) {
 // GetProductByID retrieves a single product by ID
 // GetProductByID godoc
 // @Summary Get product by ID
-// @Description Retrieves a single product by its ID
+// @Description Retrieves a single product by its ID. The response carries an ETag; send it in If-None-Match to get 304 Not Modified when the product is unchanged.
 // @Tags Products
 // @Param id path string true "Product ID"
+// @Param If-None-Match header string false "ETag from a previous response"
 // @Security BearerAuth
 // @Success 200 {object} models.SuccessResponse "Product retrieved successfully"
+// @Success 304 "Product not modified"
 // @Failure 400 {object} models.ErrorResponse "Invalid product ID"
 // @Failure 404 {object} models.ErrorResponse "Product not found"
 // @Failure 500 {object} models.ErrorResponse "Failed to retrieve product"
 // @Router /products/{id} [get]
-func GetProductByID(c *gin.Context) {
+func (h *Handler) GetProductByID(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -97,11 +177,22 @@ func GetProductByID(c *gin.Context) {
 		return
 	}
 
-	var product models.Product
-	if err := config.DB.First(&product, id).Error; err != nil {
+	product, err := h.Store.Products.ByID(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Product not found"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve product"})
+		return
+	}
+
+	etag := utils.ETag(product.Version)
+	c.Header("ETag", etag)
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" && utils.ETagMatches(ifNoneMatch, etag, true) {
+		c.Status(http.StatusNotModified)
+		return
+	}
 
 	c.JSON(http.StatusOK, models.SuccessResponse{
 		Message: "Product retrieved successfully",
@@ -116,13 +207,16 @@ func GetProductByID(c *gin.Context) {
 // @Tags Products
 // @Param id path string true "Product ID"
 // @Param product body models.ProductInput true "Updated product payload"
+// @Param If-Match header string false "ETag of the version being updated"
 // @Security BearerAuth
 // @Success 200 {object} models.SuccessResponse "Product updated successfully"
 // @Failure 400 {object} models.ValidationErrorResponse "Invalid product ID or payload"
 // @Failure 404 {object} models.ErrorResponse "Product not found"
+// @Failure 409 {object} models.ErrorResponse "Product was modified concurrently"
+// @Failure 412 {object} models.ErrorResponse "If-Match does not match the current version"
 // @Failure 500 {object} models.ErrorResponse "Failed to update product"
 // @Router /products/{id} [put]
-func UpdateProduct(c *gin.Context) {
+func (h *Handler) UpdateProduct(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -130,11 +224,19 @@ func UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	var product models.Product
-	if err := config.DB.First(&product, id).Error; err != nil {
+	product, err := h.Store.Products.ByID(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Product not found"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update product"})
+		return
+	}
+
+	if !checkIfMatch(c, product.Version) {
+		return
+	}
 
 	var updateInput models.ProductInput
 	if err := c.ShouldBindJSON(&updateInput); err != nil {
@@ -147,16 +249,108 @@ func UpdateProduct(c *gin.Context) {
 	}
 
 	// Update fields
+	previousStock := product.Stock
+	product.SKU = strings.TrimSpace(updateInput.SKU)
 	product.Name = updateInput.Name
 	product.Description = updateInput.Description
+	product.Category = updateInput.Category
+	product.TaxClass = updateInput.TaxClass
+	if product.TaxClass == "" {
+		product.TaxClass = tax.DefaultTaxClass
+	}
 	product.Price = updateInput.Price
 	product.Stock = updateInput.Stock
+	product.LowStockThreshold = updateInput.LowStockThreshold
+	product.BackorderMode = updateInput.BackorderMode
+	if product.BackorderMode == "" {
+		product.BackorderMode = models.BackorderNone
+	}
+	product.ReleaseDate = updateInput.ReleaseDate
+	product.MaxBackorder = updateInput.MaxBackorder
+	product.Weight = updateInput.Weight
+	product.Length = updateInput.Length
+	product.Width = updateInput.Width
+	product.Height = updateInput.Height
+
+	if h.skuTaken(c.Request.Context(), product.SKU, product.ID) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "A product with this SKU already exists"})
+		return
+	}
+	if !h.saveProductVersion(c, product, previousStock) {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Product updated successfully",
+		Data:    product,
+	})
+}
+
+// PatchProduct partially updates an existing product (admin only)
+// PatchProduct godoc
+// @Summary Partially update a product
+// @Description Applies a JSON Merge Patch (RFC 7396) to a product. Only the fields sent are validated and changed, so a field can be set to zero (e.g. "stock": 0). null resets optional fields (backorder_mode to "none", release_date to unset); name, price and stock cannot be null.
+// @Tags Products
+// @Accept json
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param product body models.ProductPatchInput true "Merge patch with the fields to change"
+// @Param If-Match header string false "ETag of the version being updated"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Product updated successfully"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid product ID or patch"
+// @Failure 404 {object} models.ErrorResponse "Product not found"
+// @Failure 409 {object} models.ErrorResponse "Product was modified concurrently"
+// @Failure 412 {object} models.ErrorResponse "If-Match does not match the current version"
+// @Failure 415 {object} models.ErrorResponse "Unsupported content type"
+// @Failure 500 {object} models.ErrorResponse "Failed to update product"
+// @Router /products/{id} [patch]
+func (h *Handler) PatchProduct(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid product ID"})
+		return
+	}
+
+	switch c.ContentType() {
+	case "application/merge-patch+json", "application/json":
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, models.ErrorResponse{Message: "Content-Type must be application/merge-patch+json"})
+		return
+	}
 
-	if err := config.DB.Save(&product).Error; err != nil {
+	product, err := h.Store.Products.ByID(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Product not found"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update product"})
 		return
 	}
 
+	if !checkIfMatch(c, product.Version) {
+		return
+	}
+
+	patch, validationErrors := bindProductPatch(c)
+	if validationErrors != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{Errors: validationErrors})
+		return
+	}
+
+	previousStock := product.Stock
+	catalog.ApplyPatch(product, *patch)
+
+	if h.skuTaken(c.Request.Context(), product.SKU, product.ID) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "A product with this SKU already exists"})
+		return
+	}
+	if !h.saveProductVersion(c, product, previousStock) {
+		return
+	}
+
 	c.JSON(http.StatusOK, models.SuccessResponse{
 		Message: "Product updated successfully",
 		Data:    product,
@@ -166,16 +360,18 @@ func UpdateProduct(c *gin.Context) {
 // DeleteProduct deletes a product by ID (admin only)
 // DeleteProduct godoc
 // @Summary Delete a product
-// @Description Allows an admin user to delete a product by ID
+// @Description Allows an admin user to delete a product by ID. It is removed from wishlists and its stock alerts are dropped.
 // @Tags Products
 // @Param id path string true "Product ID"
+// @Param If-Match header string false "ETag of the version being deleted"
 // @Security BearerAuth
 // @Success 200 {object} models.SuccessResponse "Product deleted successfully"
 // @Failure 400 {object} models.ErrorResponse "Invalid product ID"
 // @Failure 404 {object} models.ErrorResponse "Product not found"
+// @Failure 412 {object} models.ErrorResponse "If-Match does not match the current version"
 // @Failure 500 {object} models.ErrorResponse "Failed to delete product"
 // @Router /products/{id} [delete]
-func DeleteProduct(c *gin.Context) {
+func (h *Handler) DeleteProduct(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
@@ -183,7 +379,35 @@ func DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	if err := config.DB.Delete(&models.Product{}, id).Error; err != nil {
+	// Without If-Match the product is deleted whatever its version
+	ctx := c.Request.Context()
+	version := 0
+	if c.GetHeader("If-Match") != "" {
+		product, err := h.Store.Products.ByID(ctx, id)
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Product not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to delete product"})
+			return
+		}
+		if !checkIfMatch(c, product.Version) {
+			return
+		}
+		version = product.Version
+	}
+
+	err = h.Store.Products.Delete(ctx, id, version)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Product not found"})
+		return
+	}
+	if errors.Is(err, repository.ErrVersionConflict) {
+		versionConflict(c)
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to delete product"})
 		return
 	}
@@ -192,3 +416,155 @@ func DeleteProduct(c *gin.Context) {
 		Message: "Product deleted successfully",
 	})
 }
+
+// productPatchResets holds the value a merge patch null resets each optional
+// product field to. Fields missing here are required and cannot be null.
+var productPatchResets = map[string]json.RawMessage{
+	"sku":                 json.RawMessage(`""`),
+	"description":         json.RawMessage(`""`),
+	"category":            json.RawMessage(`""`),
+	"tax_class":           json.RawMessage(`""`),
+	"low_stock_threshold": json.RawMessage(`0`),
+	"backorder_mode":      json.RawMessage(`"none"`),
+	"release_date":        json.RawMessage(`"0001-01-01T00:00:00Z"`),
+	"max_backorder":       json.RawMessage(`0`),
+	"weight":              json.RawMessage(`0`),
+	"length":              json.RawMessage(`0`),
+	"width":               json.RawMessage(`0`),
+	"height":              json.RawMessage(`0`),
+}
+
+// bindProductPatch decodes and validates a JSON Merge Patch body. Nulls are
+// replaced by the field's reset value so that only fields present in the
+// patch end up non-nil.
+func bindProductPatch(c *gin.Context) (*models.ProductPatchInput, []models.ValidationError) {
+	var fields map[string]json.RawMessage
+	if err := c.ShouldBindBodyWith(&fields, binding.JSON); err != nil || fields == nil {
+		return nil, []models.ValidationError{{Field: "payload", Message: "Patch must be a JSON object"}}
+	}
+
+	var validationErrors []models.ValidationError
+	for name, value := range fields {
+		if string(value) != "null" {
+			continue
+		}
+		reset, ok := productPatchResets[name]
+		if !ok {
+			validationErrors = append(validationErrors, models.ValidationError{Field: name, Message: "Field cannot be null"})
+			continue
+		}
+		fields[name] = reset
+	}
+	if validationErrors != nil {
+		return nil, validationErrors
+	}
+
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return nil, []models.ValidationError{{Field: "payload", Message: err.Error()}}
+	}
+
+	var patch models.ProductPatchInput
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patch); err != nil {
+		return nil, []models.ValidationError{{Field: "payload", Message: err.Error()}}
+	}
+	if err := binding.Validator.ValidateStruct(&patch); err != nil {
+		return nil, []models.ValidationError{{Field: "payload", Message: err.Error()}}
+	}
+	return &patch, nil
+}
+
+// productFilter reads the product listing filters from the query string.
+func productFilter(c *gin.Context) (catalog.Filter, error) {
+	filter := catalog.Filter{
+		Category: c.Query("category"),
+		SKU:      strings.TrimSpace(c.Query("sku")),
+		Query:    strings.TrimSpace(c.Query("q")),
+	}
+
+	for name, target := range map[string]**float64{"min_price": &filter.MinPrice, "max_price": &filter.MaxPrice} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil || price < 0 {
+			return filter, fmt.Errorf("Invalid %s", name)
+		}
+		*target = &price
+	}
+
+	if value := c.Query("in_stock"); value != "" {
+		inStock, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("Invalid in_stock")
+		}
+		filter.InStock = &inStock
+	}
+	return filter, nil
+}
+
+// skuTaken reports whether another product than id already uses sku.
+// Products without a SKU never clash. A failed check reports the SKU as
+// free and leaves the unique index to reject it.
+func (h *Handler) skuTaken(ctx context.Context, sku string, id uuid.UUID) bool {
+	taken, err := h.Store.Products.SKUTaken(ctx, sku, id)
+	return err == nil && taken
+}
+
+// saveProductVersion writes the product's editable fields if nobody changed
+// the product since it was read, bumping its version. A stock change is
+// recorded in the inventory ledger as an adjustment from previousStock, and
+// a product.updated event is published.
+// It writes the error response and returns false on failure.
+func (h *Handler) saveProductVersion(c *gin.Context, product *models.Product, previousStock int) bool {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return false
+	}
+
+	ctx := c.Request.Context()
+	stock := product.Stock
+	product.Stock = previousStock
+	err := h.Store.Transaction(ctx, func(tx *repository.Store) error {
+		if err := tx.Products.Update(ctx, product); err != nil {
+			return err
+		}
+		if stock != previousStock {
+			// Every stock change bumps the version, so previousStock is still current
+			err := h.Inventory.SetStock(tx.DB, inventory.Adjustment{
+				ProductID: product.ID,
+				Type:      models.StockMovementAdjustment,
+				Reason:    "Stock set by product update",
+				ActorID:   &adminID,
+			}, previousStock, stock)
+			if err != nil {
+				return err
+			}
+			current, err := tx.Products.ByID(ctx, product.ID)
+			if err != nil {
+				return err
+			}
+			product.Stock, product.Backordered, product.Version = current.Stock, current.Backordered, current.Version
+		}
+
+		return outbox.Record(tx.DB, outbox.AggregateProduct, product.ID, outbox.EventProductUpdated, product)
+	})
+	if errors.Is(err, repository.ErrVersionConflict) {
+		versionConflict(c)
+		return false
+	}
+	if errors.Is(err, repository.ErrDuplicate) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "A product with this SKU already exists"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update product"})
+		return false
+	}
+
+	c.Header("ETag", utils.ETag(product.Version))
+	return true
+}
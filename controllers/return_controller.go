@@ -0,0 +1,340 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/TobiAdeniji94/ecommerce_api/inventory"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// CreateReturn opens a return request for items of a delivered order
+// CreateReturn godoc
+// @Summary Request a return
+// @Description Allows an authenticated user to return some or all items of a delivered order
+// @Tags Returns
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param return body models.CreateReturnInput true "Return payload"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Return requested successfully"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid order ID, payload, status or quantity"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 404 {object} models.ErrorResponse "Order not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to create return request"
+// @Router /orders/{id}/returns [post]
+func (h *Handler) CreateReturn(c *gin.Context) {
+	userUUID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	orderUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid order ID"})
+		return
+	}
+
+	var input models.CreateReturnInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+			Errors: []models.ValidationError{
+				{Field: "payload", Message: err.Error()},
+			},
+		})
+		return
+	}
+
+	returnRequest := models.ReturnRequest{
+		OrderID: orderUUID,
+		UserID:  userUUID,
+		Reason:  input.Reason,
+		Status:  models.ReturnStatusRequested,
+	}
+	err = h.db(c).Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").
+			Where("id = ? AND user_id = ?", orderUUID, userUUID).First(&order).Error; err != nil {
+			return &requestError{http.StatusNotFound, "Order not found"}
+		}
+
+		if order.Status != models.OrderStatusDelivered {
+			return &requestError{http.StatusBadRequest, "Only delivered orders can be returned. Current status: " + order.Status}
+		}
+
+		returnable, err := returnableQuantities(tx, order)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range input.Items {
+			itemUUID, err := uuid.Parse(item.OrderItemID)
+			if err != nil {
+				return &requestError{http.StatusBadRequest, "Invalid order item ID"}
+			}
+
+			remaining, found := returnable[itemUUID]
+			if !found {
+				return &requestError{http.StatusBadRequest, "Order item not found: " + item.OrderItemID}
+			}
+			if item.Quantity > remaining {
+				return &requestError{http.StatusBadRequest, "At most " + strconv.Itoa(remaining) + " of order item " + item.OrderItemID + " can be returned"}
+			}
+			returnable[itemUUID] -= item.Quantity
+
+			returnRequest.Items = append(returnRequest.Items, models.ReturnItem{
+				OrderItemID: itemUUID,
+				Quantity:    item.Quantity,
+			})
+		}
+
+		return tx.Create(&returnRequest).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to create return request")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Return requested successfully",
+		Data:    returnRequest,
+	})
+}
+
+// GetReturns lists return requests
+// GetReturns godoc
+// @Summary List return requests
+// @Description Lists the authenticated user's return requests. Admins see every return request and can filter by status.
+// @Tags Returns
+// @Produce json
+// @Param status query string false "Filter by status (Requested, Approved, Rejected)"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Return requests retrieved successfully"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 500 {object} models.ErrorResponse "Failed to fetch return requests"
+// @Router /returns [get]
+func (h *Handler) GetReturns(c *gin.Context) {
+	userUUID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	query := h.db(c).Preload("Items.OrderItem.Product").Order("created_at DESC")
+	if role, _ := c.Get("role"); role != "admin" {
+		query = query.Where("user_id = ?", userUUID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var returns []models.ReturnRequest
+	if err := query.Find(&returns).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch return requests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Return requests retrieved successfully",
+		Data:    returns,
+	})
+}
+
+// ApproveReturn approves a return, restocks the items and refunds them (admin only)
+// ApproveReturn godoc
+// @Summary Approve a return
+// @Description Allows an admin to approve a return request. Returned items are restocked and their value is refunded through the payment provider. If the provider is unavailable the refund is retried in the background. An order whose full total has been refunded moves to "Refunded".
+// @Tags Returns
+// @Accept json
+// @Produce json
+// @Param id path string true "Return request ID"
+// @Param review body models.ReviewReturnInput false "Optional note"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Return approved successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid return request ID or status"
+// @Failure 404 {object} models.ErrorResponse "Return request not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to approve return"
+// @Router /returns/{id}/approve [put]
+func (h *Handler) ApproveReturn(c *gin.Context) {
+	h.reviewReturn(c, true)
+}
+
+// RejectReturn rejects a return request (admin only)
+// RejectReturn godoc
+// @Summary Reject a return
+// @Description Allows an admin to reject a return request with an optional note
+// @Tags Returns
+// @Accept json
+// @Produce json
+// @Param id path string true "Return request ID"
+// @Param review body models.ReviewReturnInput false "Optional note"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Return rejected successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid return request ID or status"
+// @Failure 404 {object} models.ErrorResponse "Return request not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to reject return"
+// @Router /returns/{id}/reject [put]
+func (h *Handler) RejectReturn(c *gin.Context) {
+	h.reviewReturn(c, false)
+}
+
+// reviewReturn moves a requested return to Approved or Rejected.
+func (h *Handler) reviewReturn(c *gin.Context, approve bool) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	returnUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid return request ID"})
+		return
+	}
+
+	// The note is optional, so an empty body is fine
+	var input models.ReviewReturnInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+				Errors: []models.ValidationError{
+					{Field: "payload", Message: err.Error()},
+				},
+			})
+			return
+		}
+	}
+
+	var returnRequest models.ReturnRequest
+	var refund *models.Refund
+	err = h.db(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&returnRequest, "id = ?", returnUUID).Error; err != nil {
+			return &requestError{http.StatusNotFound, "Return request not found"}
+		}
+		if err := tx.Preload("OrderItem.Allocations").Where("return_request_id = ?", returnRequest.ID).
+			Find(&returnRequest.Items).Error; err != nil {
+			return err
+		}
+
+		if returnRequest.Status != models.ReturnStatusRequested {
+			return &requestError{http.StatusBadRequest, "Return request has already been reviewed. Current status: " + returnRequest.Status}
+		}
+
+		returnRequest.AdminNote = input.Note
+		if !approve {
+			returnRequest.Status = models.ReturnStatusRejected
+			return tx.Model(&returnRequest).Updates(map[string]interface{}{
+				"status":     returnRequest.Status,
+				"admin_note": returnRequest.AdminNote,
+			}).Error
+		}
+
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, "id = ?", returnRequest.OrderID).Error; err != nil {
+			return err
+		}
+
+		restock := make([]models.OrderItem, 0, len(returnRequest.Items))
+		var refundAmount float64
+		for _, item := range returnRequest.Items {
+			restock = append(restock, models.OrderItem{
+				ProductID:   item.OrderItem.ProductID,
+				Quantity:    item.Quantity,
+				Allocations: item.OrderItem.Allocations,
+			})
+			// Refund what was actually paid per unit: net of any coupon discount,
+			// plus tax when it was charged on top of the price
+			line := item.OrderItem.UnitPrice*float64(item.OrderItem.Quantity) - item.OrderItem.DiscountAmount
+			if !order.PricesIncludeTax {
+				line += item.OrderItem.TaxAmount
+			}
+			refundAmount += line / float64(item.OrderItem.Quantity) * float64(item.Quantity)
+		}
+		err := h.restockItems(tx, restock, inventory.Adjustment{
+			Type:            models.StockMovementReturn,
+			Reason:          "Return approved",
+			ActorID:         &adminID,
+			OrderID:         &order.ID,
+			ReturnRequestID: &returnRequest.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Never refund more than what is left on the order
+		refundAmount = roundMoney(refundAmount)
+		if remaining := roundMoney(order.Total - order.RefundedAmount); refundAmount > remaining {
+			refundAmount = remaining
+		}
+		if refund, err = h.refundOrder(tx, &order, refundAmount, &returnRequest.ID); err != nil {
+			return err
+		}
+
+		// Shipping is not refunded on returns, so returning every item refunds the order in full
+		if order.RefundedAmount >= roundMoney(order.Total-order.ShippingCost) && models.CanTransitionOrderStatus(order.Status, models.OrderStatusRefunded) {
+			if err := setOrderStatus(c.Request.Context(), h.Store.On(tx), &order, models.OrderStatusRefunded); err != nil {
+				return err
+			}
+		}
+
+		returnRequest.Status = models.ReturnStatusApproved
+		returnRequest.RefundAmount = refundAmount
+		return tx.Model(&returnRequest).Updates(map[string]interface{}{
+			"status":        returnRequest.Status,
+			"admin_note":    returnRequest.AdminNote,
+			"refund_amount": returnRequest.RefundAmount,
+		}).Error
+	})
+	if err != nil {
+		if approve {
+			respondError(c, err, "Failed to approve return")
+		} else {
+			respondError(c, err, "Failed to reject return")
+		}
+		return
+	}
+	h.sendRefund(c, refund)
+
+	message := "Return rejected successfully"
+	if approve {
+		message = "Return approved successfully"
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: message,
+		Data:    returnRequest,
+	})
+}
+
+// returnableQuantities returns, per order item, the quantity not already
+// covered by a requested or approved return.
+func returnableQuantities(tx *gorm.DB, order models.Order) (map[uuid.UUID]int, error) {
+	returnable := make(map[uuid.UUID]int, len(order.Items))
+	for _, item := range order.Items {
+		returnable[item.ID] = item.Quantity
+	}
+
+	var returned []struct {
+		OrderItemID uuid.UUID
+		Quantity    int
+	}
+	err := tx.Model(&models.ReturnItem{}).
+		Select("return_items.order_item_id, SUM(return_items.quantity) AS quantity").
+		Joins("JOIN return_requests ON return_requests.id = return_items.return_request_id").
+		Where("return_requests.order_id = ? AND return_requests.status IN ?", order.ID,
+			[]string{models.ReturnStatusRequested, models.ReturnStatusApproved}).
+		Group("return_items.order_item_id").
+		Scan(&returned).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range returned {
+		returnable[r.OrderItemID] -= r.Quantity
+	}
+	return returnable, nil
+}
@@ -0,0 +1,441 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// Product review page sizes.
+const (
+	defaultReviewLimit = 20
+	maxReviewLimit     = 100
+)
+
+// CreateReview posts a review of a product the user has received
+// CreateReview godoc
+// @Summary Review a product
+// @Description Allows a user with a delivered order containing the product to review it, once per product. Reviews are published after an admin approves them.
+// @Tags Reviews
+// @Accept json
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param review body models.ReviewInput true "Review payload"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Review submitted successfully"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid product ID or payload, or product already reviewed"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Product has not been delivered to the user"
+// @Failure 404 {object} models.ErrorResponse "Product not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to submit review"
+// @Router /products/{id}/reviews [post]
+func (h *Handler) CreateReview(c *gin.Context) {
+	userUUID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	productUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid product ID"})
+		return
+	}
+
+	var input models.ReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+			Errors: []models.ValidationError{
+				{Field: "payload", Message: err.Error()},
+			},
+		})
+		return
+	}
+
+	var product models.Product
+	if err := h.db(c).Select("id").First(&product, "id = ?", productUUID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Product not found"})
+		return
+	}
+
+	var delivered int64
+	err = h.db(c).Model(&models.OrderItem{}).
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.user_id = ? AND orders.status = ? AND order_items.product_id = ?", userUUID, models.OrderStatusDelivered, productUUID).
+		Count(&delivered).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to submit review"})
+		return
+	}
+	if delivered == 0 {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Message: "Only customers who have received this product can review it"})
+		return
+	}
+
+	var existing models.Review
+	if err := h.db(c).Where("product_id = ? AND user_id = ?", productUUID, userUUID).First(&existing).Error; err == nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "You have already reviewed this product; edit your review instead"})
+		return
+	}
+
+	review := models.Review{
+		ProductID: productUUID,
+		UserID:    userUUID,
+		Rating:    input.Rating,
+		Title:     input.Title,
+		Body:      input.Body,
+		Status:    models.ReviewStatusPending,
+	}
+	if err := h.db(c).Create(&review).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to submit review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Review submitted successfully",
+		Data:    review,
+	})
+}
+
+// GetProductReviews lists the approved reviews of a product
+// GetProductReviews godoc
+// @Summary Get product reviews
+// @Description Lists a product's approved reviews, newest first. The product's rating_average and rating_count summarise them.
+// @Tags Reviews
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param limit query int false "Maximum number of reviews (default 20, max 100)"
+// @Param offset query int false "Number of reviews to skip"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Reviews retrieved successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid product ID, limit or offset"
+// @Failure 404 {object} models.ErrorResponse "Product not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to fetch reviews"
+// @Router /products/{id}/reviews [get]
+func (h *Handler) GetProductReviews(c *gin.Context) {
+	productUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid product ID"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultReviewLimit)))
+	if err != nil || limit < 1 || limit > maxReviewLimit {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "limit must be between 1 and " + strconv.Itoa(maxReviewLimit)})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "offset must not be negative"})
+		return
+	}
+
+	var product models.Product
+	if err := h.db(c).Select("id").First(&product, "id = ?", productUUID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Product not found"})
+		return
+	}
+
+	var reviews []models.Review
+	err = h.db(c).Where("product_id = ? AND status = ?", productUUID, models.ReviewStatusApproved).
+		Order("created_at DESC").Limit(limit).Offset(offset).Find(&reviews).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Reviews retrieved successfully",
+		Data:    reviews,
+	})
+}
+
+// GetReviews lists reviews for their authors and for moderation
+// GetReviews godoc
+// @Summary List reviews
+// @Description Lists the authenticated user's reviews in any status. Admins see every review and can filter by status and product, e.g. status=Pending for the moderation queue.
+// @Tags Reviews
+// @Produce json
+// @Param status query string false "Filter by status (Pending, Approved, Rejected)"
+// @Param product_id query string false "Filter by product"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Reviews retrieved successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid product ID"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 500 {object} models.ErrorResponse "Failed to fetch reviews"
+// @Router /reviews [get]
+func (h *Handler) GetReviews(c *gin.Context) {
+	userUUID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	query := h.db(c).Order("created_at DESC")
+	if role, _ := c.Get("role"); role != "admin" {
+		query = query.Where("user_id = ?", userUUID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if productID := c.Query("product_id"); productID != "" {
+		productUUID, err := uuid.Parse(productID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid product ID"})
+			return
+		}
+		query = query.Where("product_id = ?", productUUID)
+	}
+
+	var reviews []models.Review
+	if err := query.Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Reviews retrieved successfully",
+		Data:    reviews,
+	})
+}
+
+// UpdateReview lets the author edit their review
+// UpdateReview godoc
+// @Summary Edit a review
+// @Description Allows the author to change their review. The edited review goes back to Pending until an admin approves it again.
+// @Tags Reviews
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param review body models.ReviewInput true "Review payload"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Review updated successfully"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid review ID or payload"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 404 {object} models.ErrorResponse "Review not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to update review"
+// @Router /reviews/{id} [put]
+func (h *Handler) UpdateReview(c *gin.Context) {
+	userUUID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	reviewUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid review ID"})
+		return
+	}
+
+	var input models.ReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+			Errors: []models.ValidationError{
+				{Field: "payload", Message: err.Error()},
+			},
+		})
+		return
+	}
+
+	var review models.Review
+	err = h.db(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", reviewUUID, userUUID).First(&review).Error; err != nil {
+			return &requestError{http.StatusNotFound, "Review not found"}
+		}
+
+		if err := updateRating(tx, review, -1); err != nil {
+			return err
+		}
+		review.Rating = input.Rating
+		review.Title = input.Title
+		review.Body = input.Body
+		review.Status = models.ReviewStatusPending
+		review.AdminNote = ""
+		return tx.Save(&review).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to update review")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Review updated successfully",
+		Data:    review,
+	})
+}
+
+// DeleteReview deletes a review
+// DeleteReview godoc
+// @Summary Delete a review
+// @Description Allows the author or an admin to delete a review
+// @Tags Reviews
+// @Param id path string true "Review ID"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Review deleted successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid review ID"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 404 {object} models.ErrorResponse "Review not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to delete review"
+// @Router /reviews/{id} [delete]
+func (h *Handler) DeleteReview(c *gin.Context) {
+	userUUID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	reviewUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid review ID"})
+		return
+	}
+
+	err = h.db(c).Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", reviewUUID)
+		if role, _ := c.Get("role"); role != "admin" {
+			query = query.Where("user_id = ?", userUUID)
+		}
+
+		var review models.Review
+		if err := query.First(&review).Error; err != nil {
+			return &requestError{http.StatusNotFound, "Review not found"}
+		}
+
+		if err := updateRating(tx, review, -1); err != nil {
+			return err
+		}
+		return tx.Delete(&review).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to delete review")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Review deleted successfully",
+	})
+}
+
+// ApproveReview publishes a review (admin only)
+// ApproveReview godoc
+// @Summary Approve a review
+// @Description Allows an admin to publish a pending or previously rejected review. Its rating is added to the product's rating.
+// @Tags Reviews
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param moderation body models.ModerateReviewInput false "Optional note"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Review approved successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid review ID or review already approved"
+// @Failure 404 {object} models.ErrorResponse "Review not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to approve review"
+// @Router /reviews/{id}/approve [put]
+func (h *Handler) ApproveReview(c *gin.Context) {
+	h.moderateReview(c, models.ReviewStatusApproved)
+}
+
+// RejectReview hides a review (admin only)
+// RejectReview godoc
+// @Summary Reject a review
+// @Description Allows an admin to reject a pending review or take down an approved one. Its rating is removed from the product's rating.
+// @Tags Reviews
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param moderation body models.ModerateReviewInput false "Optional note"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Review rejected successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid review ID or review already rejected"
+// @Failure 404 {object} models.ErrorResponse "Review not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to reject review"
+// @Router /reviews/{id}/reject [put]
+func (h *Handler) RejectReview(c *gin.Context) {
+	h.moderateReview(c, models.ReviewStatusRejected)
+}
+
+// moderateReview moves a review to Approved or Rejected and keeps the
+// product's rating in step.
+func (h *Handler) moderateReview(c *gin.Context, status string) {
+	action := "approve"
+	if status == models.ReviewStatusRejected {
+		action = "reject"
+	}
+
+	reviewUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid review ID"})
+		return
+	}
+
+	// The note is optional, so an empty body is fine
+	var input models.ModerateReviewInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+				Errors: []models.ValidationError{
+					{Field: "payload", Message: err.Error()},
+				},
+			})
+			return
+		}
+	}
+
+	var review models.Review
+	err = h.db(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&review, "id = ?", reviewUUID).Error; err != nil {
+			return &requestError{http.StatusNotFound, "Review not found"}
+		}
+		if review.Status == status {
+			return &requestError{http.StatusBadRequest, "Review is already " + review.Status}
+		}
+
+		if err := updateRating(tx, review, -1); err != nil {
+			return err
+		}
+		review.Status = status
+		review.AdminNote = input.Note
+		if err := updateRating(tx, review, 1); err != nil {
+			return err
+		}
+
+		return tx.Model(&review).Updates(map[string]interface{}{
+			"status":     review.Status,
+			"admin_note": review.AdminNote,
+		}).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to "+action+" review")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Review " + strings.ToLower(status) + " successfully",
+		Data:    review,
+	})
+}
+
+// updateRating adds (sign 1) or removes (sign -1) an approved review's
+// rating from its product's aggregates. The update is relative, so
+// concurrent reviews of the same product do not overwrite each other.
+// Reviews that are not approved do not count and are ignored.
+func updateRating(tx *gorm.DB, review models.Review, sign int) error {
+	if review.Status != models.ReviewStatusApproved {
+		return nil
+	}
+
+	count, total := sign, sign*review.Rating
+	return tx.Model(&models.Product{}).Where("id = ?", review.ProductID).
+		UpdateColumns(map[string]interface{}{
+			"rating_count": gorm.Expr("rating_count + ?", count),
+			"rating_total": gorm.Expr("rating_total + ?", total),
+			"rating_average": gorm.Expr("CASE WHEN rating_count + ? = 0 THEN 0 ELSE (rating_total + ?) * 1.0 / (rating_count + ?) END",
+				count, total, count),
+			"version": gorm.Expr("version + 1"),
+		}).Error
+}
@@ -0,0 +1,369 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// CreateShippingMethod allows an admin user to add a shipping method
+// CreateShippingMethod godoc
+// @Summary Create a shipping method
+// @Description Allows an admin user to add a flat or weight-based shipping method, optionally free over an order value
+// @Tags Shipping
+// @Accept json
+// @Produce json
+// @Param method body models.ShippingMethodInput true "Shipping method payload"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Shipping method created successfully"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid shipping method payload or duplicate code"
+// @Failure 500 {object} models.ErrorResponse "Failed to create shipping method"
+// @Router /shipping-methods [post]
+func (h *Handler) CreateShippingMethod(c *gin.Context) {
+	var input models.ShippingMethodInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+			Errors: []models.ValidationError{
+				{Field: "payload", Message: err.Error()},
+			},
+		})
+		return
+	}
+
+	var method models.ShippingMethod
+	applyShippingMethodInput(&method, input)
+
+	var existing models.ShippingMethod
+	if err := h.db(c).Where("code = ?", method.Code).First(&existing).Error; err == nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "A shipping method with this code already exists"})
+		return
+	}
+
+	if err := h.db(c).Create(&method).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create shipping method"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Shipping method created successfully",
+		Data:    method,
+	})
+}
+
+// GetShippingMethods lists the available shipping methods
+// GetShippingMethods godoc
+// @Summary Get shipping methods
+// @Description Lists active shipping methods. Admins also see inactive ones.
+// @Tags Shipping
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Shipping method(s) retrieved successfully"
+// @Failure 500 {object} models.ErrorResponse "Failed to retrieve shipping methods"
+// @Router /shipping-methods [get]
+func (h *Handler) GetShippingMethods(c *gin.Context) {
+	query := h.db(c).Order("name")
+	if role, _ := c.Get("role"); role != "admin" {
+		query = query.Where("active = ?", true)
+	}
+
+	var methods []models.ShippingMethod
+	if err := query.Find(&methods).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve shipping methods"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Shipping method(s) retrieved successfully",
+		Data:    methods,
+	})
+}
+
+// UpdateShippingMethod modifies a shipping method (admin only)
+// UpdateShippingMethod godoc
+// @Summary Update a shipping method
+// @Description Allows an admin user to update a shipping method by ID
+// @Tags Shipping
+// @Accept json
+// @Produce json
+// @Param id path string true "Shipping method ID"
+// @Param method body models.ShippingMethodInput true "Updated shipping method payload"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Shipping method updated successfully"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid shipping method ID, payload or duplicate code"
+// @Failure 404 {object} models.ErrorResponse "Shipping method not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to update shipping method"
+// @Router /shipping-methods/{id} [put]
+func (h *Handler) UpdateShippingMethod(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid shipping method ID"})
+		return
+	}
+
+	var method models.ShippingMethod
+	if err := h.db(c).First(&method, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Shipping method not found"})
+		return
+	}
+
+	var input models.ShippingMethodInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+			Errors: []models.ValidationError{
+				{Field: "payload", Message: err.Error()},
+			},
+		})
+		return
+	}
+	applyShippingMethodInput(&method, input)
+
+	var existing models.ShippingMethod
+	if err := h.db(c).Where("code = ? AND id <> ?", method.Code, method.ID).First(&existing).Error; err == nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "A shipping method with this code already exists"})
+		return
+	}
+
+	if err := h.db(c).Save(&method).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update shipping method"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Shipping method updated successfully",
+		Data:    method,
+	})
+}
+
+// DeleteShippingMethod deletes a shipping method (admin only)
+// DeleteShippingMethod godoc
+// @Summary Delete a shipping method
+// @Description Allows an admin user to delete a shipping method by ID
+// @Tags Shipping
+// @Param id path string true "Shipping method ID"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Shipping method deleted successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid shipping method ID"
+// @Failure 404 {object} models.ErrorResponse "Shipping method not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to delete shipping method"
+// @Router /shipping-methods/{id} [delete]
+func (h *Handler) DeleteShippingMethod(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid shipping method ID"})
+		return
+	}
+
+	result := h.db(c).Delete(&models.ShippingMethod{}, "id = ?", id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to delete shipping method"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Shipping method not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Shipping method deleted successfully",
+	})
+}
+
+// QuoteShipping returns the shipping cost of a set of items for each active method
+// QuoteShipping godoc
+// @Summary Quote shipping
+// @Description Calculates the shipping cost of the given items with every active shipping method. With a coupon code the quote is priced on the discounted subtotal, as the order would be; the coupon's use is not counted.
+// @Tags Shipping
+// @Accept json
+// @Produce json
+// @Param quote body models.ShippingQuoteInput true "Items to ship"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Shipping quoted successfully"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid payload, unknown product or invalid coupon"
+// @Failure 500 {object} models.ErrorResponse "Failed to quote shipping"
+// @Router /shipping-methods/quote [post]
+func (h *Handler) QuoteShipping(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input models.ShippingQuoteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+			Errors: []models.ValidationError{
+				{Field: "payload", Message: err.Error()},
+			},
+		})
+		return
+	}
+
+	var order models.Order
+	products := make([]models.Product, 0, len(input.Items))
+	for _, item := range input.Items {
+		var product models.Product
+		if err := h.db(c).First(&product, "id = ?", item.ProductID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Product not found: " + item.ProductID})
+			return
+		}
+		products = append(products, product)
+		order.Items = append(order.Items, models.OrderItem{ProductID: product.ID, Quantity: item.Quantity, UnitPrice: product.Price})
+		order.Subtotal += product.Price * float64(item.Quantity)
+	}
+	order.Subtotal = roundMoney(order.Subtotal)
+
+	// Check the coupon as placing the order would, then roll back so its
+	// use is not counted
+	if input.CouponCode != "" {
+		err := h.db(c).Transaction(func(tx *gorm.DB) error {
+			if _, err := h.applyCoupon(tx, userID, &order, products, input.CouponCode); err != nil {
+				return err
+			}
+			return errQuoteOnly
+		})
+		if !errors.Is(err, errQuoteOnly) {
+			respondError(c, err, "Failed to quote shipping")
+			return
+		}
+	}
+
+	var methods []models.ShippingMethod
+	if err := h.db(c).Where("active = ?", true).Order("name").Find(&methods).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to quote shipping"})
+		return
+	}
+
+	base, weight := shippingBase(&order), shippingWeight(order.Items, products)
+	quotes := make([]gin.H, 0, len(methods))
+	for _, method := range methods {
+		quotes = append(quotes, gin.H{
+			"shipping_method": method,
+			"cost":            method.Quote(base, weight),
+		})
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Shipping quoted successfully",
+		Data:    quotes,
+	})
+}
+
+// CreateShipment records a shipment with tracking details for an order (admin only)
+// CreateShipment godoc
+// @Summary Create a shipment
+// @Description Allows an admin user to record a shipment with carrier and tracking number for a paid order. An order needs a shipment before it can be marked "Shipped".
+// @Tags Shipping
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param shipment body models.CreateShipmentInput true "Shipment payload"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Shipment created successfully"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid order ID, payload or status"
+// @Failure 404 {object} models.ErrorResponse "Order not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to create shipment"
+// @Router /orders/{id}/shipments [post]
+func (h *Handler) CreateShipment(c *gin.Context) {
+	orderUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid order ID"})
+		return
+	}
+
+	var input models.CreateShipmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+			Errors: []models.ValidationError{
+				{Field: "payload", Message: err.Error()},
+			},
+		})
+		return
+	}
+
+	var order models.Order
+	if err := h.db(c).First(&order, "id = ?", orderUUID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Order not found"})
+		return
+	}
+
+	if order.Status != models.OrderStatusPaid && order.Status != models.OrderStatusShipped {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "Only paid orders can be shipped. Current status: " + order.Status,
+		})
+		return
+	}
+
+	shipment := models.Shipment{
+		OrderID:        order.ID,
+		Carrier:        input.Carrier,
+		TrackingNumber: input.TrackingNumber,
+		TrackingURL:    input.TrackingURL,
+		ShippedAt:      h.Clock.Now(),
+	}
+	if input.ShippedAt != nil {
+		shipment.ShippedAt = *input.ShippedAt
+	}
+
+	if err := h.db(c).Create(&shipment).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create shipment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Shipment created successfully",
+		Data:    shipment,
+	})
+}
+
+// applyShippingMethodInput copies a validated payload onto the shipping method.
+func applyShippingMethodInput(method *models.ShippingMethod, input models.ShippingMethodInput) {
+	method.Name = input.Name
+	method.Code = strings.ToLower(strings.TrimSpace(input.Code))
+	method.Carrier = input.Carrier
+	method.RateType = input.RateType
+	method.FlatRate = input.FlatRate
+	method.BaseRate = input.BaseRate
+	method.RatePerKg = input.RatePerKg
+	method.FreeOver = input.FreeOver
+
+	method.Active = true
+	if input.Active != nil {
+		method.Active = *input.Active
+	}
+}
+
+// errQuoteOnly rolls back the transaction a shipping quote checks its coupon in.
+var errQuoteOnly = errors.New("quote only")
+
+// applyShipping prices the order with the chosen shipping method.
+func applyShipping(tx *gorm.DB, order *models.Order, products []models.Product, methodID string) error {
+	var method models.ShippingMethod
+	if err := tx.Where("id = ? AND active = ?", methodID, true).First(&method).Error; err != nil {
+		return &requestError{http.StatusBadRequest, "Shipping method not found: " + methodID}
+	}
+
+	order.ShippingMethodID = &method.ID
+	order.ShippingCost = method.Quote(shippingBase(order), shippingWeight(order.Items, products))
+	return nil
+}
+
+// shippingBase is the order value shipping is priced on: the subtotal after
+// coupon discounts, so free-shipping thresholds apply to what is paid.
+func shippingBase(order *models.Order) float64 {
+	return roundMoney(order.Subtotal - order.DiscountAmount)
+}
+
+// shippingWeight is the billable weight of the items; products[i] is the
+// product of items[i].
+func shippingWeight(items []models.OrderItem, products []models.Product) float64 {
+	var weight float64
+	for i, item := range items {
+		weight += products[i].ShippingWeight() * float64(item.Quantity)
+	}
+	return weight
+}
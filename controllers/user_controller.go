@@ -1,15 +1,14 @@
 package controllers
 
 import (
-	"log"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 
-	"github.com/TobiAdeniji94/ecommerce_api/config"
 	"github.com/TobiAdeniji94/ecommerce_api/models"
-	"github.com/TobiAdeniji94/ecommerce_api/utils"
+	"github.com/TobiAdeniji94/ecommerce_api/repository"
 )
 
 // RegisterUser handles user signup
@@ -24,7 +23,7 @@ import (
 // @Failure 400 {object} models.ValidationErrorResponse "Validation errors"
 // @Failure 500 {object} models.ErrorResponse "Failed to create user"
 // @Router /users/register [post]
-func RegisterUser(c *gin.Context) {
+func (h *Handler) RegisterUser(c *gin.Context) {
 	var input models.UserInput
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -58,15 +57,6 @@ func RegisterUser(c *gin.Context) {
 		return
 	}
 
-	// Check if the user already exists
-	var existingUser models.User
-	if err := config.DB.Where("email = ?", input.Email).First(&existingUser).Error; err == nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Message: "A user with this email already exists",
-		})
-		return
-	}
-
 	// Hash the password before saving
 	hashedPassword, err := HashPassword(input.Password)
 	if err != nil {
@@ -88,8 +78,15 @@ func RegisterUser(c *gin.Context) {
 		user.Role = "user"
 	}
 
-	// Create the user in DB
-	if err := config.DB.Create(&user).Error; err != nil {
+	// Create the user in DB; the unique email index rejects existing users
+	err = h.Store.Users.Create(c.Request.Context(), &user)
+	if errors.Is(err, repository.ErrDuplicate) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Message: "A user with this email already exists",
+		})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Message: "Failed to create user",
 		})
@@ -115,7 +112,7 @@ func RegisterUser(c *gin.Context) {
 // @Failure 401 {object} models.ErrorResponse "Invalid email or password"
 // @Failure 500 {object} models.ErrorResponse "Failed to generate token"
 // @Router /users/login [post]
-func LoginUser(c *gin.Context) {
+func (h *Handler) LoginUser(c *gin.Context) {
 	var input models.LoginInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
@@ -127,8 +124,14 @@ func LoginUser(c *gin.Context) {
 	}
 
 	// Fetch user by email
-	var user models.User
-	if err := config.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
+	user, err := h.Store.Users.ByEmail(c.Request.Context(), input.Email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			h.log(c.Request.Context()).Warn("Login rejected", "reason", "unknown email", "email", input.Email)
+		} else {
+			h.log(c.Request.Context()).Error("Looking up user for login failed", "email", input.Email, "error", err)
+		}
+		h.Metrics.LoginFailed()
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
 			Message: "Invalid email or password",
 		})
@@ -137,6 +140,13 @@ func LoginUser(c *gin.Context) {
 
 	// Check password
 	if err := CheckPassword(input.Password, user.Password); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			h.log(c.Request.Context()).Warn("Login rejected", "reason", "wrong password", "user_id", user.ID)
+		} else {
+			// A malformed hash locks the user out; it needs fixing
+			h.log(c.Request.Context()).Error("Password check failed", "user_id", user.ID, "error", err)
+		}
+		h.Metrics.LoginFailed()
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
 			Message: "Invalid email or password",
 		})
@@ -144,7 +154,7 @@ func LoginUser(c *gin.Context) {
 	}
 
 	// Generate JWT token
-	token, err := utils.GenerateJWT(user.ID.String(), user.Role)
+	token, err := h.Tokens.Issue(user.ID, user.Role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Message: "Failed to generate token",
@@ -172,9 +182,5 @@ func HashPassword(password string) (string, error) {
 
 // CheckPassword compares plain password with hashed
 func CheckPassword(plain, hashed string) error {
-	err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(plain))
-	if err != nil {
-		log.Printf("Password comparison failed: %v\n", err)
-	}
-	return err
+	return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(plain))
 }
@@ -0,0 +1,257 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// CreateWarehouse allows an admin user to add a stock location
+// CreateWarehouse godoc
+// @Summary Create a warehouse
+// @Description Allows an admin user to add a warehouse. Orders take stock from warehouses with a lower priority first, or from the nearest one when ALLOCATION_STRATEGY is "nearest".
+// @Tags Warehouses
+// @Accept json
+// @Produce json
+// @Param warehouse body models.WarehouseInput true "Warehouse payload"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Warehouse created successfully"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid warehouse payload or duplicate code"
+// @Failure 500 {object} models.ErrorResponse "Failed to create warehouse"
+// @Router /warehouses [post]
+func (h *Handler) CreateWarehouse(c *gin.Context) {
+	var input models.WarehouseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+			Errors: []models.ValidationError{
+				{Field: "payload", Message: err.Error()},
+			},
+		})
+		return
+	}
+
+	var warehouse models.Warehouse
+	applyWarehouseInput(&warehouse, input)
+
+	var existing models.Warehouse
+	if err := h.db(c).Where("code = ?", warehouse.Code).First(&existing).Error; err == nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "A warehouse with this code already exists"})
+		return
+	}
+
+	if err := h.db(c).Create(&warehouse).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create warehouse"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Warehouse created successfully",
+		Data:    warehouse,
+	})
+}
+
+// GetWarehouses lists all warehouses (admin only)
+// GetWarehouses godoc
+// @Summary Get warehouses
+// @Description Lists all warehouses in allocation priority order
+// @Tags Warehouses
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Warehouse(s) retrieved successfully"
+// @Failure 500 {object} models.ErrorResponse "Failed to retrieve warehouses"
+// @Router /warehouses [get]
+func (h *Handler) GetWarehouses(c *gin.Context) {
+	var warehouses []models.Warehouse
+	if err := h.db(c).Order("priority, name").Find(&warehouses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve warehouses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Warehouse(s) retrieved successfully",
+		Data:    warehouses,
+	})
+}
+
+// GetWarehouseStock lists the stock held in a warehouse (admin only)
+// GetWarehouseStock godoc
+// @Summary Get warehouse stock
+// @Description Lists the stock level of every product held in a warehouse
+// @Tags Warehouses
+// @Produce json
+// @Param id path string true "Warehouse ID"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Stock levels retrieved successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid warehouse ID"
+// @Failure 404 {object} models.ErrorResponse "Warehouse not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to fetch stock levels"
+// @Router /warehouses/{id}/stock [get]
+func (h *Handler) GetWarehouseStock(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid warehouse ID"})
+		return
+	}
+
+	var warehouse models.Warehouse
+	if err := h.db(c).First(&warehouse, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Warehouse not found"})
+		return
+	}
+
+	var levels []models.StockLevel
+	if err := h.db(c).Where("warehouse_id = ? AND quantity > 0", id).Order("quantity ASC").Find(&levels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch stock levels"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Stock levels retrieved successfully",
+		Data:    levels,
+	})
+}
+
+// UpdateWarehouse modifies an existing warehouse (admin only)
+// UpdateWarehouse godoc
+// @Summary Update a warehouse
+// @Description Allows an admin user to update a warehouse by ID. Inactive warehouses keep their stock but are not allocated from.
+// @Tags Warehouses
+// @Accept json
+// @Produce json
+// @Param id path string true "Warehouse ID"
+// @Param warehouse body models.WarehouseInput true "Updated warehouse payload"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Warehouse updated successfully"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid warehouse ID, payload or duplicate code"
+// @Failure 404 {object} models.ErrorResponse "Warehouse not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to update warehouse"
+// @Router /warehouses/{id} [put]
+func (h *Handler) UpdateWarehouse(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid warehouse ID"})
+		return
+	}
+
+	var warehouse models.Warehouse
+	if err := h.db(c).First(&warehouse, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Warehouse not found"})
+		return
+	}
+
+	var input models.WarehouseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+			Errors: []models.ValidationError{
+				{Field: "payload", Message: err.Error()},
+			},
+		})
+		return
+	}
+	applyWarehouseInput(&warehouse, input)
+
+	var existing models.Warehouse
+	if err := h.db(c).Where("code = ? AND id <> ?", warehouse.Code, warehouse.ID).First(&existing).Error; err == nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "A warehouse with this code already exists"})
+		return
+	}
+
+	if err := h.db(c).Save(&warehouse).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update warehouse"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Warehouse updated successfully",
+		Data:    warehouse,
+	})
+}
+
+// DeleteWarehouse deletes an empty warehouse (admin only)
+// DeleteWarehouse godoc
+// @Summary Delete a warehouse
+// @Description Allows an admin user to delete a warehouse that holds no stock and has never been allocated to an order. Transfer its stock elsewhere first, or deactivate it instead.
+// @Tags Warehouses
+// @Param id path string true "Warehouse ID"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Warehouse deleted successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid warehouse ID, warehouse still holds stock or has order allocations"
+// @Failure 404 {object} models.ErrorResponse "Warehouse not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to delete warehouse"
+// @Router /warehouses/{id} [delete]
+func (h *Handler) DeleteWarehouse(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid warehouse ID"})
+		return
+	}
+
+	var stocked int64
+	if err := h.db(c).Model(&models.StockLevel{}).Where("warehouse_id = ? AND quantity > 0", id).Count(&stocked).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to delete warehouse"})
+		return
+	}
+	if stocked > 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Warehouse still holds stock; transfer it to another warehouse first"})
+		return
+	}
+
+	// Canceled and returned items are restocked to the warehouses they were allocated from
+	var allocated int64
+	if err := h.db(c).Model(&models.OrderAllocation{}).Where("warehouse_id = ?", id).Count(&allocated).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to delete warehouse"})
+		return
+	}
+	if allocated > 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Orders were allocated from this warehouse; deactivate it instead"})
+		return
+	}
+
+	// Empty stock levels still reference the warehouse, so they go first
+	err = h.db(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("warehouse_id = ?", id).Delete(&models.StockLevel{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Warehouse{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return &requestError{http.StatusNotFound, "Warehouse not found"}
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, err, "Failed to delete warehouse")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Warehouse deleted successfully",
+	})
+}
+
+// applyWarehouseInput copies the input fields onto the warehouse.
+func applyWarehouseInput(warehouse *models.Warehouse, input models.WarehouseInput) {
+	warehouse.Name = input.Name
+	warehouse.Code = strings.ToLower(strings.TrimSpace(input.Code))
+	warehouse.Address = models.Address{
+		Line1:      input.Address.Line1,
+		Line2:      input.Address.Line2,
+		City:       input.Address.City,
+		Region:     input.Address.Region,
+		PostalCode: input.Address.PostalCode,
+		Country:    strings.ToUpper(input.Address.Country),
+	}
+	warehouse.Priority = input.Priority
+
+	warehouse.Active = true
+	if input.Active != nil {
+		warehouse.Active = *input.Active
+	}
+}
@@ -0,0 +1,321 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/webhooks"
+)
+
+// Webhook delivery page sizes.
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+// CreateWebhook registers a URL to receive events (admin only)
+// CreateWebhook godoc
+// @Summary Create a webhook subscription
+// @Description Registers a URL to receive the selected events (order.created, order.status_changed, product.updated, stock.low) as signed JSON POST requests. The response contains the signing secret, which is not shown again; one is generated when none is given.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body models.WebhookInput true "Webhook payload"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Webhook created successfully"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid webhook payload"
+// @Failure 500 {object} models.ErrorResponse "Failed to create webhook"
+// @Router /webhooks [post]
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	var input models.WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+			Errors: []models.ValidationError{
+				{Field: "payload", Message: err.Error()},
+			},
+		})
+		return
+	}
+
+	var subscription models.WebhookSubscription
+	applyWebhookInput(&subscription, input)
+	if subscription.Secret == "" {
+		secret, err := webhooks.GenerateSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create webhook"})
+			return
+		}
+		subscription.Secret = secret
+	}
+
+	if err := h.db(c).Create(&subscription).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Webhook created successfully",
+		Data:    subscription,
+	})
+}
+
+// GetWebhooks lists webhook subscriptions (admin only)
+// GetWebhooks godoc
+// @Summary Get webhook subscriptions
+// @Description Lists all webhook subscriptions. Secrets are not included.
+// @Tags Webhooks
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Webhooks retrieved successfully"
+// @Failure 500 {object} models.ErrorResponse "Failed to retrieve webhooks"
+// @Router /webhooks [get]
+func (h *Handler) GetWebhooks(c *gin.Context) {
+	var subscriptions []models.WebhookSubscription
+	if err := h.db(c).Order("created_at").Find(&subscriptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to retrieve webhooks"})
+		return
+	}
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Webhooks retrieved successfully",
+		Data:    subscriptions,
+	})
+}
+
+// UpdateWebhook modifies a webhook subscription (admin only)
+// UpdateWebhook godoc
+// @Summary Update a webhook subscription
+// @Description Allows an admin to change a subscription's URL, events or active flag. Sending a secret rotates it; otherwise the current one is kept. Deliveries to inactive subscriptions fail and can be replayed later.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param webhook body models.WebhookInput true "Updated webhook payload"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Webhook updated successfully"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid webhook ID or payload"
+// @Failure 404 {object} models.ErrorResponse "Webhook not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to update webhook"
+// @Router /webhooks/{id} [put]
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid webhook ID"})
+		return
+	}
+
+	var subscription models.WebhookSubscription
+	if err := h.db(c).First(&subscription, "id = ?", id).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Webhook not found"})
+		return
+	}
+
+	var input models.WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+			Errors: []models.ValidationError{
+				{Field: "payload", Message: err.Error()},
+			},
+		})
+		return
+	}
+	applyWebhookInput(&subscription, input)
+
+	if err := h.db(c).Save(&subscription).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to update webhook"})
+		return
+	}
+	if input.Secret == "" {
+		subscription.Secret = ""
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Webhook updated successfully",
+		Data:    subscription,
+	})
+}
+
+// DeleteWebhook deletes a webhook subscription (admin only)
+// DeleteWebhook godoc
+// @Summary Delete a webhook subscription
+// @Description Allows an admin to delete a subscription. Its delivery log is kept; pending deliveries fail.
+// @Tags Webhooks
+// @Param id path string true "Webhook ID"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Webhook deleted successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid webhook ID"
+// @Failure 404 {object} models.ErrorResponse "Webhook not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to delete webhook"
+// @Router /webhooks/{id} [delete]
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid webhook ID"})
+		return
+	}
+
+	result := h.db(c).Delete(&models.WebhookSubscription{}, "id = ?", id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to delete webhook"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Webhook not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Webhook deleted successfully",
+	})
+}
+
+// GetWebhookDeliveries lists a subscription's deliveries (admin only)
+// GetWebhookDeliveries godoc
+// @Summary Get webhook deliveries
+// @Description Lists a subscription's deliveries, newest first, with their attempt count and last response code
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param status query string false "Filter by status (pending, succeeded, failed)"
+// @Param limit query int false "Maximum number of deliveries (default 50, max 200)"
+// @Param offset query int false "Number of deliveries to skip"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Deliveries retrieved successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid webhook ID, limit or offset"
+// @Failure 500 {object} models.ErrorResponse "Failed to fetch deliveries"
+// @Router /webhooks/{id}/deliveries [get]
+func (h *Handler) GetWebhookDeliveries(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid webhook ID"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultDeliveryLimit)))
+	if err != nil || limit < 1 || limit > maxDeliveryLimit {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "limit must be between 1 and " + strconv.Itoa(maxDeliveryLimit)})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "offset must not be negative"})
+		return
+	}
+
+	query := h.db(c).Where("subscription_id = ?", id).Order("created_at DESC").Limit(limit).Offset(offset)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Deliveries retrieved successfully",
+		Data:    deliveries,
+	})
+}
+
+// GetWebhookDelivery retrieves a delivery with its attempt log (admin only)
+// GetWebhookDelivery godoc
+// @Summary Get a webhook delivery
+// @Description Retrieves a delivery with its payload and every attempt made, including response codes and errors
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Delivery ID"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Delivery retrieved successfully"
+// @Failure 400 {object} models.ErrorResponse "Invalid delivery ID"
+// @Failure 404 {object} models.ErrorResponse "Delivery not found"
+// @Router /webhook-deliveries/{id} [get]
+func (h *Handler) GetWebhookDelivery(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid delivery ID"})
+		return
+	}
+
+	var delivery models.WebhookDelivery
+	err = h.db(c).Preload("AttemptLog", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	}).First(&delivery, "id = ?", id).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Delivery not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Delivery retrieved successfully",
+		Data:    delivery,
+	})
+}
+
+// ReplayWebhookDelivery sends a delivery again (admin only)
+// ReplayWebhookDelivery godoc
+// @Summary Replay a webhook delivery
+// @Description Queues a delivery to be sent again now with the same payload and delivery ID, for example after fixing the receiver. Its attempt count restarts; earlier attempts stay in the log.
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Delivery ID"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Delivery queued for replay"
+// @Failure 400 {object} models.ErrorResponse "Invalid delivery ID"
+// @Failure 404 {object} models.ErrorResponse "Delivery not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to replay delivery"
+// @Router /webhook-deliveries/{id}/replay [post]
+func (h *Handler) ReplayWebhookDelivery(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid delivery ID"})
+		return
+	}
+
+	result := h.db(c).Model(&models.WebhookDelivery{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          models.WebhookDeliveryPending,
+		"attempts":        0,
+		"next_attempt_at": h.Clock.Now(),
+	})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to replay delivery"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Delivery not found"})
+		return
+	}
+
+	var delivery models.WebhookDelivery
+	h.db(c).First(&delivery, "id = ?", id)
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Delivery queued for replay",
+		Data:    delivery,
+	})
+}
+
+// applyWebhookInput copies the input fields onto the subscription. The
+// secret is only replaced when one is given.
+func applyWebhookInput(subscription *models.WebhookSubscription, input models.WebhookInput) {
+	subscription.URL = input.URL
+	subscription.Description = input.Description
+	subscription.EventTypes = input.EventTypes
+	if input.Secret != "" {
+		subscription.Secret = input.Secret
+	}
+
+	subscription.Active = true
+	if input.Active != nil {
+		subscription.Active = *input.Active
+	}
+}
@@ -0,0 +1,249 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// GetWishlist lists the products on the user's wishlist
+// GetWishlist godoc
+// @Summary Get wishlist
+// @Description Lists the products the authenticated user has saved, most recently added first
+// @Tags Wishlist
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Wishlist retrieved successfully"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 500 {object} models.ErrorResponse "Failed to fetch wishlist"
+// @Router /wishlist [get]
+func (h *Handler) GetWishlist(c *gin.Context) {
+	userUUID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var items []models.WishlistItem
+	if err := h.db(c).Preload("Product").Where("user_id = ?", userUUID).Order("created_at DESC").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch wishlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Wishlist retrieved successfully",
+		Data:    items,
+	})
+}
+
+// AddToWishlist saves a product to the user's wishlist
+// AddToWishlist godoc
+// @Summary Add to wishlist
+// @Description Saves a product to the authenticated user's wishlist. Adding a product that is already there has no effect.
+// @Tags Wishlist
+// @Accept json
+// @Produce json
+// @Param item body models.WishlistInput true "Product to save"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Product added to wishlist"
+// @Failure 400 {object} models.ValidationErrorResponse "Invalid payload"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 404 {object} models.ErrorResponse "Product not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to add product to wishlist"
+// @Router /wishlist [post]
+func (h *Handler) AddToWishlist(c *gin.Context) {
+	userUUID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var input models.WishlistInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, models.ValidationErrorResponse{
+			Errors: []models.ValidationError{
+				{Field: "payload", Message: err.Error()},
+			},
+		})
+		return
+	}
+	productUUID := uuid.MustParse(input.ProductID)
+
+	var product models.Product
+	if err := h.db(c).First(&product, "id = ?", productUUID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Product not found"})
+		return
+	}
+
+	item := models.WishlistItem{UserID: userUUID, ProductID: productUUID}
+	result := h.db(c).Where("user_id = ? AND product_id = ?", userUUID, productUUID).FirstOrCreate(&item)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to add product to wishlist"})
+		return
+	}
+	item.Product = product
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Product added to wishlist",
+		Data:    item,
+	})
+}
+
+// RemoveFromWishlist removes a product from the user's wishlist
+// RemoveFromWishlist godoc
+// @Summary Remove from wishlist
+// @Description Removes a product from the authenticated user's wishlist
+// @Tags Wishlist
+// @Param product_id path string true "Product ID"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Product removed from wishlist"
+// @Failure 400 {object} models.ErrorResponse "Invalid product ID"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 404 {object} models.ErrorResponse "Product is not on the wishlist"
+// @Failure 500 {object} models.ErrorResponse "Failed to remove product from wishlist"
+// @Router /wishlist/{product_id} [delete]
+func (h *Handler) RemoveFromWishlist(c *gin.Context) {
+	userUUID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	productUUID, err := uuid.Parse(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid product ID"})
+		return
+	}
+
+	result := h.db(c).Where("user_id = ? AND product_id = ?", userUUID, productUUID).Delete(&models.WishlistItem{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to remove product from wishlist"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Product is not on the wishlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Product removed from wishlist",
+	})
+}
+
+// SubscribeStockAlert asks to be notified when a product is back in stock
+// SubscribeStockAlert godoc
+// @Summary Subscribe to a back-in-stock alert
+// @Description Notifies the authenticated user once when the out-of-stock product is restocked. Subscribing again while an alert is open has no effect.
+// @Tags Wishlist
+// @Produce json
+// @Param id path string true "Product ID"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Stock alert created"
+// @Failure 400 {object} models.ErrorResponse "Invalid product ID or product in stock"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 404 {object} models.ErrorResponse "Product not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to create stock alert"
+// @Router /products/{id}/stock-alerts [post]
+func (h *Handler) SubscribeStockAlert(c *gin.Context) {
+	userUUID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	productUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid product ID"})
+		return
+	}
+
+	var product models.Product
+	if err := h.db(c).Select("id", "stock").First(&product, "id = ?", productUUID).Error; err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "Product not found"})
+		return
+	}
+	if product.Stock > 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Product is in stock"})
+		return
+	}
+
+	alert := models.StockAlert{UserID: userUUID, ProductID: productUUID}
+	result := h.db(c).Where("user_id = ? AND product_id = ? AND notified_at IS NULL", userUUID, productUUID).FirstOrCreate(&alert)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to create stock alert"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Stock alert created",
+		Data:    alert,
+	})
+}
+
+// UnsubscribeStockAlert cancels an open back-in-stock alert
+// UnsubscribeStockAlert godoc
+// @Summary Cancel a back-in-stock alert
+// @Description Cancels the authenticated user's open alert for a product
+// @Tags Wishlist
+// @Param id path string true "Product ID"
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Stock alert canceled"
+// @Failure 400 {object} models.ErrorResponse "Invalid product ID"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 404 {object} models.ErrorResponse "No open stock alert for this product"
+// @Failure 500 {object} models.ErrorResponse "Failed to cancel stock alert"
+// @Router /products/{id}/stock-alerts [delete]
+func (h *Handler) UnsubscribeStockAlert(c *gin.Context) {
+	userUUID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	productUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Message: "Invalid product ID"})
+		return
+	}
+
+	result := h.db(c).Where("user_id = ? AND product_id = ? AND notified_at IS NULL", userUUID, productUUID).Delete(&models.StockAlert{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to cancel stock alert"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Message: "No open stock alert for this product"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Stock alert canceled",
+	})
+}
+
+// GetStockAlerts lists the user's back-in-stock alerts
+// GetStockAlerts godoc
+// @Summary Get stock alerts
+// @Description Lists the authenticated user's back-in-stock alerts, newest first. Alerts that have fired have notified_at set.
+// @Tags Wishlist
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse "Stock alerts retrieved successfully"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 500 {object} models.ErrorResponse "Failed to fetch stock alerts"
+// @Router /stock-alerts [get]
+func (h *Handler) GetStockAlerts(c *gin.Context) {
+	userUUID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var alerts []models.StockAlert
+	if err := h.db(c).Where("user_id = ?", userUUID).Order("created_at DESC").Find(&alerts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Message: "Failed to fetch stock alerts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Stock alerts retrieved successfully",
+		Data:    alerts,
+	})
+}
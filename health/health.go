@@ -0,0 +1,166 @@
+// Package health reports whether the API can serve traffic. Dependencies
+// such as the database register a named check; a Checker runs them all
+// concurrently, each under its own timeout, and reports the status and
+// latency of each one.
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check statuses.
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// defaultTimeout applies when the checker has no Timeout.
+const defaultTimeout = 2 * time.Second
+
+// Check reports whether a dependency works. It should return promptly
+// when ctx is done.
+type Check func(ctx context.Context) error
+
+// Result is the outcome of one check.
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of all checks. Status is up only when every check
+// passed and the server is not shutting down.
+type Report struct {
+	Status       string    `json:"status"`
+	ShuttingDown bool      `json:"shutting_down"`
+	CheckedAt    time.Time `json:"checked_at"`
+	Checks       []Result  `json:"checks"`
+}
+
+// Checker runs the registered checks. It is safe for concurrent use.
+type Checker struct {
+	// Timeout bounds each check.
+	Timeout time.Duration
+
+	mu           sync.RWMutex
+	checks       map[string]Check
+	shuttingDown atomic.Bool
+}
+
+// New returns a Checker with no checks.
+func New(timeout time.Duration) *Checker {
+	return &Checker{Timeout: timeout, checks: make(map[string]Check)}
+}
+
+// Register adds a check, replacing any check of the same name.
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.checks == nil {
+		c.checks = make(map[string]Check)
+	}
+	c.checks[name] = check
+}
+
+// ShutDown marks the server as shutting down, which fails readiness from
+// then on so load balancers stop sending new requests.
+func (c *Checker) ShutDown() {
+	c.shuttingDown.Store(true)
+}
+
+// ShuttingDown reports whether ShutDown was called.
+func (c *Checker) ShuttingDown() bool {
+	return c.shuttingDown.Load()
+}
+
+// Run runs every check concurrently and reports the results by name.
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	names := make([]string, 0, len(c.checks))
+	for name := range c.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.RUnlock()
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	results := make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = run(ctx, names[i], checks[i], timeout)
+		}(i)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:       StatusUp,
+		ShuttingDown: c.ShuttingDown(),
+		CheckedAt:    time.Now().UTC(),
+		Checks:       results,
+	}
+	if report.ShuttingDown {
+		report.Status = StatusDown
+	}
+	for _, result := range results {
+		if result.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// run runs one check under timeout. A check that ignores its context is
+// abandoned when the timeout expires.
+func run(ctx context.Context, name string, check Check, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- errors.New("check panicked")
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{
+		Name:      name,
+		Status:    StatusUp,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Error = "timed out after " + timeout.String()
+		}
+	}
+	return result
+}
@@ -0,0 +1,104 @@
+package integration_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// createCoupon creates a coupon as admin.
+func (h *harness) createCoupon(admin string, input models.CouponInput) models.Coupon {
+	h.t.Helper()
+	var coupon models.Coupon
+	h.request(http.MethodPost, "/coupons", input, admin).expect(http.StatusOK).data(&coupon)
+	return coupon
+}
+
+func TestCouponAdmin(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+
+	coupon := h.createCoupon(admin, models.CouponInput{Code: "SAVE10", Type: "percentage", Value: 10})
+	path := "/coupons/" + coupon.ID.String()
+
+	h.request(http.MethodPost, "/coupons", models.CouponInput{Code: "SAVE10", Type: "fixed", Value: 5}, admin).
+		expectMessage(http.StatusBadRequest, "already exists")
+	h.request(http.MethodPost, "/coupons", models.CouponInput{Code: "HALF", Type: "percentage", Value: 150}, admin).
+		expect(http.StatusBadRequest)
+	h.request(http.MethodPost, "/coupons", models.CouponInput{Code: "BOGUS", Type: "bogus", Value: 1}, admin).
+		expect(http.StatusBadRequest)
+	starts := time.Now()
+	expires := starts.Add(-time.Hour)
+	h.request(http.MethodPost, "/coupons", models.CouponInput{Code: "PAST", Type: "fixed", Value: 1, StartsAt: &starts, ExpiresAt: &expires}, admin).
+		expect(http.StatusBadRequest)
+
+	var coupons []models.Coupon
+	h.request(http.MethodGet, "/coupons", nil, admin).expect(http.StatusOK).data(&coupons)
+	if len(coupons) != 1 {
+		t.Errorf("listed %d coupons, want 1", len(coupons))
+	}
+	h.request(http.MethodGet, path, nil, admin).expect(http.StatusOK)
+	h.request(http.MethodGet, "/coupons/"+uuid.NewString(), nil, admin).expect(http.StatusNotFound)
+	h.request(http.MethodGet, "/coupons/not-a-uuid", nil, admin).expect(http.StatusBadRequest)
+
+	var updated models.Coupon
+	h.request(http.MethodPut, path, models.CouponInput{Code: "SAVE20", Type: "percentage", Value: 20}, admin).
+		expect(http.StatusOK).data(&updated)
+	if updated.Code != "SAVE20" || updated.Value != 20 {
+		t.Errorf("updated coupon = %+v", updated)
+	}
+	h.request(http.MethodPut, "/coupons/"+uuid.NewString(), models.CouponInput{Code: "X", Type: "fixed", Value: 1}, admin).
+		expect(http.StatusNotFound)
+
+	h.request(http.MethodDelete, path, nil, admin).expect(http.StatusOK)
+	h.request(http.MethodDelete, path, nil, admin).expect(http.StatusNotFound)
+}
+
+func TestOrderWithCoupon(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Price: 20, Stock: 10})
+	h.createCoupon(admin, models.CouponInput{Code: "SAVE10", Type: "percentage", Value: 10, PerUserLimit: 1})
+	h.createCoupon(admin, models.CouponInput{Code: "BIGSPEND", Type: "fixed", Value: 5, MinOrderValue: 100})
+	inactive := false
+	if off := h.createCoupon(admin, models.CouponInput{Code: "OFF", Type: "fixed", Value: 5, Active: &inactive}); off.Active {
+		t.Error("coupon created inactive is active")
+	}
+
+	place := func(code string) *response {
+		return h.request(http.MethodPost, "/orders", models.PlaceOrderInput{
+			Items:           []models.OrderItemInput{{ProductID: mug.ID.String(), Quantity: 1}},
+			ShippingAddress: &address,
+			CouponCode:      code,
+		}, user)
+	}
+
+	var placed struct {
+		OrderID uuid.UUID `json:"order_id"`
+	}
+	place("SAVE10").expect(http.StatusOK).data(&placed)
+	order := h.getOrder(user, placed.OrderID)
+	if order.DiscountAmount != 2 || order.Total != 18 {
+		t.Errorf("discounted order = discount %v, total %v; want 2 and 18", order.DiscountAmount, order.Total)
+	}
+
+	place("SAVE10").expectMessage(http.StatusBadRequest, "maximum number of times")
+	place("NOPE").expectMessage(http.StatusBadRequest, "Invalid coupon code")
+	place("BIGSPEND").expectMessage(http.StatusBadRequest, "minimum value")
+	place("OFF").expectMessage(http.StatusBadRequest, "not currently valid")
+
+	// Canceling the order gives the coupon back
+	h.request(http.MethodPut, "/orders/"+order.ID.String()+"/cancel", nil, user).expect(http.StatusOK)
+	place("SAVE10").expect(http.StatusOK)
+
+	// A failed lookup is a server error, not an invalid code
+	if err := h.app.DB.Migrator().DropTable(&models.Coupon{}); err != nil {
+		t.Fatal(err)
+	}
+	place("SAVE10").expectMessage(http.StatusInternalServerError, "Failed to create order")
+}
@@ -0,0 +1,5 @@
+// Package integration holds end-to-end tests of the HTTP API. Each test
+// boots the full router, as built by routes.InitializeRoutes, against a
+// throwaway in-memory SQLite database and drives it with real requests.
+// The package has no code of its own; see harness_test.go for the helpers.
+package integration
@@ -0,0 +1,321 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/TobiAdeniji94/ecommerce_api/app"
+	"github.com/TobiAdeniji94/ecommerce_api/config"
+	"github.com/TobiAdeniji94/ecommerce_api/migrations"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/payments"
+)
+
+const password = "correct horse battery staple"
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	gin.SetMode(gin.TestMode)
+	// Request and provider logs only help when a test is being debugged
+	if !testing.Verbose() {
+		gin.DefaultWriter = io.Discard
+		log.SetOutput(io.Discard)
+	}
+	os.Exit(m.Run())
+}
+
+// harness is the API served from a fresh database.
+type harness struct {
+	t        *testing.T
+	app      *app.App
+	router   http.Handler
+	payments *payments.Fake
+	seq      int
+}
+
+// newHarness boots the API on an empty in-memory database. Background
+// workers and rate limiting are off unless an option turns them on.
+func newHarness(t *testing.T, opts ...func(*config.Config)) *harness {
+	t.Helper()
+
+	cfg := config.Defaults()
+	cfg.Server.Environment = "test"
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = ":memory:"
+	cfg.JWT.Secret = "integration-test-secret"
+	cfg.Payments.WebhookSecret = "whsec_integration"
+	cfg.Features.Workers = false
+	cfg.Features.Swagger = false
+	cfg.RateLimit.Enabled = false
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := config.ConnectDatabase(cfg.Database)
+	if err != nil {
+		t.Fatalf("opening SQLite: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	// Tests provoke errors on purpose; keep them out of the output
+	db = db.Session(&gorm.Session{Logger: logger.Discard})
+	if _, err := migrations.Up(db, 0); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+
+	a, err := app.New(cfg, db)
+	if err != nil {
+		t.Fatalf("building the app: %v", err)
+	}
+	if !testing.Verbose() {
+		a.Log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	fake, ok := a.Payments.(*payments.Fake)
+	if !ok {
+		t.Fatalf("payments provider is %T, want the fake", a.Payments)
+	}
+
+	return &harness{t: t, app: a, router: a.Router(), payments: fake}
+}
+
+// response is a recorded API response.
+type response struct {
+	t *testing.T
+	*httptest.ResponseRecorder
+}
+
+// probe sends an unauthenticated GET to a path outside /api/v1.
+func (h *harness) probe(path string) *response {
+	h.t.Helper()
+	rec := httptest.NewRecorder()
+	h.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return &response{t: h.t, ResponseRecorder: rec}
+}
+
+// request sends a request with an optional body and bearer token. A body
+// that is not a string or []byte is sent as JSON. headers are name, value
+// pairs and replace the defaults.
+func (h *harness) request(method, path string, body interface{}, token string, headers ...string) *response {
+	h.t.Helper()
+
+	var reader io.Reader
+	contentType := ""
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(b)
+	case []byte:
+		reader = bytes.NewReader(b)
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			h.t.Fatalf("encoding request body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+		contentType = "application/json"
+	}
+
+	req := httptest.NewRequest(method, "/api/v1"+path, reader)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	h.router.ServeHTTP(rec, req)
+	return &response{t: h.t, ResponseRecorder: rec}
+}
+
+// expect fails the test unless the response has status.
+func (r *response) expect(status int) *response {
+	r.t.Helper()
+	if r.Code != status {
+		r.t.Fatalf("status = %d, want %d; body: %s", r.Code, status, r.Body.String())
+	}
+	return r
+}
+
+// data decodes the data of a success response into v.
+func (r *response) data(v interface{}) {
+	r.t.Helper()
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(r.Body.Bytes(), &envelope); err != nil {
+		r.t.Fatalf("decoding response: %v; body: %s", err, r.Body.String())
+	}
+	if err := json.Unmarshal(envelope.Data, v); err != nil {
+		r.t.Fatalf("decoding response data: %v; body: %s", err, r.Body.String())
+	}
+}
+
+// message returns the message of a success or error response, or the
+// error of a middleware response.
+func (r *response) message() string {
+	r.t.Helper()
+	var body struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(r.Body.Bytes(), &body); err != nil {
+		r.t.Fatalf("decoding response: %v; body: %s", err, r.Body.String())
+	}
+	if body.Message != "" {
+		return body.Message
+	}
+	return body.Error
+}
+
+// expectMessage fails the test unless the response has status and its
+// message contains want.
+func (r *response) expectMessage(status int, want string) {
+	r.t.Helper()
+	r.expect(status)
+	if got := r.message(); !strings.Contains(got, want) {
+		r.t.Fatalf("message = %q, want it to contain %q", got, want)
+	}
+}
+
+// uniqueEmail returns an address no other user of the harness has.
+func (h *harness) uniqueEmail(prefix string) string {
+	h.seq++
+	return fmt.Sprintf("%s%d@example.com", prefix, h.seq)
+}
+
+// register creates an account and returns its ID.
+func (h *harness) register(email, role string) uuid.UUID {
+	h.t.Helper()
+	var created struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+	h.request(http.MethodPost, "/users/register", models.UserInput{Email: email, Password: password, Role: role}, "").
+		expect(http.StatusOK).data(&created)
+	return created.UserID
+}
+
+// login returns a token for an existing account.
+func (h *harness) login(email string) string {
+	h.t.Helper()
+	var session struct {
+		Token string `json:"token"`
+	}
+	h.request(http.MethodPost, "/users/login", models.LoginInput{Email: email, Password: password}, "").
+		expect(http.StatusOK).data(&session)
+	return session.Token
+}
+
+// user registers a customer and returns their token.
+func (h *harness) user() string {
+	h.t.Helper()
+	email := h.uniqueEmail("customer")
+	h.register(email, "")
+	return h.login(email)
+}
+
+// admin registers an administrator and returns their token.
+func (h *harness) admin() string {
+	h.t.Helper()
+	email := h.uniqueEmail("admin")
+	h.register(email, "admin")
+	return h.login(email)
+}
+
+// product creates a product as admin. Name and price default when unset.
+func (h *harness) product(admin string, input models.ProductInput) models.Product {
+	h.t.Helper()
+	if input.Name == "" {
+		h.seq++
+		input.Name = fmt.Sprintf("Product %d", h.seq)
+	}
+	if input.Price == 0 {
+		input.Price = 10
+	}
+	var product models.Product
+	h.request(http.MethodPost, "/products", input, admin).expect(http.StatusOK).data(&product)
+	return product
+}
+
+// address is a valid shipping address.
+var address = models.AddressInput{Line1: "1 Main St", City: "Springfield", Country: "US"}
+
+// order places an order for quantity units of each product and returns it.
+func (h *harness) order(token string, quantity int, products ...models.Product) models.Order {
+	h.t.Helper()
+	input := models.PlaceOrderInput{ShippingAddress: &address}
+	for _, p := range products {
+		input.Items = append(input.Items, models.OrderItemInput{ProductID: p.ID.String(), Quantity: quantity})
+	}
+	var placed struct {
+		OrderID uuid.UUID `json:"order_id"`
+	}
+	h.request(http.MethodPost, "/orders", input, token).expect(http.StatusOK).data(&placed)
+	return h.getOrder(token, placed.OrderID)
+}
+
+// getOrder fetches an order.
+func (h *harness) getOrder(token string, id uuid.UUID) models.Order {
+	h.t.Helper()
+	var order models.Order
+	h.request(http.MethodGet, "/orders/"+id.String(), nil, token).expect(http.StatusOK).data(&order)
+	return order
+}
+
+// pay creates a payment for the order and captures it, leaving it Paid.
+func (h *harness) pay(token, admin string, order models.Order) models.Payment {
+	h.t.Helper()
+	payment := h.createPayment(token, order)
+	var captured models.Payment
+	h.request(http.MethodPost, "/payments/"+payment.ID.String()+"/capture", nil, admin).
+		expect(http.StatusOK).data(&captured)
+	return captured
+}
+
+// setStatus moves an order to status as admin.
+func (h *harness) setStatus(admin string, order models.Order, status string) *response {
+	h.t.Helper()
+	return h.request(http.MethodPut, "/orders/"+order.ID.String()+"/status", models.UpdateOrderStatusInput{Status: status}, admin)
+}
+
+// deliver pays for, ships and delivers an order.
+func (h *harness) deliver(token, admin string, order models.Order) models.Order {
+	h.t.Helper()
+	h.pay(token, admin, order)
+	h.request(http.MethodPost, "/orders/"+order.ID.String()+"/shipments",
+		models.CreateShipmentInput{Carrier: "UPS", TrackingNumber: "1Z999"}, admin).expect(http.StatusOK)
+	h.setStatus(admin, order, models.OrderStatusShipped).expect(http.StatusOK)
+	h.setStatus(admin, order, models.OrderStatusDelivered).expect(http.StatusOK)
+	return h.getOrder(token, order.ID)
+}
+
+// webhook posts a payment provider event signed with the fake's secret.
+func (h *harness) webhook(eventID, eventType, intentID string) *response {
+	h.t.Helper()
+	payload, signature := h.payments.SignedEvent(eventID, eventType, intentID)
+	return h.request(http.MethodPost, "/payments/webhook", payload, "", payments.SignatureHeader, signature)
+}
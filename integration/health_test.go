@@ -0,0 +1,82 @@
+package integration_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TobiAdeniji94/ecommerce_api/config"
+	"github.com/TobiAdeniji94/ecommerce_api/health"
+)
+
+func TestProbes(t *testing.T) {
+	h := newHarness(t)
+
+	h.probe("/healthz").expect(http.StatusOK)
+	h.probe("/readyz").expect(http.StatusOK)
+
+	h.app.Health.Register("search", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	res := h.probe("/readyz").expect(http.StatusServiceUnavailable)
+	if body := res.Body.String(); !strings.Contains(body, `"search":"down"`) || strings.Contains(body, "connection refused") {
+		t.Errorf("readiness body = %s, want the failing check without its error", body)
+	}
+	// A failing dependency does not make the process unhealthy
+	h.probe("/healthz").expect(http.StatusOK)
+}
+
+func TestProbesNotRateLimited(t *testing.T) {
+	h := newHarness(t, func(cfg *config.Config) {
+		cfg.RateLimit = config.RateLimitConfig{Enabled: true, RequestsPerSecond: 0.001, Burst: 1}
+	})
+
+	h.probe("/").expect(http.StatusOK)
+	h.probe("/").expect(http.StatusTooManyRequests)
+	for i := 0; i < 3; i++ {
+		h.probe("/healthz").expect(http.StatusOK)
+		h.probe("/readyz").expect(http.StatusOK)
+	}
+}
+
+func TestReadinessDuringShutdown(t *testing.T) {
+	h := newHarness(t)
+
+	h.app.Health.ShutDown()
+	h.probe("/readyz").expect(http.StatusServiceUnavailable)
+	h.probe("/healthz").expect(http.StatusOK)
+}
+
+func TestHealthDetails(t *testing.T) {
+	h := newHarness(t, func(cfg *config.Config) { cfg.Health.CheckTimeout = 50 * time.Millisecond })
+	admin := h.admin()
+
+	h.request(http.MethodGet, "/health/details", nil, h.user()).expect(http.StatusForbidden)
+	h.request(http.MethodGet, "/health/details", nil, "").expect(http.StatusUnauthorized)
+
+	var report health.Report
+	h.request(http.MethodGet, "/health/details", nil, admin).expect(http.StatusOK).data(&report)
+	if report.Status != health.StatusUp || len(report.Checks) != 1 || report.Checks[0].Name != "database" {
+		t.Fatalf("health report = %+v, want the database up", report)
+	}
+
+	// A check that hangs is cut off at the timeout
+	h.app.Health.Register("cache", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	h.request(http.MethodGet, "/health/details", nil, admin).expect(http.StatusServiceUnavailable).data(&report)
+	if len(report.Checks) != 2 {
+		t.Fatalf("health report has %d checks, want 2", len(report.Checks))
+	}
+	cache, database := report.Checks[0], report.Checks[1]
+	if cache.Status != health.StatusDown || !strings.Contains(cache.Error, "timed out") || cache.LatencyMS < 50 {
+		t.Errorf("cache check = %+v, want it timed out after 50ms", cache)
+	}
+	if database.Status != health.StatusUp {
+		t.Errorf("database check = %+v, want it up", database)
+	}
+}
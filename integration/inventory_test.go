@@ -0,0 +1,266 @@
+package integration_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// stock returns the product's stock as admins see it.
+func (h *harness) stock(admin string, product models.Product) int {
+	h.t.Helper()
+	var current models.Product
+	h.request(http.MethodGet, "/products/"+product.ID.String(), nil, admin).expect(http.StatusOK).data(&current)
+	return current.Stock
+}
+
+func TestAdjustStock(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 5})
+
+	adjust := func(input models.StockAdjustmentInput) *response {
+		return h.request(http.MethodPost, "/inventory/adjustments", input, admin)
+	}
+	var movement models.StockMovement
+	adjust(models.StockAdjustmentInput{ProductID: mug.ID.String(), Quantity: 3, Type: "receipt", Reason: "Delivery"}).
+		expect(http.StatusOK).data(&movement)
+	if movement.Type != models.StockMovementReceipt || movement.BalanceAfter != 8 {
+		t.Fatalf("receipt movement = %+v", movement)
+	}
+	adjust(models.StockAdjustmentInput{ProductID: mug.ID.String(), Quantity: -2, Reason: "Broken"}).expect(http.StatusOK)
+	if got := h.stock(admin, mug); got != 6 {
+		t.Errorf("stock after adjustments = %d, want 6", got)
+	}
+
+	adjust(models.StockAdjustmentInput{ProductID: mug.ID.String(), Quantity: -7, Reason: "Lost"}).
+		expectMessage(http.StatusBadRequest, "below zero")
+	adjust(models.StockAdjustmentInput{ProductID: uuid.NewString(), Quantity: 1, Reason: "Found"}).expect(http.StatusNotFound)
+	adjust(models.StockAdjustmentInput{ProductID: mug.ID.String(), WarehouseID: uuid.NewString(), Quantity: 1, Reason: "Found"}).
+		expect(http.StatusNotFound)
+	adjust(models.StockAdjustmentInput{ProductID: mug.ID.String(), Quantity: 0, Reason: "Nothing"}).expect(http.StatusBadRequest)
+
+	var movements []models.StockMovement
+	movementsPath := "/products/" + mug.ID.String() + "/movements"
+	h.request(http.MethodGet, movementsPath, nil, admin).expect(http.StatusOK).data(&movements)
+	if len(movements) != 3 {
+		t.Errorf("product has %d movements, want 3", len(movements))
+	}
+	h.request(http.MethodGet, movementsPath+"?type=receipt", nil, admin).expect(http.StatusOK).data(&movements)
+	if len(movements) != 2 {
+		t.Errorf("product has %d receipts, want 2", len(movements))
+	}
+	h.request(http.MethodGet, movementsPath+"?limit=1000", nil, admin).expect(http.StatusBadRequest)
+	h.request(http.MethodGet, "/products/"+uuid.NewString()+"/movements", nil, admin).expect(http.StatusNotFound)
+}
+
+func TestWarehousesAndTransfers(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 5})
+
+	var warehouses []models.Warehouse
+	h.request(http.MethodGet, "/warehouses", nil, admin).expect(http.StatusOK).data(&warehouses)
+	if len(warehouses) != 1 {
+		t.Fatalf("found %d warehouses, want the default one", len(warehouses))
+	}
+	main := warehouses[0]
+
+	var east models.Warehouse
+	h.request(http.MethodPost, "/warehouses", models.WarehouseInput{Name: "East", Code: "east", Address: address, Priority: 1}, admin).
+		expect(http.StatusOK).data(&east)
+	h.request(http.MethodPost, "/warehouses", models.WarehouseInput{Name: "East again", Code: "east", Address: address}, admin).
+		expectMessage(http.StatusBadRequest, "already exists")
+	h.request(http.MethodPost, "/warehouses", models.WarehouseInput{Name: "Nowhere", Code: "none"}, admin).expect(http.StatusBadRequest)
+
+	inactive := false
+	var west models.Warehouse
+	h.request(http.MethodPost, "/warehouses", models.WarehouseInput{Name: "West", Code: "west", Address: address, Active: &inactive}, admin).
+		expect(http.StatusOK).data(&west)
+	h.request(http.MethodGet, "/warehouses", nil, admin).expect(http.StatusOK).data(&warehouses)
+	for _, w := range warehouses {
+		if w.ID == west.ID && w.Active {
+			t.Error("warehouse created inactive is active")
+		}
+	}
+
+	transfer := func(from, to models.Warehouse, quantity int) *response {
+		return h.request(http.MethodPost, "/inventory/transfers", models.StockTransferInput{
+			ProductID: mug.ID.String(), FromWarehouseID: from.ID.String(), ToWarehouseID: to.ID.String(), Quantity: quantity,
+		}, admin)
+	}
+	transfer(main, east, 2).expect(http.StatusOK)
+	transfer(main, east, 4).expectMessage(http.StatusBadRequest, "Not enough stock")
+	transfer(main, main, 1).expect(http.StatusBadRequest)
+	transfer(main, models.Warehouse{ID: uuid.New()}, 1).expect(http.StatusNotFound)
+
+	var levels []models.StockLevel
+	h.request(http.MethodGet, "/products/"+mug.ID.String()+"/stock", nil, admin).expect(http.StatusOK).data(&levels)
+	if len(levels) != 2 {
+		t.Fatalf("product is stocked in %d warehouses, want 2", len(levels))
+	}
+	h.request(http.MethodGet, "/warehouses/"+east.ID.String()+"/stock", nil, admin).expect(http.StatusOK).data(&levels)
+	if len(levels) != 1 || levels[0].Quantity != 2 {
+		t.Errorf("east stock = %+v, want 2 mugs", levels)
+	}
+	if got := h.stock(admin, mug); got != 5 {
+		t.Errorf("total stock after transfer = %d, want 5", got)
+	}
+	h.request(http.MethodGet, "/warehouses/"+uuid.NewString()+"/stock", nil, admin).expect(http.StatusNotFound)
+	h.request(http.MethodGet, "/products/"+uuid.NewString()+"/stock", nil, admin).expect(http.StatusNotFound)
+
+	path := "/warehouses/" + east.ID.String()
+	h.request(http.MethodPut, path, models.WarehouseInput{Name: "East coast", Code: "east", Address: address, Priority: 2}, admin).
+		expect(http.StatusOK).data(&east)
+	if east.Name != "East coast" || east.Priority != 2 {
+		t.Errorf("updated warehouse = %+v", east)
+	}
+	h.request(http.MethodPut, path, models.WarehouseInput{Name: "East", Code: "main", Address: address}, admin).
+		expectMessage(http.StatusBadRequest, "already exists")
+	h.request(http.MethodPut, "/warehouses/"+uuid.NewString(), models.WarehouseInput{Name: "X", Code: "x", Address: address}, admin).
+		expect(http.StatusNotFound)
+
+	h.request(http.MethodDelete, path, nil, admin).expectMessage(http.StatusBadRequest, "still holds stock")
+	transfer(east, main, 2).expect(http.StatusOK)
+	h.request(http.MethodDelete, path, nil, admin).expect(http.StatusOK)
+	h.request(http.MethodDelete, path, nil, admin).expect(http.StatusNotFound)
+}
+
+func TestDeleteAllocatedWarehouse(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 5})
+
+	var warehouses []models.Warehouse
+	h.request(http.MethodGet, "/warehouses", nil, admin).expect(http.StatusOK).data(&warehouses)
+	main := warehouses[0]
+	var east models.Warehouse
+	h.request(http.MethodPost, "/warehouses", models.WarehouseInput{Name: "East", Code: "east", Address: address, Priority: 1}, admin).
+		expect(http.StatusOK).data(&east)
+	transfer := func(from, to models.Warehouse, quantity int) {
+		h.request(http.MethodPost, "/inventory/transfers", models.StockTransferInput{
+			ProductID: mug.ID.String(), FromWarehouseID: from.ID.String(), ToWarehouseID: to.ID.String(), Quantity: quantity,
+		}, admin).expect(http.StatusOK)
+	}
+
+	// The order is allocated from east, which is then emptied
+	transfer(main, east, 5)
+	order := h.order(user, 2, mug)
+	transfer(east, main, 3)
+
+	path := "/warehouses/" + east.ID.String()
+	h.request(http.MethodDelete, path, nil, admin).expectMessage(http.StatusBadRequest, "deactivate it instead")
+	inactive := false
+	h.request(http.MethodPut, path, models.WarehouseInput{Name: "East", Code: "east", Address: address, Priority: 1, Active: &inactive}, admin).
+		expect(http.StatusOK)
+
+	// Canceling the order still restocks east
+	h.setStatus(admin, order, models.OrderStatusCanceled).expect(http.StatusOK)
+	var levels []models.StockLevel
+	h.request(http.MethodGet, path+"/stock", nil, admin).expect(http.StatusOK).data(&levels)
+	if len(levels) != 1 || levels[0].Quantity != 2 {
+		t.Errorf("east stock after cancel = %+v, want 2 mugs", levels)
+	}
+
+	// Lowering the stock reaches the inactive warehouse once main is empty
+	h.request(http.MethodPatch, "/products/"+mug.ID.String(), `{"stock": 1}`, admin, "Content-Type", "application/merge-patch+json").
+		expect(http.StatusOK)
+	h.request(http.MethodGet, path+"/stock", nil, admin).expect(http.StatusOK).data(&levels)
+	if len(levels) != 1 || levels[0].Quantity != 1 {
+		t.Errorf("east stock after lowering = %+v, want 1 mug", levels)
+	}
+}
+
+func TestLowStockAndBackorders(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	h.product(admin, models.ProductInput{Name: "Lamp", Stock: 50})
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 1, BackorderMode: models.BackorderAllowed})
+
+	var low []models.Product
+	h.request(http.MethodGet, "/inventory/low-stock", nil, admin).expect(http.StatusOK).data(&low)
+	if len(low) != 1 || low[0].ID != mug.ID {
+		t.Errorf("low-stock products = %+v, want only the mug", low)
+	}
+	h.request(http.MethodGet, "/inventory/low-stock?threshold=100", nil, admin).expect(http.StatusOK).data(&low)
+	if len(low) != 2 {
+		t.Errorf("products under 100 = %d, want 2", len(low))
+	}
+	h.request(http.MethodGet, "/inventory/low-stock?threshold=-1", nil, admin).expect(http.StatusBadRequest)
+
+	order := h.order(user, 3, mug)
+	if order.Items[0].BackorderedQuantity != 2 {
+		t.Fatalf("backordered quantity = %d, want 2", order.Items[0].BackorderedQuantity)
+	}
+	var backorders []models.OrderItem
+	h.request(http.MethodGet, "/inventory/backorders?product_id="+mug.ID.String(), nil, admin).expect(http.StatusOK).data(&backorders)
+	if len(backorders) != 1 {
+		t.Fatalf("found %d backorders, want 1", len(backorders))
+	}
+	h.request(http.MethodGet, "/inventory/backorders?product_id=mug", nil, admin).expect(http.StatusBadRequest)
+
+	// New stock goes to the backorder first
+	h.request(http.MethodPost, "/inventory/adjustments", models.StockAdjustmentInput{
+		ProductID: mug.ID.String(), Quantity: 5, Type: "receipt", Reason: "Delivery",
+	}, admin).expect(http.StatusOK)
+	h.request(http.MethodGet, "/inventory/backorders", nil, admin).expect(http.StatusOK).data(&backorders)
+	if len(backorders) != 0 {
+		t.Errorf("found %d backorders after restock, want 0", len(backorders))
+	}
+	if got := h.stock(admin, mug); got != 3 {
+		t.Errorf("stock after restock = %d, want 3", got)
+	}
+}
+
+func TestPreorderWithoutStock(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	release := time.Now().AddDate(0, 1, 0)
+	game := h.product(admin, models.ProductInput{Name: "Game", BackorderMode: models.BackorderPreorder, ReleaseDate: &release})
+	if game.Stock != 0 {
+		t.Fatalf("new product stock = %d, want 0", game.Stock)
+	}
+
+	order := h.order(user, 2, game)
+	if item := order.Items[0]; !item.Preorder || item.BackorderedQuantity != 2 {
+		t.Errorf("pre-ordered item = %+v, want 2 units on pre-order", item)
+	}
+}
+
+func TestReconciliation(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 5})
+
+	var discrepancies []models.StockDiscrepancy
+	h.request(http.MethodGet, "/inventory/reconciliation", nil, admin).expect(http.StatusOK).data(&discrepancies)
+	if len(discrepancies) != 0 {
+		t.Fatalf("fresh catalog has %d discrepancies", len(discrepancies))
+	}
+
+	// Change stock behind the ledger's back
+	if err := h.app.DB.Model(&models.Product{}).Where("id = ?", mug.ID).Update("stock", 9).Error; err != nil {
+		t.Fatal(err)
+	}
+	h.request(http.MethodGet, "/inventory/reconciliation", nil, admin).expect(http.StatusOK).data(&discrepancies)
+	if len(discrepancies) != 1 || discrepancies[0].LedgerStock != 5 || discrepancies[0].Stock != 9 {
+		t.Fatalf("discrepancies = %+v, want the mug at 9 against a ledger of 5", discrepancies)
+	}
+
+	var movements []models.StockMovement
+	h.request(http.MethodPost, "/inventory/reconciliation", nil, admin).expect(http.StatusOK).data(&movements)
+	if len(movements) != 1 || movements[0].Quantity != 4 {
+		t.Errorf("reconciliation movements = %+v, want one of 4", movements)
+	}
+	h.request(http.MethodGet, "/inventory/reconciliation", nil, admin).expect(http.StatusOK).data(&discrepancies)
+	if len(discrepancies) != 0 {
+		t.Errorf("%d discrepancies left after reconciling", len(discrepancies))
+	}
+}
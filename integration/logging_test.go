@@ -0,0 +1,146 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/config"
+	"github.com/TobiAdeniji94/ecommerce_api/logging"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// logs sends the app's log, at every level and redacted as in
+// production, to a buffer.
+func (h *harness) logs() *bytes.Buffer {
+	var buf bytes.Buffer
+	h.app.Log = logging.New(config.LogConfig{Level: "debug", Format: "json"}, &buf)
+	h.router = h.app.Router()
+	return &buf
+}
+
+// records decodes a JSON log, keeping the records with message msg.
+func records(t *testing.T, buf *bytes.Buffer, msg string) []map[string]interface{} {
+	t.Helper()
+	var found []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line is not JSON: %v: %s", err, line)
+		}
+		if record["msg"] == msg {
+			found = append(found, record)
+		}
+	}
+	return found
+}
+
+func TestRequestID(t *testing.T) {
+	h := newHarness(t)
+
+	given := h.request(http.MethodGet, "/products", nil, h.user(), "X-Request-ID", "checkout-42")
+	if got := given.Header().Get("X-Request-ID"); got != "checkout-42" {
+		t.Errorf("X-Request-ID = %q, want the caller's ID", got)
+	}
+
+	generated := h.probe("/healthz").Header().Get("X-Request-ID")
+	if _, err := uuid.Parse(generated); err != nil {
+		t.Errorf("generated X-Request-ID %q is not a UUID", generated)
+	}
+
+	tooLong := strings.Repeat("x", 200)
+	replaced := h.request(http.MethodGet, "/products", nil, "", "X-Request-ID", tooLong).Header().Get("X-Request-ID")
+	if replaced == tooLong || replaced == "" {
+		t.Errorf("X-Request-ID = %q, want a new ID in place of the oversized one", replaced)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 10})
+	logs := h.logs()
+
+	h.request(http.MethodGet, "/products/"+mug.ID.String()+"?token=abc", nil, user, "X-Request-ID", "req-1").
+		expect(http.StatusOK)
+	h.request(http.MethodGet, "/products/"+uuid.NewString(), nil, user).expect(http.StatusNotFound)
+	h.probe("/healthz").expect(http.StatusOK)
+
+	entries := records(t, logs, "request")
+	if len(entries) != 3 {
+		t.Fatalf("got %d access log records, want 3:\n%s", len(entries), logs.String())
+	}
+
+	found := entries[0]
+	for key, want := range map[string]interface{}{
+		"level":      "INFO",
+		"request_id": "req-1",
+		"method":     "GET",
+		"route":      "/api/v1/products/:id",
+		"path":       "/api/v1/products/" + mug.ID.String(),
+		"status":     float64(http.StatusOK),
+	} {
+		if found[key] != want {
+			t.Errorf("%s = %v, want %v", key, found[key], want)
+		}
+	}
+	if userID, _ := found["user_id"].(string); userID == "" {
+		t.Error("the access log does not name the user")
+	}
+	if _, ok := found["latency_ms"].(float64); !ok {
+		t.Error("the access log has no latency")
+	}
+	if _, ok := found["trace_id"]; !ok {
+		t.Error("the access log has no trace ID")
+	}
+
+	if level := entries[1]["level"]; level != "WARN" {
+		t.Errorf("404 logged at %v, want WARN", level)
+	}
+	// Probes are only logged at debug level
+	if level := entries[2]["level"]; level != "DEBUG" {
+		t.Errorf("health probe logged at %v, want DEBUG", level)
+	}
+	if strings.Contains(logs.String(), "token=abc") {
+		t.Error("the query string was logged")
+	}
+}
+
+func TestLoginFailureLog(t *testing.T) {
+	h := newHarness(t)
+	email := h.uniqueEmail("customer")
+	id := h.register(email, "")
+	logs := h.logs()
+
+	h.request(http.MethodPost, "/users/login", models.LoginInput{Email: email, Password: "Tr0ub4dor&3"}, "").
+		expect(http.StatusUnauthorized)
+	h.request(http.MethodPost, "/users/login", models.LoginInput{Email: "nobody@example.com", Password: password}, "").
+		expect(http.StatusUnauthorized)
+
+	rejected := records(t, logs, "Login rejected")
+	if len(rejected) != 2 {
+		t.Fatalf("got %d login rejections, want 2:\n%s", len(rejected), logs.String())
+	}
+	if rejected[0]["reason"] != "wrong password" || rejected[0]["user_id"] != id.String() {
+		t.Errorf("wrong password logged as %v", rejected[0])
+	}
+	if rejected[1]["reason"] != "unknown email" || rejected[1]["email"] != "n***@example.com" {
+		t.Errorf("unknown email logged as %v", rejected[1])
+	}
+	if rejected[0]["route"] != "/api/v1/users/login" || rejected[0]["request_id"] == "" {
+		t.Errorf("login rejection lacks the request's details: %v", rejected[0])
+	}
+	for _, secret := range []string{email, "nobody@example.com", "Tr0ub4dor&3", password} {
+		if strings.Contains(logs.String(), secret) {
+			t.Errorf("the log contains %q", secret)
+		}
+	}
+}
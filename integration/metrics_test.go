@@ -0,0 +1,115 @@
+package integration_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/TobiAdeniji94/ecommerce_api/config"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/payments"
+)
+
+// scrape returns the metrics exposition, sending token when it is set.
+func (h *harness) scrape(token string) string {
+	h.t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.router.ServeHTTP(rec, req)
+	(&response{t: h.t, ResponseRecorder: rec}).expect(http.StatusOK)
+	return rec.Body.String()
+}
+
+// expectMetric fails unless the exposition has a sample line starting
+// with series and ending with value.
+func expectMetric(t *testing.T, exposition, series, value string) {
+	t.Helper()
+	for _, line := range strings.Split(exposition, "\n") {
+		if strings.HasPrefix(line, series+" ") {
+			if got := strings.TrimPrefix(line, series+" "); got != value {
+				t.Errorf("%s = %s, want %s", series, got, value)
+			}
+			return
+		}
+	}
+	t.Errorf("no sample for %s", series)
+}
+
+func TestMetrics(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Price: 12.5, Stock: 10})
+
+	order := h.order(user, 2, mug)
+	h.pay(user, admin, order)
+	h.setStatus(admin, order, models.OrderStatusCanceled).expect(http.StatusOK)
+	h.request(http.MethodPut, "/orders/"+h.order(user, 1, mug).ID.String()+"/cancel", nil, user).expect(http.StatusOK)
+	h.request(http.MethodPost, "/users/login", models.LoginInput{Email: "nobody@example.com", Password: "secret123"}, "").
+		expect(http.StatusUnauthorized)
+	h.probe("/no-such-page").expect(http.StatusNotFound)
+
+	exposition := h.scrape("")
+	expectMetric(t, exposition, "ecommerce_orders_placed_total", "2")
+	expectMetric(t, exposition, `ecommerce_orders_canceled_total{by="admin"}`, "1")
+	expectMetric(t, exposition, `ecommerce_orders_canceled_total{by="customer"}`, "1")
+	expectMetric(t, exposition, `ecommerce_revenue_total{currency="usd"}`, "25")
+	expectMetric(t, exposition, "ecommerce_login_failures_total", "1")
+	// Routes are labeled by template, and unknown paths share one label
+	expectMetric(t, exposition, `ecommerce_http_requests_total{method="PUT",route="/api/v1/orders/:id/cancel",status="200"}`, "1")
+	expectMetric(t, exposition, `ecommerce_http_requests_total{method="GET",route="unmatched",status="404"}`, "1")
+	expectMetric(t, exposition, `ecommerce_http_request_duration_seconds_count{method="POST",route="/api/v1/users/login",status="401"}`, "1")
+	expectMetric(t, exposition, "ecommerce_http_requests_in_flight", "1")
+	expectMetric(t, exposition, `go_sql_max_open_connections{db_name="sqlite"}`, "1")
+}
+
+func TestPaymentWebhookRevenueCountedOnce(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Price: 10, Stock: 10})
+
+	order := h.order(user, 1, mug)
+	payment := h.createPayment(user, order)
+	h.request(http.MethodPost, "/payments/"+payment.ID.String()+"/capture", nil, admin).expect(http.StatusOK)
+	// The provider reports the capture as well
+	h.webhook("evt_captured", payments.EventPaymentSucceeded, payment.ProviderRef).expect(http.StatusOK)
+
+	expectMetric(t, h.scrape(""), `ecommerce_revenue_total{currency="usd"}`, "10")
+}
+
+func TestMetricsRateLimitRejections(t *testing.T) {
+	h := newHarness(t, func(cfg *config.Config) {
+		cfg.RateLimit = config.RateLimitConfig{Enabled: true, RequestsPerSecond: 0.001, Burst: 1}
+	})
+
+	h.probe("/").expect(http.StatusOK)
+	h.probe("/").expect(http.StatusTooManyRequests)
+	h.probe("/").expect(http.StatusTooManyRequests)
+
+	exposition := h.scrape("")
+	expectMetric(t, exposition, "ecommerce_rate_limit_rejections_total", "2")
+	expectMetric(t, exposition, `ecommerce_http_requests_total{method="GET",route="/",status="429"}`, "2")
+}
+
+func TestMetricsToken(t *testing.T) {
+	h := newHarness(t, func(cfg *config.Config) { cfg.Metrics.Token = "scrape-secret" })
+
+	h.probe("/metrics").expect(http.StatusUnauthorized)
+	if exposition := h.scrape("scrape-secret"); !strings.Contains(exposition, "ecommerce_http_requests_total") {
+		t.Error("metrics are missing the request counter")
+	}
+}
+
+func TestMetricsDisabled(t *testing.T) {
+	h := newHarness(t, func(cfg *config.Config) { cfg.Features.Metrics = false })
+
+	h.probe("/metrics").expect(http.StatusNotFound)
+	// Business events are still handled without metrics
+	h.request(http.MethodPost, "/users/login", models.LoginInput{Email: "nobody@example.com", Password: "secret123"}, "").
+		expect(http.StatusUnauthorized)
+}
@@ -0,0 +1,233 @@
+package integration_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/middleware"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/utils"
+)
+
+func TestPlaceOrder(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Price: 8, Stock: 5})
+
+	order := h.order(user, 2, mug)
+	if order.Status != models.OrderStatusPending || order.Subtotal != 16 || len(order.Items) != 1 {
+		t.Fatalf("placed order = %+v", order)
+	}
+	var product models.Product
+	h.request(http.MethodGet, "/products/"+mug.ID.String(), nil, user).expect(http.StatusOK).data(&product)
+	if product.Stock != 3 {
+		t.Errorf("stock after order = %d, want 3", product.Stock)
+	}
+
+	place := func(items ...models.OrderItemInput) *response {
+		return h.request(http.MethodPost, "/orders", models.PlaceOrderInput{Items: items, ShippingAddress: &address}, user)
+	}
+	place(models.OrderItemInput{ProductID: mug.ID.String(), Quantity: 4}).
+		expectMessage(http.StatusBadRequest, "Insufficient stock")
+	place(models.OrderItemInput{ProductID: uuid.NewString(), Quantity: 1}).
+		expectMessage(http.StatusBadRequest, "Product not found")
+	place(models.OrderItemInput{ProductID: "mug", Quantity: 1}).
+		expectMessage(http.StatusBadRequest, "Invalid product ID")
+	place(models.OrderItemInput{ProductID: mug.ID.String(), Quantity: 0}).expect(http.StatusBadRequest)
+	h.request(http.MethodPost, "/orders", models.PlaceOrderInput{
+		Items:           []models.OrderItemInput{{ProductID: mug.ID.String(), Quantity: 1}},
+		ShippingAddress: &models.AddressInput{Line1: "1 Main St", City: "Springfield"},
+	}, user).expect(http.StatusBadRequest)
+
+	// Failed orders must not take stock
+	h.request(http.MethodGet, "/products/"+mug.ID.String(), nil, user).expect(http.StatusOK).data(&product)
+	if product.Stock != 3 {
+		t.Errorf("stock after failed orders = %d, want 3", product.Stock)
+	}
+}
+
+func TestGetOrders(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	ada := h.user()
+	bob := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 10})
+	first := h.order(ada, 1, mug)
+	h.order(ada, 1, mug)
+	h.order(bob, 1, mug)
+
+	var orders []models.Order
+	h.request(http.MethodGet, "/orders", nil, ada).expect(http.StatusOK).data(&orders)
+	if len(orders) != 2 || orders[0].ID != first.ID {
+		t.Fatalf("ada's orders = %+v, want her 2 orders oldest first", orders)
+	}
+	if orders[0].User.Password != "" {
+		t.Error("order list exposes the password hash")
+	}
+
+	path := "/orders/" + first.ID.String()
+	resp := h.request(http.MethodGet, path, nil, ada).expect(http.StatusOK)
+	h.request(http.MethodGet, path, nil, ada, "If-None-Match", resp.Header().Get("ETag")).expect(http.StatusNotModified)
+	h.request(http.MethodGet, path, nil, admin).expect(http.StatusOK)
+	h.request(http.MethodGet, path, nil, bob).expect(http.StatusNotFound)
+	h.request(http.MethodGet, "/orders/"+uuid.NewString(), nil, ada).expect(http.StatusNotFound)
+	h.request(http.MethodGet, "/orders/not-a-uuid", nil, ada).expectMessage(http.StatusBadRequest, "Invalid order ID")
+}
+
+func TestCancelOrder(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	ada := h.user()
+	bob := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 5})
+	order := h.order(ada, 2, mug)
+	path := "/orders/" + order.ID.String() + "/cancel"
+
+	h.request(http.MethodPut, path, nil, bob).expect(http.StatusNotFound)
+	h.request(http.MethodPut, path, nil, ada, "If-Match", utils.ETag(order.Version+1)).expect(http.StatusPreconditionFailed)
+	h.request(http.MethodPut, path, nil, ada, "If-Match", utils.ETag(order.Version)).expect(http.StatusOK)
+	h.request(http.MethodPut, path, nil, ada).expectMessage(http.StatusBadRequest, "cannot be canceled")
+
+	var product models.Product
+	h.request(http.MethodGet, "/products/"+mug.ID.String(), nil, ada).expect(http.StatusOK).data(&product)
+	if product.Stock != 5 {
+		t.Errorf("stock after cancel = %d, want 5", product.Stock)
+	}
+}
+
+func TestUpdateOrderStatus(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 10})
+	order := h.order(user, 1, mug)
+
+	h.setStatus(admin, order, models.OrderStatusDelivered).expectMessage(http.StatusBadRequest, "cannot move from Pending")
+	h.setStatus(admin, order, "Lost").expect(http.StatusBadRequest)
+	h.request(http.MethodPut, "/orders/"+order.ID.String()+"/status", `{}`, admin, "Content-Type", "application/json").
+		expect(http.StatusBadRequest)
+	h.request(http.MethodPut, "/orders/"+uuid.NewString()+"/status",
+		models.UpdateOrderStatusInput{Status: models.OrderStatusPaid}, admin).expect(http.StatusNotFound)
+
+	// Paid and PaymentFailed follow the payments, so a paid order can always be refunded
+	h.setStatus(admin, order, models.OrderStatusPaid).expectMessage(http.StatusBadRequest, "through their payments")
+	h.setStatus(admin, order, models.OrderStatusPaymentFailed).expectMessage(http.StatusBadRequest, "through their payments")
+	h.pay(user, admin, order)
+	h.setStatus(admin, order, models.OrderStatusShipped).expectMessage(http.StatusBadRequest, "Create a shipment")
+
+	shipments := "/orders/" + order.ID.String() + "/shipments"
+	h.request(http.MethodPost, shipments, models.CreateShipmentInput{Carrier: "UPS"}, admin).expect(http.StatusBadRequest)
+	h.request(http.MethodPost, "/orders/"+uuid.NewString()+"/shipments",
+		models.CreateShipmentInput{Carrier: "UPS", TrackingNumber: "1Z999"}, admin).expect(http.StatusNotFound)
+	h.request(http.MethodPost, shipments, models.CreateShipmentInput{Carrier: "UPS", TrackingNumber: "1Z999"}, admin).
+		expect(http.StatusOK)
+
+	stale := h.request(http.MethodPut, "/orders/"+order.ID.String()+"/status",
+		models.UpdateOrderStatusInput{Status: models.OrderStatusShipped}, admin, "If-Match", utils.ETag(order.Version))
+	stale.expect(http.StatusPreconditionFailed)
+
+	var shipped models.Order
+	h.setStatus(admin, order, models.OrderStatusShipped).expect(http.StatusOK).data(&shipped)
+	if shipped.Status != models.OrderStatusShipped || len(shipped.Shipments) != 1 {
+		t.Fatalf("shipped order = %+v", shipped)
+	}
+	h.setStatus(admin, order, models.OrderStatusCanceled).expect(http.StatusBadRequest)
+	h.setStatus(admin, order, models.OrderStatusDelivered).expect(http.StatusOK)
+	// Refunded follows approved returns, which refund the payment and restock
+	h.setStatus(admin, order, models.OrderStatusRefunded).expectMessage(http.StatusBadRequest, "returns are approved")
+	if got := h.getOrder(user, order.ID).Status; got != models.OrderStatusDelivered {
+		t.Errorf("order status = %s, want Delivered", got)
+	}
+
+	// Only paid orders can be shipped
+	pending := h.order(user, 1, mug)
+	h.request(http.MethodPost, "/orders/"+pending.ID.String()+"/shipments",
+		models.CreateShipmentInput{Carrier: "UPS", TrackingNumber: "1Z998"}, admin).expectMessage(http.StatusBadRequest, "Only paid orders")
+}
+
+func TestIdempotentOrders(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 5})
+	input := models.PlaceOrderInput{
+		Items:           []models.OrderItemInput{{ProductID: mug.ID.String(), Quantity: 1}},
+		ShippingAddress: &address,
+	}
+
+	var first, retried struct {
+		OrderID uuid.UUID `json:"order_id"`
+	}
+	h.request(http.MethodPost, "/orders", input, user, "Idempotency-Key", "order-1").expect(http.StatusOK).data(&first)
+	resp := h.request(http.MethodPost, "/orders", input, user, "Idempotency-Key", "order-1").expect(http.StatusOK)
+	resp.data(&retried)
+	if retried.OrderID != first.OrderID || resp.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("retry returned order %s (replayed %q), want a replay of %s",
+			retried.OrderID, resp.Header().Get("Idempotent-Replayed"), first.OrderID)
+	}
+
+	input.Items[0].Quantity = 2
+	h.request(http.MethodPost, "/orders", input, user, "Idempotency-Key", "order-1").
+		expectMessage(http.StatusConflict, "different request")
+
+	var orders []models.Order
+	h.request(http.MethodGet, "/orders", nil, user).expect(http.StatusOK).data(&orders)
+	if len(orders) != 1 {
+		t.Errorf("user has %d orders, want 1", len(orders))
+	}
+
+	// Keys are scoped to the user
+	h.request(http.MethodPost, "/orders", input, h.user(), "Idempotency-Key", "order-1").expect(http.StatusOK)
+}
+
+func TestIdempotencyKeyReleasedAfterPanic(t *testing.T) {
+	h := newHarness(t)
+	user := h.user()
+
+	// A route that panics on its first call
+	calls := 0
+	h.router.(*gin.Engine).POST("/api/v1/flaky",
+		middleware.Auth(h.app.Tokens),
+		middleware.Idempotency(h.app.DB, time.Hour, h.app.Clock),
+		func(c *gin.Context) {
+			calls++
+			if calls == 1 {
+				panic("boom")
+			}
+			c.JSON(http.StatusOK, gin.H{"calls": calls})
+		})
+
+	h.request(http.MethodPost, "/flaky", nil, user, "Idempotency-Key", "flaky-1").expect(http.StatusInternalServerError)
+	h.request(http.MethodPost, "/flaky", nil, user, "Idempotency-Key", "flaky-1").expect(http.StatusOK)
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want 2", calls)
+	}
+}
+
+func TestIdempotencyPurge(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 5})
+	input := models.PlaceOrderInput{
+		Items:           []models.OrderItemInput{{ProductID: mug.ID.String(), Quantity: 1}},
+		ShippingAddress: &address,
+	}
+	h.request(http.MethodPost, "/orders", input, user, "Idempotency-Key", "order-1").expect(http.StatusOK)
+
+	purger := &middleware.IdempotencyPurger{DB: h.app.DB, Clock: utils.SystemClock{}}
+	if purged, err := purger.Purge(context.Background()); err != nil || purged != 0 {
+		t.Fatalf("Purge before expiry = %d, %v; want 0, nil", purged, err)
+	}
+
+	purger.Clock = fixedClock(time.Now().Add(h.app.Config.Idempotency.TTL + time.Minute))
+	if purged, err := purger.Purge(context.Background()); err != nil || purged != 1 {
+		t.Fatalf("Purge after expiry = %d, %v; want 1, nil", purged, err)
+	}
+}
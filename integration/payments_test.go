@@ -0,0 +1,182 @@
+package integration_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/config"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/payments"
+)
+
+// createPayment starts a payment for the order.
+func (h *harness) createPayment(token string, order models.Order) models.Payment {
+	h.t.Helper()
+	var created struct {
+		Payment      models.Payment `json:"payment"`
+		ClientSecret string         `json:"client_secret"`
+	}
+	h.request(http.MethodPost, "/orders/"+order.ID.String()+"/payments", nil, token).expect(http.StatusOK).data(&created)
+	if created.ClientSecret == "" {
+		h.t.Fatal("payment has no client secret")
+	}
+	return created.Payment
+}
+
+func TestCreateAndCapturePayment(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	ada := h.user()
+	bob := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Price: 8, Stock: 10})
+	order := h.order(ada, 2, mug)
+
+	h.request(http.MethodPost, "/orders/"+order.ID.String()+"/payments", nil, bob).expect(http.StatusNotFound)
+	h.request(http.MethodPost, "/orders/not-a-uuid/payments", nil, ada).expect(http.StatusBadRequest)
+
+	payment := h.createPayment(ada, order)
+	if payment.Status != models.PaymentStatusRequiresCapture || payment.Amount != order.Total {
+		t.Fatalf("payment = %+v, want %v awaiting capture", payment, order.Total)
+	}
+
+	capture := "/payments/" + payment.ID.String() + "/capture"
+	h.request(http.MethodPost, "/payments/"+uuid.NewString()+"/capture", nil, admin).expect(http.StatusNotFound)
+	h.request(http.MethodPost, "/payments/not-a-uuid/capture", nil, admin).expect(http.StatusBadRequest)
+	var captured models.Payment
+	h.request(http.MethodPost, capture, nil, admin).expect(http.StatusOK).data(&captured)
+	if captured.Status != models.PaymentStatusSucceeded {
+		t.Errorf("captured payment status = %s", captured.Status)
+	}
+	h.request(http.MethodPost, capture, nil, admin).expectMessage(http.StatusBadRequest, "cannot be captured")
+
+	if got := h.getOrder(ada, order.ID).Status; got != models.OrderStatusPaid {
+		t.Errorf("order status after capture = %s, want Paid", got)
+	}
+	h.request(http.MethodPost, "/orders/"+order.ID.String()+"/payments", nil, ada).expectMessage(http.StatusBadRequest, "cannot be paid")
+}
+
+func TestOneCapturePerOrder(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	order := h.order(user, 1, h.product(admin, models.ProductInput{Name: "Mug", Price: 8, Stock: 10}))
+
+	// A new payment cancels the one before it
+	first := h.createPayment(user, order)
+	second := h.createPayment(user, order)
+	h.request(http.MethodPost, "/payments/"+first.ID.String()+"/capture", nil, admin).
+		expectMessage(http.StatusBadRequest, "Current status: canceled")
+
+	// A payment left open by a concurrent request is refused once the order is paid
+	intent, err := h.payments.CreateIntent(context.Background(), payments.CreateIntentParams{OrderID: order.ID.String(), Amount: 800, Currency: "usd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stray := models.Payment{OrderID: order.ID, Provider: "fake", ProviderRef: intent.ID, Amount: 8, Currency: "usd", Status: models.PaymentStatusRequiresCapture}
+	if err := h.app.DB.Create(&stray).Error; err != nil {
+		t.Fatal(err)
+	}
+	h.request(http.MethodPost, "/payments/"+second.ID.String()+"/capture", nil, admin).expect(http.StatusOK)
+	h.request(http.MethodPost, "/payments/"+stray.ID.String()+"/capture", nil, admin).
+		expectMessage(http.StatusBadRequest, "Order status: Paid")
+}
+
+func TestIdempotentCapture(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	order := h.order(user, 1, h.product(admin, models.ProductInput{Name: "Mug", Stock: 10}))
+	payment := h.createPayment(user, order)
+	capture := "/payments/" + payment.ID.String() + "/capture"
+
+	h.request(http.MethodPost, capture, nil, admin, "Idempotency-Key", "capture-1").expect(http.StatusOK)
+	resp := h.request(http.MethodPost, capture, nil, admin, "Idempotency-Key", "capture-1").expect(http.StatusOK)
+	if resp.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retried capture was not replayed")
+	}
+}
+
+func TestPaymentWebhook(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 10})
+
+	paid := h.order(user, 1, mug)
+	payment := h.createPayment(user, paid)
+	h.webhook("evt_1", payments.EventPaymentSucceeded, payment.ProviderRef).expectMessage(http.StatusOK, "Webhook processed")
+	if got := h.getOrder(user, paid.ID).Status; got != models.OrderStatusPaid {
+		t.Errorf("order status after success event = %s, want Paid", got)
+	}
+	h.webhook("evt_1", payments.EventPaymentSucceeded, payment.ProviderRef).expectMessage(http.StatusOK, "already processed")
+
+	// A failure reported after the success arrived out of order
+	h.webhook("evt_late", payments.EventPaymentFailed, payment.ProviderRef).expect(http.StatusOK)
+	h.setStatus(admin, paid, models.OrderStatusCanceled).expect(http.StatusOK)
+	var refunds []models.Refund
+	if err := h.app.DB.Where("payment_id = ?", payment.ID).Find(&refunds).Error; err != nil || len(refunds) != 1 {
+		t.Errorf("refunds after canceling = %d (%v), want 1", len(refunds), err)
+	}
+
+	failed := h.order(user, 1, mug)
+	payment = h.createPayment(user, failed)
+	h.webhook("evt_2", payments.EventPaymentFailed, payment.ProviderRef).expect(http.StatusOK)
+	if got := h.getOrder(user, failed.ID).Status; got != models.OrderStatusPaymentFailed {
+		t.Errorf("order status after failure event = %s, want PaymentFailed", got)
+	}
+
+	h.webhook("evt_3", "charge.refunded", payment.ProviderRef).expectMessage(http.StatusOK, "Event ignored")
+	h.webhook("evt_4", payments.EventPaymentSucceeded, "pi_unknown").expect(http.StatusNotFound)
+
+	payload, _ := h.payments.SignedEvent("evt_5", payments.EventPaymentSucceeded, payment.ProviderRef)
+	h.request(http.MethodPost, "/payments/webhook", payload, "", payments.SignatureHeader, "t=1,v1=forged").
+		expectMessage(http.StatusBadRequest, "Invalid webhook signature")
+	h.request(http.MethodPost, "/payments/webhook", payload, "").expect(http.StatusBadRequest)
+}
+
+func TestPaymentWebhookForgedWithPublicSecret(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	order := h.order(user, 1, h.product(admin, models.ProductInput{Name: "Mug", Stock: 10}))
+	payment := h.createPayment(user, order)
+
+	// An attacker signing with the well-known fake secret gets nowhere
+	forged := payments.NewFake("whsec_fake")
+	payload, signature := forged.SignedEvent("evt_forged", payments.EventPaymentSucceeded, payment.ProviderRef)
+	h.request(http.MethodPost, "/payments/webhook", payload, "", payments.SignatureHeader, signature).
+		expectMessage(http.StatusBadRequest, "Invalid webhook signature")
+	if got := h.getOrder(user, order.ID).Status; got != models.OrderStatusPending {
+		t.Errorf("order status = %s, want Pending", got)
+	}
+}
+
+func TestFakePaymentProviderConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name, environment, secret, problem string
+	}{
+		{"production", "production", "whsec_random", "PAYMENT_PROVIDER=fake is only allowed"},
+		{"no secret", "development", "", "PAYMENT_WEBHOOK_SECRET is required"},
+		{"public secret", "test", "whsec_fake", "PAYMENT_WEBHOOK_SECRET is required"},
+	} {
+		cfg := config.Defaults()
+		cfg.JWT.Secret = "secret"
+		cfg.Server.Environment = tc.environment
+		cfg.Payments.WebhookSecret = tc.secret
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), tc.problem) {
+			t.Errorf("%s: Validate() = %v, want %q", tc.name, err, tc.problem)
+		}
+	}
+
+	// The defaults are safe: they refuse to start until configured
+	cfg := config.Defaults()
+	cfg.JWT.Secret = "secret"
+	if err := cfg.Validate(); err == nil {
+		t.Error("the default configuration validates with the fake provider")
+	}
+}
@@ -0,0 +1,197 @@
+package integration_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/catalog"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/utils"
+)
+
+func TestCreateProduct(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+
+	product := h.product(admin, models.ProductInput{SKU: "MUG-1", Name: "Mug", Price: 8.5, Stock: 4})
+	if product.ID == uuid.Nil || product.Stock != 4 || product.Version == 0 {
+		t.Fatalf("created product = %+v", product)
+	}
+
+	h.request(http.MethodPost, "/products", models.ProductInput{SKU: "MUG-1", Name: "Other mug", Price: 9, Stock: 1}, admin).
+		expectMessage(http.StatusBadRequest, "SKU already exists")
+	h.request(http.MethodPost, "/products", models.ProductInput{Name: "Free mug", Price: 0, Stock: 1}, admin).
+		expect(http.StatusBadRequest)
+	h.request(http.MethodPost, "/products", models.ProductInput{Name: "Mug", Price: 8, Stock: 1, BackorderMode: "sometimes"}, admin).
+		expect(http.StatusBadRequest)
+}
+
+func TestGetProducts(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	mug := h.product(admin, models.ProductInput{SKU: "MUG-1", Name: "Mug", Category: "kitchen", Price: 8, Stock: 10})
+	h.product(admin, models.ProductInput{SKU: "LAMP-1", Name: "Desk lamp", Category: "office", Price: 30, Stock: 10})
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 2},
+		{"?category=kitchen", 1},
+		{"?sku=LAMP-1", 1},
+		{"?q=lamp", 1},
+		{"?min_price=10", 1},
+		{"?max_price=100&in_stock=true", 2},
+		{"?in_stock=false", 0},
+	}
+	for _, test := range tests {
+		var products []models.Product
+		h.request(http.MethodGet, "/products"+test.query, nil, user).expect(http.StatusOK).data(&products)
+		if len(products) != test.want {
+			t.Errorf("GET /products%s returned %d products, want %d", test.query, len(products), test.want)
+		}
+	}
+	h.request(http.MethodGet, "/products?min_price=cheap", nil, user).expect(http.StatusBadRequest)
+
+	resp := h.request(http.MethodGet, "/products/"+mug.ID.String(), nil, user).expect(http.StatusOK)
+	var got models.Product
+	resp.data(&got)
+	if got.SKU != "MUG-1" {
+		t.Errorf("GET product SKU = %q, want MUG-1", got.SKU)
+	}
+	etag := resp.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("GET product returned no ETag")
+	}
+	h.request(http.MethodGet, "/products/"+mug.ID.String(), nil, user, "If-None-Match", etag).expect(http.StatusNotModified)
+
+	h.request(http.MethodGet, "/products/"+uuid.NewString(), nil, user).expect(http.StatusNotFound)
+	h.request(http.MethodGet, "/products/not-a-uuid", nil, user).expectMessage(http.StatusBadRequest, "Invalid product ID")
+}
+
+func TestUpdateProduct(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	mug := h.product(admin, models.ProductInput{SKU: "MUG-1", Name: "Mug", Price: 8, Stock: 10})
+	h.product(admin, models.ProductInput{SKU: "LAMP-1", Name: "Lamp", Price: 30, Stock: 10})
+	path := "/products/" + mug.ID.String()
+	read := utils.ETag(mug.Version)
+
+	var updated models.Product
+	h.request(http.MethodPut, path, models.ProductInput{SKU: "MUG-1", Name: "Big mug", Price: 12, Stock: 7}, admin, "If-Match", read).
+		expect(http.StatusOK).data(&updated)
+	if updated.Name != "Big mug" || updated.Stock != 7 || updated.Version <= mug.Version {
+		t.Fatalf("updated product = %+v", updated)
+	}
+
+	h.request(http.MethodPut, path, models.ProductInput{SKU: "MUG-1", Name: "Stale", Price: 12, Stock: 7}, admin, "If-Match", read).
+		expect(http.StatusPreconditionFailed)
+	h.request(http.MethodPut, path, models.ProductInput{SKU: "LAMP-1", Name: "Mug", Price: 12, Stock: 7}, admin).
+		expectMessage(http.StatusBadRequest, "SKU already exists")
+	h.request(http.MethodPut, path, models.ProductInput{Name: "Mug"}, admin).expect(http.StatusBadRequest)
+	h.request(http.MethodPut, "/products/"+uuid.NewString(), models.ProductInput{Name: "Mug", Price: 1, Stock: 1}, admin).
+		expect(http.StatusNotFound)
+}
+
+func TestPatchProduct(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	mug := h.product(admin, models.ProductInput{SKU: "MUG-1", Name: "Mug", Category: "kitchen", Price: 8, Stock: 10})
+	path := "/products/" + mug.ID.String()
+
+	var patched models.Product
+	h.request(http.MethodPatch, path, `{"price": 9.5, "category": null}`, admin, "Content-Type", "application/merge-patch+json").
+		expect(http.StatusOK).data(&patched)
+	if patched.Price != 9.5 || patched.Category != "" || patched.Name != "Mug" {
+		t.Fatalf("patched product = %+v", patched)
+	}
+
+	h.request(http.MethodPatch, path, `{"price": 10}`, admin, "Content-Type", "text/plain").
+		expect(http.StatusUnsupportedMediaType)
+	h.request(http.MethodPatch, path, `{"price": -1}`, admin, "Content-Type", "application/merge-patch+json").
+		expect(http.StatusBadRequest)
+	h.request(http.MethodPatch, path, `{"price": 10}`, admin, "Content-Type", "application/merge-patch+json", "If-Match", utils.ETag(mug.Version)).
+		expect(http.StatusPreconditionFailed)
+}
+
+func TestDeleteProduct(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 10})
+	path := "/products/" + mug.ID.String()
+
+	h.request(http.MethodDelete, path, nil, admin, "If-Match", utils.ETag(mug.Version+1)).expect(http.StatusPreconditionFailed)
+	h.request(http.MethodDelete, path, nil, admin, "If-Match", utils.ETag(mug.Version)).expect(http.StatusOK)
+	h.request(http.MethodDelete, path, nil, admin).expect(http.StatusNotFound)
+	h.request(http.MethodDelete, "/products/not-a-uuid", nil, admin).expect(http.StatusBadRequest)
+
+	// Wishlist entries and stock alerts are deleted with the product
+	user := h.user()
+	game := h.product(admin, models.ProductInput{Name: "Game"})
+	h.request(http.MethodPost, "/wishlist", models.WishlistInput{ProductID: game.ID.String()}, user).expect(http.StatusOK)
+	h.request(http.MethodPost, "/products/"+game.ID.String()+"/stock-alerts", nil, user).expect(http.StatusOK)
+	h.request(http.MethodDelete, "/products/"+game.ID.String(), nil, admin).expect(http.StatusOK)
+	var items []models.WishlistItem
+	h.request(http.MethodGet, "/wishlist", nil, user).expect(http.StatusOK).data(&items)
+	if len(items) != 0 {
+		t.Errorf("wishlist has %d items after the product was deleted, want 0", len(items))
+	}
+}
+
+func TestImportExportProducts(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+
+	file := "sku,name,price,stock\nMUG-1,Mug,8,3\nLAMP-1,Lamp,30,1\nBAD-1,,,\n"
+	var report catalog.Report
+	h.request(http.MethodPost, "/products/import?dry_run=true", file, admin, "Content-Type", "text/csv").
+		expect(http.StatusOK).data(&report)
+	if !report.DryRun || report.Created != 2 || report.Failed != 1 {
+		t.Fatalf("dry run report = %+v", report)
+	}
+	var products []models.Product
+	h.request(http.MethodGet, "/products", nil, admin).expect(http.StatusOK).data(&products)
+	if len(products) != 0 {
+		t.Fatalf("dry run saved %d products", len(products))
+	}
+
+	h.request(http.MethodPost, "/products/import", file, admin, "Content-Type", "text/csv").
+		expect(http.StatusOK).data(&report)
+	if report.Created != 2 || report.Failed != 1 {
+		t.Fatalf("import report = %+v", report)
+	}
+	h.request(http.MethodPost, "/products/import", "sku,colour\nMUG-1,red\n", admin, "Content-Type", "text/csv").
+		expect(http.StatusBadRequest)
+	h.request(http.MethodPost, "/products/import", file, admin, "Content-Type", "application/pdf").
+		expect(http.StatusUnsupportedMediaType)
+
+	resp := h.request(http.MethodGet, "/products/export?format=ndjson", nil, admin).expect(http.StatusOK)
+	lines := strings.Split(strings.TrimSpace(resp.Body.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(resp.Body.String(), `"MUG-1"`) {
+		t.Fatalf("export = %q, want the 2 imported products", resp.Body.String())
+	}
+	resp = h.request(http.MethodGet, "/products/export?category=none", nil, admin).expect(http.StatusOK)
+	if got := strings.TrimSpace(resp.Body.String()); got != strings.Join(catalog.Columns, ",") {
+		t.Errorf("empty CSV export = %q, want only the header", got)
+	}
+	h.request(http.MethodGet, "/products/export?format=xml", nil, admin).expect(http.StatusBadRequest)
+
+	// Products without a SKU are left out and counted, so the export imports again
+	h.product(admin, models.ProductInput{Name: "Unlabelled"})
+	resp = h.request(http.MethodGet, "/products/export", nil, admin).expect(http.StatusOK)
+	if got := resp.Header().Get("Skipped-Products"); got != "1" {
+		t.Errorf("Skipped-Products = %q, want 1", got)
+	}
+	if strings.Contains(resp.Body.String(), "Unlabelled") {
+		t.Errorf("export = %q, want the product without a SKU left out", resp.Body.String())
+	}
+	h.request(http.MethodPost, "/products/import", resp.Body.String(), admin, "Content-Type", "text/csv").
+		expect(http.StatusOK).data(&report)
+	if report.Updated != 2 || report.Failed != 0 {
+		t.Errorf("re-import report = %+v, want 2 updated", report)
+	}
+}
@@ -0,0 +1,166 @@
+package integration_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/refunds"
+)
+
+// requestReturn asks to return quantity units of the order's first item.
+func (h *harness) requestReturn(token string, order models.Order, quantity int) *response {
+	h.t.Helper()
+	return h.request(http.MethodPost, "/orders/"+order.ID.String()+"/returns", models.CreateReturnInput{
+		Reason: "Damaged",
+		Items:  []models.ReturnItemInput{{OrderItemID: order.Items[0].ID.String(), Quantity: quantity}},
+	}, token)
+}
+
+func TestCreateReturn(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	ada := h.user()
+	bob := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Price: 8, Stock: 10})
+
+	pending := h.order(ada, 1, mug)
+	h.requestReturn(ada, pending, 1).expectMessage(http.StatusBadRequest, "Only delivered orders")
+
+	order := h.deliver(ada, admin, h.order(ada, 2, mug))
+	h.requestReturn(bob, order, 1).expect(http.StatusNotFound)
+	h.requestReturn(ada, order, 3).expectMessage(http.StatusBadRequest, "At most 2")
+	h.request(http.MethodPost, "/orders/"+order.ID.String()+"/returns", models.CreateReturnInput{
+		Reason: "Damaged",
+		Items:  []models.ReturnItemInput{{OrderItemID: uuid.NewString(), Quantity: 1}},
+	}, ada).expectMessage(http.StatusBadRequest, "Order item not found")
+	h.request(http.MethodPost, "/orders/"+order.ID.String()+"/returns", models.CreateReturnInput{Reason: "Damaged"}, ada).
+		expect(http.StatusBadRequest)
+
+	var requested models.ReturnRequest
+	h.requestReturn(ada, order, 1).expect(http.StatusOK).data(&requested)
+	if requested.Status != models.ReturnStatusRequested {
+		t.Fatalf("return status = %s, want Requested", requested.Status)
+	}
+	// Units already being returned cannot be returned again
+	h.requestReturn(ada, order, 2).expectMessage(http.StatusBadRequest, "At most 1")
+
+	var returns []models.ReturnRequest
+	h.request(http.MethodGet, "/returns", nil, ada).expect(http.StatusOK).data(&returns)
+	if len(returns) != 1 {
+		t.Errorf("ada sees %d returns, want 1", len(returns))
+	}
+	h.request(http.MethodGet, "/returns", nil, bob).expect(http.StatusOK).data(&returns)
+	if len(returns) != 0 {
+		t.Errorf("bob sees %d returns, want 0", len(returns))
+	}
+	h.request(http.MethodGet, "/returns?status=Requested", nil, admin).expect(http.StatusOK).data(&returns)
+	if len(returns) != 1 {
+		t.Errorf("admin sees %d requested returns, want 1", len(returns))
+	}
+}
+
+func TestReviewReturn(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Price: 8, Stock: 5})
+	order := h.deliver(user, admin, h.order(user, 2, mug))
+
+	var requested, approved models.ReturnRequest
+	h.requestReturn(user, order, 1).expect(http.StatusOK).data(&requested)
+	approve := "/returns/" + requested.ID.String() + "/approve"
+	h.request(http.MethodPut, approve, models.ReviewReturnInput{Note: "Sorry"}, admin).expect(http.StatusOK).data(&approved)
+	if approved.Status != models.ReturnStatusApproved || approved.RefundAmount != 8 {
+		t.Fatalf("approved return = %+v, want a refund of 8", approved)
+	}
+	h.request(http.MethodPut, approve, nil, admin).expectMessage(http.StatusBadRequest, "already been reviewed")
+
+	var product models.Product
+	h.request(http.MethodGet, "/products/"+mug.ID.String(), nil, admin).expect(http.StatusOK).data(&product)
+	if product.Stock != 4 {
+		t.Errorf("stock after return = %d, want 4", product.Stock)
+	}
+
+	var rejected models.ReturnRequest
+	h.requestReturn(user, order, 1).expect(http.StatusOK).data(&requested)
+	h.request(http.MethodPut, "/returns/"+requested.ID.String()+"/reject", nil, admin).expect(http.StatusOK).data(&rejected)
+	if rejected.Status != models.ReturnStatusRejected {
+		t.Errorf("rejected return status = %s", rejected.Status)
+	}
+
+	h.request(http.MethodPut, "/returns/"+uuid.NewString()+"/approve", nil, admin).expect(http.StatusNotFound)
+	h.request(http.MethodPut, "/returns/not-a-uuid/reject", nil, admin).expect(http.StatusBadRequest)
+}
+
+func TestReturnOrderWithShipping(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Price: 8, Stock: 5})
+
+	var method models.ShippingMethod
+	h.request(http.MethodPost, "/shipping-methods", models.ShippingMethodInput{Name: "Standard", Code: "STD", RateType: "flat", FlatRate: 5}, admin).
+		expect(http.StatusOK).data(&method)
+	var placed struct {
+		OrderID uuid.UUID `json:"order_id"`
+	}
+	h.request(http.MethodPost, "/orders", models.PlaceOrderInput{
+		Items:            []models.OrderItemInput{{ProductID: mug.ID.String(), Quantity: 2}},
+		ShippingAddress:  &address,
+		ShippingMethodID: method.ID.String(),
+	}, user).expect(http.StatusOK).data(&placed)
+	order := h.deliver(user, admin, h.getOrder(user, placed.OrderID))
+
+	// Returning every item refunds all but the shipping, and that is a full refund
+	var requested models.ReturnRequest
+	h.requestReturn(user, order, 2).expect(http.StatusOK).data(&requested)
+	h.request(http.MethodPut, "/returns/"+requested.ID.String()+"/approve", nil, admin).expect(http.StatusOK)
+	if got := h.getOrder(user, order.ID); got.Status != models.OrderStatusRefunded || got.RefundedAmount != 16 {
+		t.Errorf("order = %s with %v refunded, want Refunded with 16", got.Status, got.RefundedAmount)
+	}
+}
+
+func TestRefundRetriedAfterProviderOutage(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Price: 8, Stock: 10})
+	order := h.deliver(user, admin, h.order(user, 1, mug))
+
+	var requested models.ReturnRequest
+	h.requestReturn(user, order, 1).expect(http.StatusOK).data(&requested)
+	h.payments.FailRefunds(errors.New("provider unavailable"))
+	// The approval stands; the refund waits for the provider
+	h.request(http.MethodPut, "/returns/"+requested.ID.String()+"/approve", nil, admin).expect(http.StatusOK)
+	if got := h.getOrder(user, order.ID); got.Status != models.OrderStatusRefunded || got.RefundedAmount != 8 {
+		t.Fatalf("order = %s with %v refunded, want Refunded with 8", got.Status, got.RefundedAmount)
+	}
+
+	var refund models.Refund
+	if err := h.app.DB.First(&refund, "return_request_id = ?", requested.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if refund.Status != models.RefundStatusPending || refund.Attempts != 1 || refund.LastError == "" {
+		t.Fatalf("refund after the outage = %+v, want pending after 1 attempt", refund)
+	}
+
+	h.payments.FailRefunds(nil)
+	h.app.DB.Model(&refund).Update("next_attempt_at", time.Now().Add(-time.Second))
+	worker := &refunds.Worker{DB: h.app.DB, Provider: h.app.Payments}
+	if sent, err := worker.SendDue(context.Background()); err != nil || sent != 1 {
+		t.Fatalf("SendDue() = %d, %v, want 1 refund sent", sent, err)
+	}
+	h.app.DB.First(&refund, "id = ?", refund.ID)
+	if refund.Status != models.RefundStatusSucceeded || refund.ProviderRef == "" {
+		t.Errorf("refund after the retry = %+v, want succeeded", refund)
+	}
+	if sent, _ := worker.SendDue(context.Background()); sent != 0 {
+		t.Errorf("a succeeded refund was sent again")
+	}
+}
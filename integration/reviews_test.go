@@ -0,0 +1,78 @@
+package integration_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+func TestReviews(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	ada := h.user()
+	bob := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 10})
+	reviews := "/products/" + mug.ID.String() + "/reviews"
+	input := models.ReviewInput{Rating: 4, Title: "Holds coffee"}
+
+	h.request(http.MethodPost, reviews, input, ada).expectMessage(http.StatusForbidden, "received this product")
+	h.deliver(ada, admin, h.order(ada, 1, mug))
+
+	h.request(http.MethodPost, reviews, models.ReviewInput{Rating: 6, Title: "Too good"}, ada).expect(http.StatusBadRequest)
+	h.request(http.MethodPost, "/products/"+uuid.NewString()+"/reviews", input, ada).expect(http.StatusNotFound)
+	var review models.Review
+	h.request(http.MethodPost, reviews, input, ada).expect(http.StatusOK).data(&review)
+	if review.Status != models.ReviewStatusPending {
+		t.Fatalf("new review status = %s, want Pending", review.Status)
+	}
+	h.request(http.MethodPost, reviews, input, ada).expectMessage(http.StatusBadRequest, "already reviewed")
+
+	// Only approved reviews are public
+	var public []models.Review
+	h.request(http.MethodGet, reviews, nil, bob).expect(http.StatusOK).data(&public)
+	if len(public) != 0 {
+		t.Fatalf("public reviews before approval = %d, want 0", len(public))
+	}
+	path := "/reviews/" + review.ID.String()
+	h.request(http.MethodPut, path+"/approve", nil, admin).expect(http.StatusOK)
+	h.request(http.MethodPut, path+"/approve", nil, admin).expectMessage(http.StatusBadRequest, "already Approved")
+	h.request(http.MethodGet, reviews, nil, bob).expect(http.StatusOK).data(&public)
+	if len(public) != 1 {
+		t.Fatalf("public reviews after approval = %d, want 1", len(public))
+	}
+	var product models.Product
+	h.request(http.MethodGet, "/products/"+mug.ID.String(), nil, bob).expect(http.StatusOK).data(&product)
+	if product.RatingCount != 1 || product.RatingAverage != 4 {
+		t.Errorf("product rating = %v from %d reviews, want 4 from 1", product.RatingAverage, product.RatingCount)
+	}
+	h.request(http.MethodGet, reviews+"?limit=0", nil, bob).expect(http.StatusBadRequest)
+	h.request(http.MethodGet, reviews+"?offset=-1", nil, bob).expect(http.StatusBadRequest)
+
+	// Editing sends the review back to moderation
+	h.request(http.MethodPut, path, models.ReviewInput{Rating: 2, Title: "Chipped"}, bob).expect(http.StatusNotFound)
+	h.request(http.MethodPut, path, models.ReviewInput{Rating: 2, Title: "Chipped"}, ada).expect(http.StatusOK).data(&review)
+	if review.Status != models.ReviewStatusPending || review.Rating != 2 {
+		t.Fatalf("edited review = %+v", review)
+	}
+	h.request(http.MethodPut, path+"/reject", models.ModerateReviewInput{Note: "Off topic"}, admin).expect(http.StatusOK)
+
+	var own, all []models.Review
+	h.request(http.MethodGet, "/reviews", nil, ada).expect(http.StatusOK).data(&own)
+	h.request(http.MethodGet, "/reviews", nil, bob).expect(http.StatusOK).data(&all)
+	if len(own) != 1 || len(all) != 0 {
+		t.Errorf("ada sees %d reviews and bob %d, want 1 and 0", len(own), len(all))
+	}
+	h.request(http.MethodGet, "/reviews?status=Rejected&product_id="+mug.ID.String(), nil, admin).expect(http.StatusOK).data(&all)
+	if len(all) != 1 {
+		t.Errorf("admin sees %d rejected reviews, want 1", len(all))
+	}
+	h.request(http.MethodGet, "/reviews?product_id=mug", nil, admin).expect(http.StatusBadRequest)
+
+	h.request(http.MethodDelete, path, nil, bob).expect(http.StatusNotFound)
+	h.request(http.MethodDelete, path, nil, ada).expect(http.StatusOK)
+	h.request(http.MethodDelete, path, nil, ada).expect(http.StatusNotFound)
+	h.request(http.MethodPut, "/reviews/not-a-uuid/approve", nil, admin).expect(http.StatusBadRequest)
+}
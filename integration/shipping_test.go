@@ -0,0 +1,135 @@
+package integration_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+func TestShippingMethods(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+
+	var standard models.ShippingMethod
+	h.request(http.MethodPost, "/shipping-methods", models.ShippingMethodInput{
+		Name: "Standard", Code: "STD", RateType: "flat", FlatRate: 5, FreeOver: 50,
+	}, admin).expect(http.StatusOK).data(&standard)
+	inactive := false
+	h.request(http.MethodPost, "/shipping-methods", models.ShippingMethodInput{
+		Name: "Freight", Code: "FRT", RateType: "weight_based", BaseRate: 20, RatePerKg: 2, Active: &inactive,
+	}, admin).expect(http.StatusOK)
+
+	h.request(http.MethodPost, "/shipping-methods", models.ShippingMethodInput{Name: "Again", Code: "STD", RateType: "flat"}, admin).
+		expectMessage(http.StatusBadRequest, "already exists")
+	h.request(http.MethodPost, "/shipping-methods", models.ShippingMethodInput{Name: "Pigeon", Code: "PGN", RateType: "bird"}, admin).
+		expect(http.StatusBadRequest)
+
+	// Customers only see active methods
+	var methods []models.ShippingMethod
+	h.request(http.MethodGet, "/shipping-methods", nil, user).expect(http.StatusOK).data(&methods)
+	if len(methods) != 1 {
+		t.Errorf("customer sees %d shipping methods, want 1", len(methods))
+	}
+	h.request(http.MethodGet, "/shipping-methods", nil, admin).expect(http.StatusOK).data(&methods)
+	if len(methods) != 2 {
+		t.Errorf("admin sees %d shipping methods, want 2", len(methods))
+	}
+
+	path := "/shipping-methods/" + standard.ID.String()
+	h.request(http.MethodPut, path, models.ShippingMethodInput{Name: "Standard", Code: "STD", RateType: "flat", FlatRate: 6}, admin).
+		expect(http.StatusOK).data(&standard)
+	if standard.FlatRate != 6 {
+		t.Errorf("updated flat rate = %v, want 6", standard.FlatRate)
+	}
+	h.request(http.MethodPut, path, models.ShippingMethodInput{Name: "Standard", Code: "FRT", RateType: "flat"}, admin).
+		expectMessage(http.StatusBadRequest, "already exists")
+	h.request(http.MethodPut, "/shipping-methods/"+uuid.NewString(), models.ShippingMethodInput{Name: "X", Code: "X", RateType: "flat"}, admin).
+		expect(http.StatusNotFound)
+
+	h.request(http.MethodDelete, "/shipping-methods/not-a-uuid", nil, admin).expect(http.StatusBadRequest)
+	h.request(http.MethodDelete, "/shipping-methods/"+uuid.NewString(), nil, admin).expect(http.StatusNotFound)
+}
+
+func TestShippingQuoteAndOrder(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Price: 10, Stock: 10})
+
+	var method models.ShippingMethod
+	h.request(http.MethodPost, "/shipping-methods", models.ShippingMethodInput{
+		Name: "Standard", Code: "STD", RateType: "flat", FlatRate: 5, FreeOver: 50,
+	}, admin).expect(http.StatusOK).data(&method)
+
+	var quotes []struct {
+		Cost float64 `json:"cost"`
+	}
+	quote := func(quantity int) {
+		t.Helper()
+		h.request(http.MethodPost, "/shipping-methods/quote", models.ShippingQuoteInput{
+			Items: []models.OrderItemInput{{ProductID: mug.ID.String(), Quantity: quantity}},
+		}, user).expect(http.StatusOK).data(&quotes)
+	}
+	quote(1)
+	if len(quotes) != 1 || quotes[0].Cost != 5 {
+		t.Errorf("quotes for 1 mug = %+v, want one costing 5", quotes)
+	}
+	quote(5)
+	if len(quotes) != 1 || quotes[0].Cost != 0 {
+		t.Errorf("quotes for 5 mugs = %+v, want free shipping", quotes)
+	}
+	h.request(http.MethodPost, "/shipping-methods/quote", models.ShippingQuoteInput{
+		Items: []models.OrderItemInput{{ProductID: uuid.NewString(), Quantity: 1}},
+	}, user).expectMessage(http.StatusBadRequest, "Product not found")
+	h.request(http.MethodPost, "/shipping-methods/quote", models.ShippingQuoteInput{}, user).expect(http.StatusBadRequest)
+
+	// A coupon taking the order under free_over is charged shipping, in
+	// the quote and on the order alike
+	h.createCoupon(admin, models.CouponInput{Code: "SAVE10", Type: "percentage", Value: 10})
+	five := []models.OrderItemInput{{ProductID: mug.ID.String(), Quantity: 5}}
+	h.request(http.MethodPost, "/shipping-methods/quote", models.ShippingQuoteInput{Items: five, CouponCode: "SAVE10"}, user).
+		expect(http.StatusOK).data(&quotes)
+	if len(quotes) != 1 || quotes[0].Cost != 5 {
+		t.Errorf("quotes for 5 mugs with a coupon = %+v, want one costing 5", quotes)
+	}
+	h.request(http.MethodPost, "/shipping-methods/quote", models.ShippingQuoteInput{Items: five, CouponCode: "NOPE"}, user).
+		expectMessage(http.StatusBadRequest, "Invalid coupon code")
+
+	input := models.PlaceOrderInput{
+		Items:            []models.OrderItemInput{{ProductID: mug.ID.String(), Quantity: 1}},
+		ShippingAddress:  &address,
+		ShippingMethodID: method.ID.String(),
+	}
+	var placed struct {
+		OrderID uuid.UUID `json:"order_id"`
+	}
+	h.request(http.MethodPost, "/orders", input, user).expect(http.StatusOK).data(&placed)
+	if order := h.getOrder(user, placed.OrderID); order.ShippingCost != 5 || order.Total != 15 {
+		t.Errorf("order shipping %v, total %v; want 5 and 15", order.ShippingCost, order.Total)
+	}
+	discounted := models.PlaceOrderInput{Items: five, ShippingAddress: &address, ShippingMethodID: method.ID.String(), CouponCode: "SAVE10"}
+	h.request(http.MethodPost, "/orders", discounted, user).expect(http.StatusOK).data(&placed)
+	if order := h.getOrder(user, placed.OrderID); order.ShippingCost != 5 || order.Total != 50 {
+		t.Errorf("discounted order shipping %v, total %v; want 5 and 50", order.ShippingCost, order.Total)
+	}
+	input.ShippingMethodID = uuid.NewString()
+	h.request(http.MethodPost, "/orders", input, user).expectMessage(http.StatusBadRequest, "Shipping method not found")
+
+	// Every order needs an address to be taxed, with or without a shipping method
+	input.ShippingMethodID = ""
+	h.request(http.MethodPost, "/orders", input, user).expect(http.StatusOK).data(&placed)
+	if order := h.getOrder(user, placed.OrderID); order.ShippingCost != 0 || order.Total != 10 {
+		t.Errorf("unshipped order shipping %v, total %v; want 0 and 10", order.ShippingCost, order.Total)
+	}
+	input.ShippingAddress = nil
+	if body := h.request(http.MethodPost, "/orders", input, user).expect(http.StatusBadRequest).Body.String(); !strings.Contains(body, "ShippingAddress") {
+		t.Errorf("order without an address = %s, want a ShippingAddress validation error", body)
+	}
+
+	h.request(http.MethodDelete, "/shipping-methods/"+method.ID.String(), nil, admin).expect(http.StatusOK)
+}
@@ -0,0 +1,143 @@
+package integration_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+const (
+	parentTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentSpanID  = "00f067aa0ba902b7"
+	traceparent   = "00-" + parentTraceID + "-" + parentSpanID + "-01"
+)
+
+// record swaps the app's tracer for one that keeps every span in memory.
+func (h *harness) record() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	h.app.Tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	h.router = h.app.Router()
+	return recorder
+}
+
+// spanNamed returns the ended span called name, failing the test if
+// there is none.
+func spanNamed(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	var names []string
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+		names = append(names, span.Name())
+	}
+	t.Fatalf("no span %q among %q", name, names)
+	return nil
+}
+
+// attribute returns the string form of a span attribute, or "".
+func attribute(span sdktrace.ReadOnlySpan, key string) string {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestTracingContinuesCallerTrace(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 10})
+	recorder := h.record()
+
+	h.request(http.MethodGet, "/products/"+mug.ID.String(), nil, user, "traceparent", traceparent).
+		expect(http.StatusOK)
+
+	server := spanNamed(t, recorder, "GET /api/v1/products/:id")
+	if server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("span kind = %v, want server", server.SpanKind())
+	}
+	if got := server.SpanContext().TraceID().String(); got != parentTraceID {
+		t.Errorf("trace ID = %s, want the caller's %s", got, parentTraceID)
+	}
+	if got := server.Parent().SpanID().String(); got != parentSpanID {
+		t.Errorf("parent span = %s, want the caller's %s", got, parentSpanID)
+	}
+	if got := attribute(server, "http.route"); got != "/api/v1/products/:id" {
+		t.Errorf("http.route = %q", got)
+	}
+	if got := attribute(server, "http.response.status_code"); got != "200" {
+		t.Errorf("http.response.status_code = %q, want 200", got)
+	}
+
+	query := spanNamed(t, recorder, "SELECT products")
+	if query.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("the query span is not a child of the request span")
+	}
+	if got := attribute(query, "db.system.name"); got != "sqlite" {
+		t.Errorf("db.system.name = %q, want sqlite", got)
+	}
+	if text := attribute(query, "db.query.text"); text == "" || strings.Contains(text, mug.ID.String()) {
+		t.Errorf("db.query.text = %q, want the query without its values", text)
+	}
+}
+
+func TestTracingStartsTrace(t *testing.T) {
+	h := newHarness(t)
+	recorder := h.record()
+
+	h.probe("/healthz").expect(http.StatusOK)
+	h.probe("/no-such-page").expect(http.StatusNotFound)
+
+	server := spanNamed(t, recorder, "GET /healthz")
+	if !server.SpanContext().IsValid() || server.Parent().IsValid() {
+		t.Error("a request without traceparent should start a new trace")
+	}
+	// Unmatched paths are named by method alone
+	spanNamed(t, recorder, "GET")
+}
+
+func TestTracingLogsTraceID(t *testing.T) {
+	h := newHarness(t)
+	email := h.uniqueEmail("customer")
+	h.register(email, "")
+	var logs bytes.Buffer
+	h.app.Log = slog.New(slog.NewJSONHandler(&logs, nil))
+	recorder := h.record()
+
+	h.request(http.MethodPost, "/users/login", models.LoginInput{Email: email, Password: "wrong password"}, "",
+		"traceparent", traceparent).expect(http.StatusUnauthorized)
+
+	if !strings.Contains(logs.String(), `"trace_id":"`+parentTraceID+`"`) {
+		t.Errorf("log output %q lacks the trace ID", logs.String())
+	}
+	for _, span := range recorder.Ended() {
+		if text := attribute(span, "db.query.text"); strings.Contains(text, email) {
+			t.Errorf("span %q recorded the email address: %s", span.Name(), text)
+		}
+	}
+}
+
+func TestTracingUntracedQueries(t *testing.T) {
+	h := newHarness(t)
+	recorder := h.record()
+
+	// Workers query outside any request; they must not start traces
+	var count int64
+	if err := h.app.DB.Model(&models.Product{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if spans := recorder.Ended(); len(spans) != 0 {
+		t.Errorf("recorded %d spans for an untraced query", len(spans))
+	}
+}
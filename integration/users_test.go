@@ -0,0 +1,130 @@
+package integration_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/config"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/utils"
+)
+
+// fixedClock is a clock stopped at a given time.
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func TestWelcome(t *testing.T) {
+	h := newHarness(t)
+	h.probe("/").expectMessage(http.StatusOK, "Welcome")
+}
+
+func TestRegister(t *testing.T) {
+	h := newHarness(t)
+
+	id := h.register("ada@example.com", "")
+	if id == uuid.Nil {
+		t.Fatal("register returned no user ID")
+	}
+
+	h.request(http.MethodPost, "/users/register", models.UserInput{Email: "ada@example.com", Password: password}, "").
+		expectMessage(http.StatusBadRequest, "already exists")
+	h.request(http.MethodPost, "/users/register", models.UserInput{Email: "not-an-email", Password: password}, "").
+		expect(http.StatusBadRequest)
+	h.request(http.MethodPost, "/users/register", models.UserInput{Email: "bob@example.com"}, "").
+		expect(http.StatusBadRequest)
+	h.request(http.MethodPost, "/users/register", "{", "", "Content-Type", "application/json").
+		expect(http.StatusBadRequest)
+}
+
+func TestLogin(t *testing.T) {
+	h := newHarness(t)
+	h.register("ada@example.com", "")
+
+	if token := h.login("ada@example.com"); token == "" {
+		t.Fatal("login returned no token")
+	}
+
+	h.request(http.MethodPost, "/users/login", models.LoginInput{Email: "ada@example.com", Password: "wrong"}, "").
+		expectMessage(http.StatusUnauthorized, "Invalid email or password")
+	h.request(http.MethodPost, "/users/login", models.LoginInput{Email: "nobody@example.com", Password: password}, "").
+		expectMessage(http.StatusUnauthorized, "Invalid email or password")
+	h.request(http.MethodPost, "/users/login", models.LoginInput{Email: "ada@example.com"}, "").
+		expect(http.StatusBadRequest)
+}
+
+func TestAuthFailures(t *testing.T) {
+	h := newHarness(t)
+	user := h.user()
+
+	expired, err := utils.NewJWT(h.app.Config.JWT, fixedClock(time.Now().Add(-48*time.Hour))).Issue(uuid.New(), "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := utils.NewJWT(config.JWTConfig{Secret: "some-other-secret", TTL: time.Hour}, utils.SystemClock{}).Issue(uuid.New(), "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+		want   string
+	}{
+		{"Missing", "", http.StatusUnauthorized, "Missing Authorization header"},
+		{"EmptyBearer", "Bearer ", http.StatusUnauthorized, "Invalid Authorization header format"},
+		{"Garbage", "Bearer not-a-token", http.StatusUnauthorized, "Invalid token"},
+		{"Expired", "Bearer " + expired, http.StatusUnauthorized, "Invalid token"},
+		{"WrongSecret", "Bearer " + forged, http.StatusUnauthorized, "Invalid token"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var headers []string
+			if test.header != "" {
+				headers = []string{"Authorization", test.header}
+			}
+			h.request(http.MethodGet, "/orders", nil, "", headers...).expectMessage(test.status, test.want)
+		})
+	}
+
+	// Customers cannot use admin routes
+	adminOnly := []struct{ method, path string }{
+		{http.MethodPost, "/products"},
+		{http.MethodGet, "/products/export"},
+		{http.MethodPut, "/orders/" + uuid.NewString() + "/status"},
+		{http.MethodGet, "/inventory/low-stock"},
+		{http.MethodGet, "/warehouses"},
+		{http.MethodGet, "/coupons"},
+		{http.MethodPost, "/shipping-methods"},
+		{http.MethodPut, "/reviews/" + uuid.NewString() + "/approve"},
+		{http.MethodGet, "/webhooks"},
+		{http.MethodPut, "/returns/" + uuid.NewString() + "/approve"},
+		{http.MethodPost, "/payments/" + uuid.NewString() + "/capture"},
+	}
+	for _, route := range adminOnly {
+		h.request(route.method, route.path, nil, user).expect(http.StatusForbidden)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	h := newHarness(t, func(cfg *config.Config) {
+		cfg.RateLimit = config.RateLimitConfig{Enabled: true, RequestsPerSecond: 0.001, Burst: 3}
+	})
+
+	for i := 0; i < 3; i++ {
+		h.request(http.MethodPost, "/users/login", models.LoginInput{Email: "nobody@example.com", Password: password}, "").
+			expect(http.StatusUnauthorized)
+	}
+	h.request(http.MethodPost, "/users/login", models.LoginInput{Email: "nobody@example.com", Password: password}, "").
+		expect(http.StatusTooManyRequests)
+	// The limit is per client, not per route
+	h.request(http.MethodGet, "/products", nil, "").expect(http.StatusTooManyRequests)
+	h.request(http.MethodPost, "/users/login", models.LoginInput{Email: "nobody@example.com", Password: password}, "",
+		"X-Forwarded-For", "203.0.113.7").expect(http.StatusUnauthorized)
+}
@@ -0,0 +1,161 @@
+package integration_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/config"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/outbox"
+	"github.com/TobiAdeniji94/ecommerce_api/webhooks"
+)
+
+// deliverWebhooks relays recorded events and sends the deliveries they
+// queue, as the background workers would.
+func (h *harness) deliverWebhooks() {
+	h.t.Helper()
+	relay := &outbox.Relay{DB: h.app.DB, Bus: h.app.Bus, Sinks: h.app.Sinks}
+	if _, err := relay.RelayDue(context.Background()); err != nil {
+		h.t.Fatalf("relaying events: %v", err)
+	}
+	worker := &webhooks.Worker{DB: h.app.DB}
+	if _, err := worker.DeliverDue(context.Background()); err != nil {
+		h.t.Fatalf("delivering webhooks: %v", err)
+	}
+}
+
+func TestWebhookAdmin(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+
+	var subscription models.WebhookSubscription
+	h.request(http.MethodPost, "/webhooks", models.WebhookInput{
+		URL: "https://example.com/hooks", EventTypes: []string{"order.created"},
+	}, admin).expect(http.StatusOK).data(&subscription)
+	if subscription.Secret == "" || !subscription.Active {
+		t.Fatalf("new subscription = %+v, want an active one with a secret", subscription)
+	}
+	h.request(http.MethodPost, "/webhooks", models.WebhookInput{URL: "not a url", EventTypes: []string{"order.created"}}, admin).
+		expect(http.StatusBadRequest)
+	h.request(http.MethodPost, "/webhooks", models.WebhookInput{URL: "https://example.com", EventTypes: []string{"order.eaten"}}, admin).
+		expect(http.StatusBadRequest)
+	h.request(http.MethodPost, "/webhooks", models.WebhookInput{URL: "https://example.com", EventTypes: []string{"stock.low"}, Secret: "short"}, admin).
+		expect(http.StatusBadRequest)
+
+	// Secrets are only shown on creation
+	var subscriptions []models.WebhookSubscription
+	h.request(http.MethodGet, "/webhooks", nil, admin).expect(http.StatusOK).data(&subscriptions)
+	if len(subscriptions) != 1 || subscriptions[0].Secret != "" {
+		t.Errorf("listed subscriptions = %+v, want one without its secret", subscriptions)
+	}
+
+	path := "/webhooks/" + subscription.ID.String()
+	inactive := false
+	h.request(http.MethodPut, path, models.WebhookInput{
+		URL: "https://example.com/v2", EventTypes: []string{"order.created", "stock.low"}, Active: &inactive,
+	}, admin).expect(http.StatusOK).data(&subscription)
+	if subscription.URL != "https://example.com/v2" || subscription.Active || len(subscription.EventTypes) != 2 {
+		t.Errorf("updated subscription = %+v", subscription)
+	}
+	h.request(http.MethodPut, "/webhooks/"+uuid.NewString(), models.WebhookInput{URL: "https://example.com", EventTypes: []string{"stock.low"}}, admin).
+		expectMessage(http.StatusNotFound, "Webhook not found")
+
+	h.request(http.MethodGet, path+"/deliveries?limit=0", nil, admin).expect(http.StatusBadRequest)
+	h.request(http.MethodGet, path+"/deliveries?offset=-1", nil, admin).expect(http.StatusBadRequest)
+	h.request(http.MethodGet, "/webhook-deliveries/"+uuid.NewString(), nil, admin).expectMessage(http.StatusNotFound, "Delivery not found")
+	h.request(http.MethodPost, "/webhook-deliveries/"+uuid.NewString()+"/replay", nil, admin).expect(http.StatusNotFound)
+	h.request(http.MethodGet, "/webhook-deliveries/not-a-uuid", nil, admin).expect(http.StatusBadRequest)
+
+	h.request(http.MethodDelete, path, nil, admin).expect(http.StatusOK)
+	h.request(http.MethodDelete, path, nil, admin).expect(http.StatusNotFound)
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	user := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 10})
+
+	var (
+		mu       sync.Mutex
+		received []*http.Request
+		status   = http.StatusNoContent
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r)
+		w.WriteHeader(status)
+	}))
+	defer receiver.Close()
+
+	var subscription models.WebhookSubscription
+	h.request(http.MethodPost, "/webhooks", models.WebhookInput{
+		URL: receiver.URL, EventTypes: []string{"order.created"},
+	}, admin).expect(http.StatusOK).data(&subscription)
+
+	// A subscription created inactive receives nothing
+	inactive := false
+	var paused models.WebhookSubscription
+	h.request(http.MethodPost, "/webhooks", models.WebhookInput{
+		URL: receiver.URL, EventTypes: []string{"order.created"}, Active: &inactive,
+	}, admin).expect(http.StatusOK).data(&paused)
+	if paused.Active {
+		t.Error("subscription created inactive is active")
+	}
+
+	h.order(user, 1, mug)
+	h.deliverWebhooks()
+	if len(received) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(received))
+	}
+	if got := received[0].Header.Get(webhooks.EventHeader); got != "order.created" {
+		t.Errorf("event header = %q, want order.created", got)
+	}
+	if received[0].Header.Get(webhooks.SignatureHeader) == "" {
+		t.Error("delivery is not signed")
+	}
+
+	var deliveries []models.WebhookDelivery
+	h.request(http.MethodGet, "/webhooks/"+subscription.ID.String()+"/deliveries", nil, admin).expect(http.StatusOK).data(&deliveries)
+	if len(deliveries) != 1 || deliveries[0].Status != models.WebhookDeliverySucceeded {
+		t.Fatalf("deliveries = %+v, want one that succeeded", deliveries)
+	}
+	path := "/webhook-deliveries/" + deliveries[0].ID.String()
+
+	var delivery models.WebhookDelivery
+	h.request(http.MethodGet, path, nil, admin).expect(http.StatusOK).data(&delivery)
+	if len(delivery.AttemptLog) != 1 || delivery.AttemptLog[0].StatusCode != http.StatusNoContent {
+		t.Errorf("attempt log = %+v, want one 204", delivery.AttemptLog)
+	}
+
+	// A replay that the receiver rejects is queued for a retry
+	status = http.StatusInternalServerError
+	h.request(http.MethodPost, path+"/replay", nil, admin).expect(http.StatusOK).data(&delivery)
+	if delivery.Status != models.WebhookDeliveryPending {
+		t.Fatalf("replayed delivery status = %s, want pending", delivery.Status)
+	}
+	h.deliverWebhooks()
+	h.request(http.MethodGet, path, nil, admin).expect(http.StatusOK).data(&delivery)
+	if len(received) != 2 || delivery.Status != models.WebhookDeliveryPending || delivery.LastStatusCode != http.StatusInternalServerError {
+		t.Errorf("after a failed replay: %d requests, delivery %+v", len(received), delivery)
+	}
+	h.request(http.MethodGet, "/webhooks/"+subscription.ID.String()+"/deliveries?status=succeeded", nil, admin).
+		expect(http.StatusOK).data(&deliveries)
+	if len(deliveries) != 0 {
+		t.Errorf("found %d succeeded deliveries after the failed replay, want 0", len(deliveries))
+	}
+}
+
+func TestWebhooksDisabled(t *testing.T) {
+	h := newHarness(t, func(cfg *config.Config) { cfg.Features.Webhooks = false })
+	admin := h.admin()
+
+	h.request(http.MethodGet, "/webhooks", nil, admin).expect(http.StatusNotFound)
+	h.request(http.MethodGet, "/webhook-deliveries/"+uuid.NewString(), nil, admin).expect(http.StatusNotFound)
+}
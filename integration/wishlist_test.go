@@ -0,0 +1,90 @@
+package integration_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+func TestWishlist(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	ada := h.user()
+	bob := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 10})
+
+	h.request(http.MethodPost, "/wishlist", models.WishlistInput{ProductID: mug.ID.String()}, ada).expect(http.StatusOK)
+	// Adding twice keeps one entry
+	h.request(http.MethodPost, "/wishlist", models.WishlistInput{ProductID: mug.ID.String()}, ada).expect(http.StatusOK)
+	h.request(http.MethodPost, "/wishlist", models.WishlistInput{ProductID: uuid.NewString()}, ada).expect(http.StatusNotFound)
+	h.request(http.MethodPost, "/wishlist", models.WishlistInput{ProductID: "mug"}, ada).expect(http.StatusBadRequest)
+
+	var items []models.WishlistItem
+	h.request(http.MethodGet, "/wishlist", nil, ada).expect(http.StatusOK).data(&items)
+	if len(items) != 1 || items[0].Product.Name != "Mug" {
+		t.Fatalf("ada's wishlist = %+v, want the mug", items)
+	}
+	h.request(http.MethodGet, "/wishlist", nil, bob).expect(http.StatusOK).data(&items)
+	if len(items) != 0 {
+		t.Errorf("bob's wishlist has %d items, want 0", len(items))
+	}
+
+	h.request(http.MethodDelete, "/wishlist/"+mug.ID.String(), nil, bob).expect(http.StatusNotFound)
+	h.request(http.MethodDelete, "/wishlist/"+mug.ID.String(), nil, ada).expect(http.StatusOK)
+	h.request(http.MethodDelete, "/wishlist/"+mug.ID.String(), nil, ada).expectMessage(http.StatusNotFound, "not on the wishlist")
+	h.request(http.MethodDelete, "/wishlist/mug", nil, ada).expect(http.StatusBadRequest)
+}
+
+func TestStockAlertsAndNotifications(t *testing.T) {
+	h := newHarness(t)
+	admin := h.admin()
+	ada := h.user()
+	bob := h.user()
+	mug := h.product(admin, models.ProductInput{Name: "Mug", Stock: 1})
+	alerts := "/products/" + mug.ID.String() + "/stock-alerts"
+
+	h.request(http.MethodPost, alerts, nil, ada).expectMessage(http.StatusBadRequest, "Product is in stock")
+	h.request(http.MethodPost, "/inventory/adjustments", models.StockAdjustmentInput{
+		ProductID: mug.ID.String(), Quantity: -1, Reason: "Sold out",
+	}, admin).expect(http.StatusOK)
+
+	h.request(http.MethodPost, alerts, nil, ada).expect(http.StatusOK)
+	h.request(http.MethodPost, alerts, nil, bob).expect(http.StatusOK)
+	h.request(http.MethodPost, "/products/"+uuid.NewString()+"/stock-alerts", nil, ada).expect(http.StatusNotFound)
+	h.request(http.MethodPost, "/products/mug/stock-alerts", nil, ada).expect(http.StatusBadRequest)
+
+	h.request(http.MethodDelete, alerts, nil, bob).expect(http.StatusOK)
+	h.request(http.MethodDelete, alerts, nil, bob).expectMessage(http.StatusNotFound, "No open stock alert")
+
+	var open []models.StockAlert
+	h.request(http.MethodGet, "/stock-alerts", nil, ada).expect(http.StatusOK).data(&open)
+	if len(open) != 1 {
+		t.Fatalf("ada has %d stock alerts, want 1", len(open))
+	}
+
+	// Restocking notifies the subscribers left
+	h.request(http.MethodPost, "/inventory/adjustments", models.StockAdjustmentInput{
+		ProductID: mug.ID.String(), Quantity: 4, Type: "receipt", Reason: "Delivery",
+	}, admin).expect(http.StatusOK)
+
+	var notifications []models.Notification
+	h.request(http.MethodGet, "/notifications", nil, ada).expect(http.StatusOK).data(&notifications)
+	if len(notifications) != 1 || notifications[0].Type != models.NotificationBackInStock {
+		t.Fatalf("ada's notifications = %+v, want one back-in-stock", notifications)
+	}
+	h.request(http.MethodGet, "/notifications", nil, bob).expect(http.StatusOK).data(&notifications)
+	if len(notifications) != 0 {
+		t.Errorf("bob has %d notifications, want 0", len(notifications))
+	}
+	h.request(http.MethodGet, "/notifications?status=pending", nil, admin).expect(http.StatusOK).data(&notifications)
+	if len(notifications) != 1 {
+		t.Errorf("admin sees %d pending notifications, want 1", len(notifications))
+	}
+	h.request(http.MethodGet, "/notifications?status=sent", nil, admin).expect(http.StatusOK).data(&notifications)
+	if len(notifications) != 0 {
+		t.Errorf("admin sees %d sent notifications, want 0", len(notifications))
+	}
+}
@@ -0,0 +1,41 @@
+package inventory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/notifications"
+)
+
+// notifyBackInStock queues a notification for every open stock alert on
+// the product and closes the alerts, so each alert fires once. Adjust calls
+// it in the transaction that restocks the product, whose row it has locked.
+func notifyBackInStock(tx *gorm.DB, productID uuid.UUID) error {
+	var alerts []models.StockAlert
+	if err := tx.Where("product_id = ? AND notified_at IS NULL", productID).Find(&alerts).Error; err != nil {
+		return err
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	var product models.Product
+	if err := tx.Select("id", "name").First(&product, "id = ?", productID).Error; err != nil {
+		return err
+	}
+
+	subject := product.Name + " is back in stock"
+	body := fmt.Sprintf("Good news: %s is available again. Order soon, before it sells out.\n\nProduct ID: %s\n", product.Name, product.ID)
+	ids := make([]uuid.UUID, 0, len(alerts))
+	for _, alert := range alerts {
+		if err := notifications.Enqueue(tx, alert.UserID, models.NotificationBackInStock, subject, body); err != nil {
+			return err
+		}
+		ids = append(ids, alert.ID)
+	}
+	return tx.Model(&models.StockAlert{}).Where("id IN ?", ids).Update("notified_at", time.Now()).Error
+}
@@ -0,0 +1,136 @@
+package inventory
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// ErrBackorderLimit is returned when a backorder would exceed the product's
+// maximum backorder quantity.
+var ErrBackorderLimit = errors.New("backorder limit reached")
+
+// Available locks the product's stock levels in active warehouses for the
+// rest of the transaction and returns how many units they hold.
+func Available(tx *gorm.DB, productID uuid.UUID) (int, error) {
+	var levels []models.StockLevel
+	if err := activeLevels(tx, productID).Find(&levels).Error; err != nil {
+		return 0, err
+	}
+
+	available := 0
+	for _, level := range levels {
+		available += level.Quantity
+	}
+	return available, nil
+}
+
+// Backorder counts quantity more units of the product as backordered. The
+// update is conditional, so concurrent orders cannot exceed MaxBackorder.
+func Backorder(tx *gorm.DB, productID uuid.UUID, quantity int) error {
+	result := tx.Model(&models.Product{}).
+		Where("id = ? AND (max_backorder = 0 OR backordered + ? <= max_backorder)", productID, quantity).
+		UpdateColumns(map[string]interface{}{
+			"backordered": gorm.Expr("backordered + ?", quantity),
+			"version":     gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBackorderLimit
+	}
+	return nil
+}
+
+// ReleaseBackorder drops the units of an order item that are still waiting
+// for stock, for example when its order is canceled.
+func ReleaseBackorder(tx *gorm.DB, item *models.OrderItem) error {
+	if item.BackorderedQuantity == 0 {
+		return nil
+	}
+
+	err := tx.Model(&models.Product{}).Where("id = ?", item.ProductID).
+		UpdateColumns(map[string]interface{}{
+			"backordered": gorm.Expr("backordered - ?", item.BackorderedQuantity),
+			"version":     gorm.Expr("version + 1"),
+		}).Error
+	if err != nil {
+		return err
+	}
+	if err := tx.Model(item).UpdateColumn("backordered_quantity", 0).Error; err != nil {
+		return err
+	}
+	item.BackorderedQuantity = 0
+	return nil
+}
+
+// FulfilBackorders allocates available stock of the product to backordered
+// order items, oldest order first, until stock or backorders run out.
+func (s *Service) FulfilBackorders(tx *gorm.DB, productID uuid.UUID, actorID *uuid.UUID) error {
+	var items []models.OrderItem
+	err := tx.Model(&models.OrderItem{}).Select("order_items.*").
+		Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "order_items"}}).
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("order_items.product_id = ? AND order_items.backordered_quantity > 0", productID).
+		Where("orders.status NOT IN ?", []string{models.OrderStatusCanceled, models.OrderStatusRefunded}).
+		Order("orders.created_at, order_items.id").
+		Find(&items).Error
+	if err != nil || len(items) == 0 {
+		return err
+	}
+
+	available, err := Available(tx, productID)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if available == 0 {
+			return nil
+		}
+		take := min(item.BackorderedQuantity, available)
+
+		var order models.Order
+		if err := tx.Select("id", "shipping_line1", "shipping_line2", "shipping_city", "shipping_region", "shipping_postal_code", "shipping_country").
+			First(&order, "id = ?", item.OrderID).Error; err != nil {
+			return err
+		}
+
+		allocations, err := s.Allocate(tx, Adjustment{
+			ProductID: productID,
+			Type:      models.StockMovementSale,
+			Reason:    "Backorder fulfilled",
+			ActorID:   actorID,
+			OrderID:   &order.ID,
+		}, take, order.ShippingAddress)
+		if err != nil {
+			return err
+		}
+		for i := range allocations {
+			allocations[i].OrderItemID = item.ID
+		}
+		if err := tx.Create(&allocations).Error; err != nil {
+			return err
+		}
+
+		err = tx.Model(&item).UpdateColumn("backordered_quantity", gorm.Expr("backordered_quantity - ?", take)).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&models.Product{}).Where("id = ?", productID).
+			UpdateColumns(map[string]interface{}{
+				"backordered": gorm.Expr("backordered - ?", take),
+				"version":     gorm.Expr("version + 1"),
+			}).Error
+		if err != nil {
+			return err
+		}
+		available -= take
+	}
+	return nil
+}
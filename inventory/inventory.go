@@ -0,0 +1,231 @@
+// Package inventory keeps product stock and its movement ledger in step.
+// Every stock change goes through Adjust, which updates the product and
+// records the movement in the same transaction, and queues back-in-stock
+// notifications when a product is restocked from zero.
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/TobiAdeniji94/ecommerce_api/config"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/outbox"
+)
+
+var (
+	// ErrInsufficientStock is returned when a movement would take stock below zero.
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrProductNotFound is returned when the product does not exist.
+	ErrProductNotFound = errors.New("product not found")
+	// ErrWarehouseNotFound is returned when the warehouse does not exist.
+	ErrWarehouseNotFound = errors.New("warehouse not found")
+)
+
+// Adjustment describes a stock change in one warehouse. Quantity is
+// signed: positive values add stock and negative values remove it.
+type Adjustment struct {
+	ProductID       uuid.UUID
+	WarehouseID     uuid.UUID
+	Quantity        int
+	Type            string
+	Reason          string
+	ActorID         *uuid.UUID
+	OrderID         *uuid.UUID
+	ReturnRequestID *uuid.UUID
+}
+
+// Service applies stock changes with the configured low-stock threshold
+// and allocation strategy.
+type Service struct {
+	// LowStockThreshold is the stock level at or below which products
+	// without their own threshold are reported as low.
+	LowStockThreshold int
+	// Strategy is StrategyPriority or StrategyNearest.
+	Strategy string
+}
+
+// New returns a Service configured by cfg.
+func New(cfg config.InventoryConfig) (*Service, error) {
+	if cfg.LowStockThreshold < 0 {
+		return nil, fmt.Errorf("invalid low-stock threshold %d", cfg.LowStockThreshold)
+	}
+	if cfg.AllocationStrategy != StrategyPriority && cfg.AllocationStrategy != StrategyNearest {
+		return nil, fmt.Errorf("invalid allocation strategy %q", cfg.AllocationStrategy)
+	}
+	return &Service{LowStockThreshold: cfg.LowStockThreshold, Strategy: cfg.AllocationStrategy}, nil
+}
+
+// Setup creates a default warehouse on first start and moves stock that
+// has no location into it.
+func Setup(db *gorm.DB) error {
+	return db.Transaction(ensureWarehouse)
+}
+
+// Adjust applies a stock change to one warehouse and records it in the
+// ledger, keeping the product's total stock in step. The updates are
+// conditional, so concurrent movements can never take stock below zero.
+// It must run inside a transaction.
+func (s *Service) Adjust(tx *gorm.DB, adj Adjustment) (*models.StockMovement, error) {
+	if adj.WarehouseID == uuid.Nil {
+		return nil, ErrWarehouseNotFound
+	}
+
+	result := tx.Model(&models.Product{}).
+		Where("id = ? AND stock + ? >= 0", adj.ProductID, adj.Quantity).
+		UpdateColumns(map[string]interface{}{
+			"stock":   gorm.Expr("stock + ?", adj.Quantity),
+			"version": gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := tx.Model(&models.Product{}).Where("id = ?", adj.ProductID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, ErrProductNotFound
+		}
+		return nil, ErrInsufficientStock
+	}
+
+	if err := adjustLevel(tx, adj); err != nil {
+		return nil, err
+	}
+
+	// The updated row stays locked until commit, so this is the balance after the change
+	var product models.Product
+	if err := tx.Select("id", "sku", "name", "stock", "backordered", "low_stock_threshold").
+		First(&product, "id = ?", adj.ProductID).Error; err != nil {
+		return nil, err
+	}
+
+	// Stock falling to or below the low-stock threshold
+	if threshold := product.LowStockThreshold; adj.Quantity < 0 && adj.Type != models.StockMovementTransfer {
+		if threshold == 0 {
+			threshold = s.LowStockThreshold
+		}
+		if product.Stock <= threshold && product.Stock-adj.Quantity > threshold {
+			err := outbox.Record(tx, outbox.AggregateProduct, product.ID, outbox.EventStockLow, outbox.StockData{
+				ProductID: product.ID,
+				SKU:       product.SKU,
+				Name:      product.Name,
+				Stock:     product.Stock,
+				Threshold: threshold,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Stock coming back from zero is back in stock, unless backorders will
+	// take all of it; transfers only pass through zero
+	if adj.Quantity > 0 && product.Stock == adj.Quantity && product.Stock > product.Backordered &&
+		adj.Type != models.StockMovementTransfer {
+		if err := notifyBackInStock(tx, adj.ProductID); err != nil {
+			return nil, err
+		}
+	}
+
+	movement := &models.StockMovement{
+		ProductID:       adj.ProductID,
+		WarehouseID:     &adj.WarehouseID,
+		Type:            adj.Type,
+		Quantity:        adj.Quantity,
+		BalanceAfter:    product.Stock,
+		Reason:          adj.Reason,
+		ActorID:         adj.ActorID,
+		OrderID:         adj.OrderID,
+		ReturnRequestID: adj.ReturnRequestID,
+	}
+	if err := tx.Create(movement).Error; err != nil {
+		return nil, err
+	}
+	return movement, nil
+}
+
+// adjustLevel applies a stock change to the product's level in the
+// adjustment's warehouse, creating the level on the first receipt.
+func adjustLevel(tx *gorm.DB, adj Adjustment) error {
+	if adj.Quantity < 0 {
+		result := tx.Model(&models.StockLevel{}).
+			Where("product_id = ? AND warehouse_id = ? AND quantity + ? >= 0", adj.ProductID, adj.WarehouseID, adj.Quantity).
+			UpdateColumns(map[string]interface{}{
+				"quantity":   gorm.Expr("quantity + ?", adj.Quantity),
+				"updated_at": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInsufficientStock
+		}
+		return nil
+	}
+
+	var count int64
+	if err := tx.Model(&models.Warehouse{}).Where("id = ?", adj.WarehouseID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrWarehouseNotFound
+	}
+
+	level := models.StockLevel{ProductID: adj.ProductID, WarehouseID: adj.WarehouseID, Quantity: adj.Quantity}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "product_id"}, {Name: "warehouse_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"quantity":   gorm.Expr("stock_levels.quantity + excluded.quantity"),
+			"updated_at": time.Now(),
+		}),
+	}).Create(&level).Error
+}
+
+// Discrepancies lists products whose stock differs from the sum of their
+// ledger movements.
+func Discrepancies(db *gorm.DB) ([]models.StockDiscrepancy, error) {
+	var discrepancies []models.StockDiscrepancy
+	err := db.Table("products").
+		Select("products.id AS product_id, products.name, products.stock, COALESCE(SUM(stock_movements.quantity), 0) AS ledger_stock").
+		Joins("LEFT JOIN stock_movements ON stock_movements.product_id = products.id").
+		Group("products.id, products.name, products.stock").
+		Having("products.stock <> COALESCE(SUM(stock_movements.quantity), 0)").
+		Order("products.name").
+		Scan(&discrepancies).Error
+	return discrepancies, err
+}
+
+// Reconcile records a reconciliation movement for every discrepancy so the
+// ledger matches current stock, which is treated as the physical count.
+// It must run inside a transaction.
+func Reconcile(tx *gorm.DB, actorID *uuid.UUID) ([]models.StockMovement, error) {
+	discrepancies, err := Discrepancies(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	movements := make([]models.StockMovement, 0, len(discrepancies))
+	for _, d := range discrepancies {
+		movement := models.StockMovement{
+			ProductID:    d.ProductID,
+			Type:         models.StockMovementReconciliation,
+			Quantity:     d.Stock - d.LedgerStock,
+			BalanceAfter: d.Stock,
+			Reason:       "Ledger reconciled against recorded stock",
+			ActorID:      actorID,
+		}
+		if err := tx.Create(&movement).Error; err != nil {
+			return nil, err
+		}
+		movements = append(movements, movement)
+	}
+	return movements, nil
+}
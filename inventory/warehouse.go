@@ -0,0 +1,220 @@
+package inventory
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// Allocation strategies decide which warehouses an order takes stock from.
+const (
+	// StrategyPriority takes stock from warehouses in priority order.
+	StrategyPriority = "priority"
+	// StrategyNearest prefers warehouses in the shipping address's region,
+	// then its country, falling back to priority order.
+	StrategyNearest = "nearest"
+)
+
+// defaultWarehouseCode identifies the warehouse created on first start.
+const defaultWarehouseCode = "main"
+
+// DefaultWarehouse returns the active warehouse with the highest priority
+// (lowest number). Stock without an explicit location goes there.
+func DefaultWarehouse(tx *gorm.DB) (*models.Warehouse, error) {
+	var warehouse models.Warehouse
+	err := tx.Where("active = ?", true).Order("priority, name").First(&warehouse).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWarehouseNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &warehouse, nil
+}
+
+// Allocate removes quantity units of adj.ProductID from active warehouses,
+// visiting them in the order of the configured strategy, and records one
+// movement per warehouse based on adj. Nothing is removed unless the full
+// quantity is available.
+func (s *Service) Allocate(tx *gorm.DB, adj Adjustment, quantity int, destination models.Address) ([]models.OrderAllocation, error) {
+	query := activeLevels(tx, adj.ProductID)
+	if s.Strategy == StrategyNearest && destination.Country != "" {
+		query = query.Order(clause.Expr{
+			SQL:  "CASE WHEN warehouses.address_country = ? AND UPPER(warehouses.address_region) = UPPER(?) AND ? <> '' THEN 0 WHEN warehouses.address_country = ? THEN 1 ELSE 2 END",
+			Vars: []interface{}{destination.Country, destination.Region, destination.Region, destination.Country},
+		})
+	}
+
+	return s.take(tx, adj, quantity, query.Order("warehouses.priority, warehouses.name"))
+}
+
+// take removes quantity units of adj.ProductID from the stock levels
+// selected by query, in the order given, and records one movement per
+// warehouse based on adj. Nothing is removed unless the full quantity is
+// available.
+func (s *Service) take(tx *gorm.DB, adj Adjustment, quantity int, query *gorm.DB) ([]models.OrderAllocation, error) {
+	var levels []models.StockLevel
+	if err := query.Find(&levels).Error; err != nil {
+		return nil, err
+	}
+
+	available := 0
+	for _, level := range levels {
+		available += level.Quantity
+	}
+	if available < quantity {
+		return nil, ErrInsufficientStock
+	}
+
+	var allocations []models.OrderAllocation
+	remaining := quantity
+	for _, level := range levels {
+		if remaining == 0 {
+			break
+		}
+		take := min(level.Quantity, remaining)
+		adj.WarehouseID = level.WarehouseID
+		adj.Quantity = -take
+		if _, err := s.Adjust(tx, adj); err != nil {
+			return nil, err
+		}
+		allocations = append(allocations, models.OrderAllocation{WarehouseID: level.WarehouseID, Quantity: take})
+		remaining -= take
+	}
+	return allocations, nil
+}
+
+// stockedLevels selects the product's non-empty stock levels, locking them
+// for the rest of the transaction.
+func stockedLevels(tx *gorm.DB, productID uuid.UUID) *gorm.DB {
+	return tx.Model(&models.StockLevel{}).Select("stock_levels.*").
+		Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "stock_levels"}}).
+		Joins("JOIN warehouses ON warehouses.id = stock_levels.warehouse_id").
+		Where("stock_levels.product_id = ? AND stock_levels.quantity > 0", productID)
+}
+
+// activeLevels is stockedLevels restricted to active warehouses.
+func activeLevels(tx *gorm.DB, productID uuid.UUID) *gorm.DB {
+	return stockedLevels(tx, productID).Where("warehouses.active = ?", true)
+}
+
+// Restock returns quantity units of adj.ProductID to the warehouses they
+// were allocated from, filling the allocations in order. Units without an
+// allocation, such as those of orders placed before warehouses existed, go
+// to the default warehouse.
+func (s *Service) Restock(tx *gorm.DB, adj Adjustment, quantity int, allocations []models.OrderAllocation) error {
+	remaining := quantity
+	for _, allocation := range allocations {
+		if remaining == 0 {
+			return nil
+		}
+		adj.WarehouseID = allocation.WarehouseID
+		adj.Quantity = min(allocation.Quantity, remaining)
+		if _, err := s.Adjust(tx, adj); err != nil {
+			return err
+		}
+		remaining -= adj.Quantity
+	}
+	if remaining == 0 {
+		return nil
+	}
+
+	warehouse, err := DefaultWarehouse(tx)
+	if err != nil {
+		return err
+	}
+	adj.WarehouseID = warehouse.ID
+	adj.Quantity = remaining
+	_, err = s.Adjust(tx, adj)
+	return err
+}
+
+// Transfer moves quantity units of a product between two warehouses,
+// recording a transfer movement out of one and into the other. Total stock
+// is unchanged.
+func (s *Service) Transfer(tx *gorm.DB, productID, from, to uuid.UUID, quantity int, reason string, actorID *uuid.UUID) ([]models.StockMovement, error) {
+	adj := Adjustment{
+		ProductID: productID,
+		Type:      models.StockMovementTransfer,
+		Reason:    reason,
+		ActorID:   actorID,
+	}
+
+	adj.WarehouseID = from
+	adj.Quantity = -quantity
+	out, err := s.Adjust(tx, adj)
+	if err != nil {
+		return nil, err
+	}
+
+	adj.WarehouseID = to
+	adj.Quantity = quantity
+	in, err := s.Adjust(tx, adj)
+	if err != nil {
+		return nil, err
+	}
+	return []models.StockMovement{*out, *in}, nil
+}
+
+// ensureWarehouse creates the default warehouse when there are none and
+// places stock that has no location in the default warehouse.
+func ensureWarehouse(tx *gorm.DB) error {
+	var count int64
+	if err := tx.Model(&models.Warehouse{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		warehouse := models.Warehouse{Name: "Main warehouse", Code: defaultWarehouseCode, Active: true}
+		if err := tx.Create(&warehouse).Error; err != nil {
+			return err
+		}
+	}
+
+	var products []models.Product
+	err := tx.Where("stock > 0 AND NOT EXISTS (SELECT 1 FROM stock_levels WHERE stock_levels.product_id = products.id)").
+		Find(&products).Error
+	if err != nil || len(products) == 0 {
+		return err
+	}
+
+	warehouse, err := DefaultWarehouse(tx)
+	if err != nil {
+		return err
+	}
+	for _, product := range products {
+		level := models.StockLevel{ProductID: product.ID, WarehouseID: warehouse.ID, Quantity: product.Stock}
+		if err := tx.Create(&level).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetStock changes a product's total stock from current to target, recording
+// movements based on adj. Added stock goes to the default warehouse and is
+// offered to backorders first; removed stock is taken from active warehouses
+// in priority order, then from inactive ones.
+func (s *Service) SetStock(tx *gorm.DB, adj Adjustment, current, target int) error {
+	switch {
+	case target > current:
+		warehouse, err := DefaultWarehouse(tx)
+		if err != nil {
+			return err
+		}
+		adj.WarehouseID = warehouse.ID
+		adj.Quantity = target - current
+		if _, err := s.Adjust(tx, adj); err != nil {
+			return err
+		}
+		return s.FulfilBackorders(tx, adj.ProductID, adj.ActorID)
+	case target < current:
+		query := stockedLevels(tx, adj.ProductID).Order("warehouses.active DESC, warehouses.priority, warehouses.name")
+		_, err := s.take(tx, adj, current-target, query)
+		return err
+	}
+	return nil
+}
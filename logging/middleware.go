@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/TobiAdeniji94/ecommerce_api/tracing"
+)
+
+// RequestIDHeader carries a request's ID in requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey is the gin context key holding the request's ID.
+const RequestIDKey = "request_id"
+
+// maxRequestIDLength bounds the request IDs accepted from callers.
+const maxRequestIDLength = 128
+
+// RequestID returns middleware that gives every request an ID: the
+// caller's X-Request-ID header when it is a usable ID, or a new UUID. The
+// ID is echoed back in the response's X-Request-ID header so clients can
+// quote it when reporting a problem.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Set(RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID reports whether id is short and printable, so a caller
+// cannot forge log lines or flood them with it.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength || !utf8.ValidString(id) {
+		return false
+	}
+	for _, r := range id {
+		if r < ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware returns middleware that passes every request a logger
+// carrying its request ID, trace ID, method and route, and writes an
+// access log line once the request is handled. Loggers later enriched
+// with logging.With, e.g. with the user ID, are used for the access log
+// too. Successful requests to the quiet paths, such as health probes, are
+// logged at debug level; client errors are warnings and server errors
+// errors.
+func Middleware(logger *slog.Logger, quiet ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		ctx := c.Request.Context()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		requestID := c.GetString(RequestIDKey)
+		args := []any{"request_id", requestID}
+		if traceID := tracing.TraceID(ctx); traceID != "" {
+			args = append(args, "trace_id", traceID)
+		}
+		args = append(args, "method", c.Request.Method, "route", route)
+		c.Request = c.Request.WithContext(NewContext(ctx, logger.With(args...)))
+		if requestID != "" {
+			trace.SpanFromContext(ctx).SetAttributes(attribute.String("request_id", requestID))
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		default:
+			for _, path := range quiet {
+				if c.Request.URL.Path == path {
+					level = slog.LevelDebug
+				}
+			}
+		}
+
+		// The query string is left out: it may carry tokens
+		attrs := []slog.Attr{
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("bytes", c.Writer.Size()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+		FromContext(c.Request.Context()).LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// Recovery returns middleware that turns a panic into a 500 response and
+// logs it, with its stack, through the request's logger.
+func Recovery() gin.HandlerFunc {
+	// With no writer gin logs nothing itself; a broken connection is added
+	// to the request's errors instead, for the access log
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered any) {
+		FromContext(c.Request.Context()).Error("Recovered from panic",
+			"panic", recovered,
+			"stack", string(debug.Stack()),
+		)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
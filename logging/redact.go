@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the value of a secret.
+const Redacted = "[REDACTED]"
+
+// secretKeys are substrings of attribute keys whose values are never
+// logged.
+var secretKeys = []string{"password", "secret", "token", "authorization", "cookie", "api_key", "apikey", "signature", "card"}
+
+var (
+	// emailAddress matches an email address inside free text.
+	emailAddress = regexp.MustCompile(`([A-Za-z0-9._%+-])[A-Za-z0-9._%+-]*@([A-Za-z0-9.-]+\.[A-Za-z]{2,})`)
+	// bearerToken matches a bearer credential, e.g. an Authorization header.
+	bearerToken = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]+`)
+	// jwt matches a JSON Web Token, which starts with an encoded {".
+	jwt = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+)
+
+// Redact is a slog ReplaceAttr function. Attributes whose key names a
+// secret, such as password or token, are replaced with Redacted. Email
+// addresses are masked to their first letter and domain, and bearer
+// tokens and JWTs are redacted, wherever they appear in a message, an
+// error or a string value.
+func Redact(groups []string, attr slog.Attr) slog.Attr {
+	key := strings.ToLower(attr.Key)
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return slog.String(attr.Key, Redacted)
+		}
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, scrub(attr.Value.String()))
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			return slog.String(attr.Key, scrub(err.Error()))
+		}
+	}
+	return attr
+}
+
+// scrub masks the email addresses and redacts the tokens in s.
+func scrub(s string) string {
+	s = jwt.ReplaceAllString(s, Redacted)
+	s = bearerToken.ReplaceAllString(s, "Bearer "+Redacted)
+	return emailAddress.ReplaceAllString(s, "$1***@$2")
+}
@@ -2,22 +2,25 @@ package main
 
 import (
     "context"
+    "fmt"
     "log"
+    "log/slog"
     "net/http"
     "os"
     "os/signal"
+    "strconv"
+    "strings"
+    "syscall"
     "time"
 
-    "github.com/gin-contrib/cors"
     "github.com/gin-gonic/gin"
     "github.com/joho/godotenv"
-    "github.com/swaggo/files"
-    ginSwagger "github.com/swaggo/gin-swagger"
     _ "github.com/TobiAdeniji94/ecommerce_api/docs"
 
+    "github.com/TobiAdeniji94/ecommerce_api/app"
     "github.com/TobiAdeniji94/ecommerce_api/config"
-    "github.com/TobiAdeniji94/ecommerce_api/routes"
-    "github.com/TobiAdeniji94/ecommerce_api/utils"
+    "github.com/TobiAdeniji94/ecommerce_api/logging"
+    "github.com/TobiAdeniji94/ecommerce_api/migrations"
 )
 
 // @title E-Commerce API
@@ -32,70 +35,116 @@ import (
 // @description Use "Bearer {your token}" to authorize
 func main() {
     // Load .env if it exists
-    if err := godotenv.Load(); err != nil {
-        log.Println("No .env file found or it failed to load. Continuing with system environment variables.")
+    envErr := godotenv.Load()
+
+    // Load and validate the configuration; flags come before any command
+    cfg, args, err := config.Load(os.Args[1:])
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    // Everything logs structured records from here on, including the log package
+    slog.SetDefault(logging.New(cfg.Log, os.Stderr))
+    if envErr != nil {
+        slog.Info("No .env file found or it failed to load. Continuing with system environment variables.")
+    }
+    // gin's debug-mode messages, such as registered routes, are debug records
+    gin.DebugPrintFunc = func(format string, values ...interface{}) {
+        slog.Debug(strings.TrimSpace(fmt.Sprintf(format, values...)))
+    }
+
+    // Print the configuration or manage the schema before anything else touches it
+    if len(args) > 0 && (args[0] == "config" || args[0] == "migrate") {
+        os.Exit(setupCommand(cfg, args))
     }
 
     // Connect to database
-    config.ConnectDatabase()
-
-    // Gin router
-    r := gin.Default()
-
-    // CORS middleware
-    r.Use(cors.New(cors.Config{
-        AllowOrigins:     []string{"http://localhost:3000", "https://ecommerce-api-vkui.onrender.com"}, 
-        AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},                   
-        AllowHeaders:     []string{"Authorization", "Content-Type"},                 
-        ExposeHeaders:    []string{"Content-Length"},                                
-        AllowCredentials: true,                                                     
-        MaxAge:           12 * time.Hour,                                           
-    }))
-
-    // Rate Limiting middleware
-    r.Use(utils.PerClientRateLimiter()) // Call the middleware from utils
-
-    // Swagger docs
-    r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-
-    // Initialize routes
-    routes.InitializeRoutes(r)
-
-    // Fallback port if PORT is not set
-    port := os.Getenv("PORT")
-    if port == "" {
-        port = "3001" // Default port
+    db, err := config.ConnectDatabase(cfg.Database)
+    if err != nil {
+        fatal("Failed to connect to database", err)
+    }
+
+    // Apply pending migrations when asked to; replicas wait for each other
+    if cfg.Database.MigrateOnStart {
+        if _, err := migrations.Up(db, 0); err != nil {
+            fatal("Failed to migrate database", err)
+        }
+    }
+
+    // Refuse to run against a schema older than this build expects
+    if err := migrations.Check(db); err != nil {
+        fatal("Failed to start", err)
+    }
+
+    // Build the application: payments, tax, notifications, outbox, webhooks and inventory
+    a, err := app.New(cfg, db)
+    if err != nil {
+        fatal("Failed to start", err)
+    }
+
+    // Run a command-line task instead of the server, e.g. import-products
+    if len(args) > 0 {
+        os.Exit(runCommand(a, args))
     }
 
     // HTTP server
+    port := strconv.Itoa(cfg.Server.Port)
     srv := &http.Server{
-        Addr:    ":" + port,
-        Handler: r,
+        Addr:              ":" + port,
+        Handler:           a.Router(),
+        ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+        ReadTimeout:       cfg.Server.ReadTimeout,
+        WriteTimeout:      cfg.Server.WriteTimeout,
+        IdleTimeout:       cfg.Server.IdleTimeout,
     }
 
+    // Relay outbox events and deliver queued notifications and webhooks in the background
+    workers, stopWorkers := context.WithCancel(context.Background())
+    a.RunWorkers(workers)
+
     // Start server
     go func() {
-        log.Printf("Server is running on port %s", port)
+        slog.Info("Server is running", "port", port)
         if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-            log.Fatalf("Failed to run server: %v", err)
+            fatal("Failed to run server", err)
         }
     }()
 
-    // Wait for interrupt signal to gracefully shut down the server
+    // Wait for an interrupt, or the SIGTERM orchestrators send, to
+    // gracefully shut down the server
     quit := make(chan os.Signal, 1)
-    signal.Notify(quit, os.Interrupt)
+    signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
     <-quit
 
-    log.Println("Shutting down server...")
+    slog.Info("Shutting down server")
+
+    // Fail readiness first so load balancers stop routing new requests here
+    a.Health.ShutDown()
+    if cfg.Server.ShutdownDelay > 0 {
+        slog.Info("Draining before closing connections", "delay", cfg.Server.ShutdownDelay.String())
+        time.Sleep(cfg.Server.ShutdownDelay)
+    }
 
     // Create a deadline to wait for ongoing requests
-    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
     defer cancel()
 
     // Gracefully shutdown the server
     if err := srv.Shutdown(ctx); err != nil {
-        log.Fatalf("Server forced to shutdown: %v", err)
+        fatal("Server forced to shutdown", err)
+    }
+    stopWorkers()
+
+    // Send the spans still buffered for export
+    if err := a.Tracer.Shutdown(ctx); err != nil {
+        slog.Error("Failed to flush traces", "error", err)
     }
 
-    log.Println("Server exiting")
+    slog.Info("Server exiting")
+}
+
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+    slog.Error(msg, "error", err)
+    os.Exit(1)
 }
@@ -0,0 +1,167 @@
+// Package metrics exposes Prometheus metrics: HTTP request counts and
+// latencies by route, database pool statistics, rate-limiter rejections
+// and business counters. Each Metrics has its own registry, so several can
+// exist side by side, e.g. one per test. A nil *Metrics records nothing.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric name.
+const namespace = "ecommerce"
+
+// unmatchedRoute labels requests that matched no route, so unknown paths
+// cannot blow up the number of series.
+const unmatchedRoute = "unmatched"
+
+// Metrics holds the collectors and the registry they are served from.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	requestsInFlight prometheus.Gauge
+	rateLimited      prometheus.Counter
+	ordersPlaced     prometheus.Counter
+	ordersCanceled   *prometheus.CounterVec
+	revenue          *prometheus.CounterVec
+	loginFailures    prometheus.Counter
+}
+
+// New registers the collectors, including Go runtime and process metrics
+// and the pool statistics of db, named dbName.
+func New(db *sql.DB, dbName string) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by method, route template and status.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to handle HTTP requests, by method, route template and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		requestsInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests being handled.",
+		}),
+		rateLimited: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limit_rejections_total",
+			Help:      "Requests rejected by the per-client rate limiter.",
+		}),
+		ordersPlaced: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orders_placed_total",
+			Help:      "Orders placed.",
+		}),
+		ordersCanceled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "orders_canceled_total",
+			Help:      "Orders canceled, by who canceled them: customer or admin.",
+		}, []string{"by"}),
+		revenue: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "revenue_total",
+			Help:      "Amount of successful payments, by currency. Refunds are not subtracted.",
+		}, []string{"currency"}),
+		loginFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "login_failures_total",
+			Help:      "Logins rejected for an unknown email or a wrong password.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, dbName),
+		m.requests,
+		m.requestDuration,
+		m.requestsInFlight,
+		m.rateLimited,
+		m.ordersPlaced,
+		m.ordersCanceled,
+		m.revenue,
+		m.loginFailures,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware records every request's count, duration and status, labeled
+// with the route template, e.g. /api/v1/orders/:id, rather than the path.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m.requestsInFlight.Inc()
+		start := time.Now()
+
+		c.Next()
+
+		m.requestsInFlight.Dec()
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		m.requests.WithLabelValues(c.Request.Method, route, status).Inc()
+		m.requestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RateLimited counts a request rejected by the rate limiter.
+func (m *Metrics) RateLimited() {
+	if m == nil {
+		return
+	}
+	m.rateLimited.Inc()
+}
+
+// OrderPlaced counts a placed order.
+func (m *Metrics) OrderPlaced() {
+	if m == nil {
+		return
+	}
+	m.ordersPlaced.Inc()
+}
+
+// OrderCanceled counts an order canceled by a customer or an admin.
+func (m *Metrics) OrderCanceled(by string) {
+	if m == nil {
+		return
+	}
+	m.ordersCanceled.WithLabelValues(by).Inc()
+}
+
+// PaymentSucceeded adds a successful payment's amount to the revenue.
+func (m *Metrics) PaymentSucceeded(amount float64, currency string) {
+	if m == nil {
+		return
+	}
+	m.revenue.WithLabelValues(currency).Add(amount)
+}
+
+// LoginFailed counts a rejected login.
+func (m *Metrics) LoginFailed() {
+	if m == nil {
+		return
+	}
+	m.loginFailures.Inc()
+}
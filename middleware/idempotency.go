@@ -0,0 +1,197 @@
+package middleware
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "io"
+    "log/slog"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+
+    "github.com/TobiAdeniji94/ecommerce_api/logging"
+    "github.com/TobiAdeniji94/ecommerce_api/models"
+    "github.com/TobiAdeniji94/ecommerce_api/utils"
+)
+
+// IdempotencyHeader is the request header clients use to make retries safe.
+const IdempotencyHeader = "Idempotency-Key"
+
+// defaultPurgeInterval is how often IdempotencyPurger runs when no interval is set.
+const defaultPurgeInterval = 10 * time.Minute
+
+// responseRecorder copies everything written to the response.
+type responseRecorder struct {
+    gin.ResponseWriter
+    body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+    w.body.Write(b)
+    return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+    w.body.WriteString(s)
+    return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes a route safe to retry. Requests carrying an
+// Idempotency-Key are stored per user with a fingerprint of the request:
+// a retry with the same key and body replays the stored response, while
+// reusing the key for a different request returns 409 Conflict. Records
+// expire after ttl (IDEMPOTENCY_TTL); IdempotencyPurger deletes them. It
+// must run after Auth.
+func Idempotency(db *gorm.DB, ttl time.Duration, clock utils.Clock) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        key := c.GetHeader(IdempotencyHeader)
+        if key == "" {
+            c.Next()
+            return
+        }
+        if len(key) > 255 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+            c.Abort()
+            return
+        }
+
+        userID, ok := c.Get("userID")
+        userUUID, isUUID := userID.(uuid.UUID)
+        if !ok || !isUUID {
+            c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+            c.Abort()
+            return
+        }
+
+        // Fingerprint the request so a reused key with a different body is detected
+        body, err := io.ReadAll(c.Request.Body)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+            c.Abort()
+            return
+        }
+        c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+        hash := sha256.New()
+        hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+        hash.Write(body)
+        fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+        var existing models.IdempotencyRecord
+        err = db.Where("key = ? AND user_id = ?", key, userUUID).Limit(1).Find(&existing).Error
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check Idempotency-Key"})
+            c.Abort()
+            return
+        }
+
+        if existing.ID != uuid.Nil && existing.ExpiresAt.Before(clock.Now()) {
+            db.Delete(&existing)
+            existing = models.IdempotencyRecord{}
+        }
+
+        if existing.ID != uuid.Nil {
+            switch {
+            case existing.RequestHash != fingerprint:
+                c.JSON(http.StatusConflict, gin.H{"error": "Idempotency-Key has already been used for a different request"})
+            case existing.StatusCode == 0:
+                c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed"})
+            default:
+                c.Header("Idempotent-Replayed", "true")
+                c.Data(existing.StatusCode, existing.ContentType, []byte(existing.ResponseBody))
+            }
+            c.Abort()
+            return
+        }
+
+        // Claim the key; losing the race means another request holds it
+        record := models.IdempotencyRecord{
+            Key:         key,
+            UserID:      userUUID,
+            RequestHash: fingerprint,
+            ExpiresAt:   clock.Now().Add(ttl),
+        }
+        result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+        if result.Error != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store Idempotency-Key"})
+            c.Abort()
+            return
+        }
+        if result.RowsAffected == 0 {
+            c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed"})
+            c.Abort()
+            return
+        }
+
+        // Release the key if the handler panics, or retries would get 409
+        // until the record expires
+        completed := false
+        defer func() {
+            if !completed {
+                db.Delete(&record)
+            }
+        }()
+
+        recorder := &responseRecorder{ResponseWriter: c.Writer}
+        c.Writer = recorder
+        c.Next()
+        completed = true
+
+        // Server errors are not stored so the client can retry them
+        status := recorder.Status()
+        if status >= http.StatusInternalServerError {
+            db.Delete(&record)
+            return
+        }
+
+        err = db.Model(&record).Updates(map[string]interface{}{
+            "status_code":   status,
+            "content_type":  recorder.Header().Get("Content-Type"),
+            "response_body": recorder.body.String(),
+        }).Error
+        if err != nil {
+            logging.FromContext(c.Request.Context()).Error("Failed to store idempotent response", "idempotency_key", key, "error", err)
+        }
+    }
+}
+
+// IdempotencyPurger deletes expired Idempotency-Key records.
+type IdempotencyPurger struct {
+    DB       *gorm.DB
+    Clock    utils.Clock
+    Interval time.Duration
+}
+
+// Run purges expired records every Interval until ctx is cancelled.
+func (p *IdempotencyPurger) Run(ctx context.Context) {
+    interval := p.Interval
+    if interval <= 0 {
+        interval = defaultPurgeInterval
+    }
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+
+        if _, err := p.Purge(ctx); err != nil {
+            slog.Error("Failed to purge idempotency records", "error", err)
+        }
+    }
+}
+
+// Purge deletes the records that have expired and returns how many it deleted.
+func (p *IdempotencyPurger) Purge(ctx context.Context) (int64, error) {
+    result := p.DB.WithContext(ctx).Where("expires_at < ?", p.Clock.Now()).Delete(&models.IdempotencyRecord{})
+    return result.RowsAffected, result.Error
+}
@@ -0,0 +1,26 @@
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS webhook_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS stock_alerts;
+DROP TABLE IF EXISTS wishlist_items;
+DROP TABLE IF EXISTS reviews;
+DROP TABLE IF EXISTS stock_levels;
+DROP TABLE IF EXISTS warehouses;
+DROP TABLE IF EXISTS stock_movements;
+DROP TABLE IF EXISTS idempotency_records;
+DROP TABLE IF EXISTS shipments;
+DROP TABLE IF EXISTS shipping_methods;
+DROP TABLE IF EXISTS coupon_redemptions;
+DROP TABLE IF EXISTS coupons;
+DROP TABLE IF EXISTS refunds;
+DROP TABLE IF EXISTS return_items;
+DROP TABLE IF EXISTS return_requests;
+DROP TABLE IF EXISTS payment_events;
+DROP TABLE IF EXISTS payments;
+DROP TABLE IF EXISTS order_allocations;
+DROP TABLE IF EXISTS order_items;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS users;
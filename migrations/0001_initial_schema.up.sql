@@ -0,0 +1,403 @@
+-- Baseline schema. It matches what AutoMigrate created before versioned
+-- migrations, and only creates what is missing, so existing databases can
+-- adopt it with "migrate up".
+
+CREATE TABLE IF NOT EXISTS users (
+    id char(36),
+    email text NOT NULL,
+    password varchar(255) NOT NULL,
+    role text DEFAULT 'user',
+    created_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT uni_users_email UNIQUE (email)
+);
+
+CREATE TABLE IF NOT EXISTS products (
+    id char(36),
+    sku text,
+    name text NOT NULL,
+    description text,
+    category text,
+    tax_class text NOT NULL DEFAULT 'standard',
+    price decimal NOT NULL,
+    stock bigint NOT NULL,
+    low_stock_threshold bigint NOT NULL DEFAULT 0,
+    backorder_mode text NOT NULL DEFAULT 'none',
+    release_date timestamptz,
+    max_backorder bigint NOT NULL DEFAULT 0,
+    backordered bigint NOT NULL DEFAULT 0,
+    weight decimal NOT NULL DEFAULT 0,
+    length decimal NOT NULL DEFAULT 0,
+    width decimal NOT NULL DEFAULT 0,
+    height decimal NOT NULL DEFAULT 0,
+    rating_average decimal NOT NULL DEFAULT 0,
+    rating_count bigint NOT NULL DEFAULT 0,
+    rating_total bigint NOT NULL DEFAULT 0,
+    version bigint NOT NULL DEFAULT 1,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_sku ON products (sku) WHERE sku <> '';
+CREATE INDEX IF NOT EXISTS idx_products_category ON products (category);
+
+CREATE TABLE IF NOT EXISTS orders (
+    id char(36),
+    user_id char(36),
+    status text DEFAULT 'Pending',
+    subtotal decimal NOT NULL DEFAULT 0,
+    coupon_code text,
+    discount_amount decimal NOT NULL DEFAULT 0,
+    tax_total decimal NOT NULL DEFAULT 0,
+    prices_include_tax boolean NOT NULL DEFAULT false,
+    shipping_line1 text,
+    shipping_line2 text,
+    shipping_city text,
+    shipping_region text,
+    shipping_postal_code text,
+    shipping_country text,
+    shipping_method_id text,
+    shipping_cost decimal NOT NULL DEFAULT 0,
+    total decimal NOT NULL DEFAULT 0,
+    refunded_amount decimal NOT NULL DEFAULT 0,
+    version bigint NOT NULL DEFAULT 1,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_orders_user FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS order_items (
+    id char(36),
+    order_id char(36),
+    product_id char(36),
+    quantity bigint,
+    unit_price decimal NOT NULL DEFAULT 0,
+    discount_amount decimal NOT NULL DEFAULT 0,
+    tax_rate decimal NOT NULL DEFAULT 0,
+    tax_amount decimal NOT NULL DEFAULT 0,
+    backordered_quantity bigint NOT NULL DEFAULT 0,
+    preorder boolean NOT NULL DEFAULT false,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_order_items_product FOREIGN KEY (product_id) REFERENCES products(id),
+    CONSTRAINT fk_orders_items FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE TABLE IF NOT EXISTS order_allocations (
+    id char(36),
+    order_item_id char(36) NOT NULL,
+    warehouse_id text NOT NULL,
+    quantity bigint NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_order_items_allocations FOREIGN KEY (order_item_id) REFERENCES order_items(id)
+);
+CREATE INDEX IF NOT EXISTS idx_order_allocations_order_item_id ON order_allocations (order_item_id);
+
+CREATE TABLE IF NOT EXISTS payments (
+    id char(36),
+    order_id text NOT NULL,
+    provider text NOT NULL,
+    provider_ref text NOT NULL,
+    amount decimal NOT NULL,
+    currency text NOT NULL,
+    status text NOT NULL DEFAULT 'requires_payment',
+    amount_refunded decimal NOT NULL DEFAULT 0,
+    failure_message text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments (order_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_provider_ref ON payments (provider_ref);
+
+CREATE TABLE IF NOT EXISTS payment_events (
+    id text,
+    provider text NOT NULL,
+    type text NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS return_requests (
+    id char(36),
+    order_id text NOT NULL,
+    user_id text NOT NULL,
+    reason text NOT NULL,
+    status text NOT NULL DEFAULT 'Requested',
+    admin_note text,
+    refund_amount decimal NOT NULL DEFAULT 0,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_return_requests_user_id ON return_requests (user_id);
+CREATE INDEX IF NOT EXISTS idx_return_requests_order_id ON return_requests (order_id);
+
+CREATE TABLE IF NOT EXISTS return_items (
+    id char(36),
+    return_request_id char(36) NOT NULL,
+    order_item_id char(36) NOT NULL,
+    quantity bigint NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_return_items_order_item FOREIGN KEY (order_item_id) REFERENCES order_items(id),
+    CONSTRAINT fk_return_requests_items FOREIGN KEY (return_request_id) REFERENCES return_requests(id)
+);
+CREATE INDEX IF NOT EXISTS idx_return_items_order_item_id ON return_items (order_item_id);
+CREATE INDEX IF NOT EXISTS idx_return_items_return_request_id ON return_items (return_request_id);
+
+CREATE TABLE IF NOT EXISTS refunds (
+    id char(36),
+    payment_id text NOT NULL,
+    return_request_id text,
+    provider_ref text NOT NULL,
+    amount decimal NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_refunds_payment_id ON refunds (payment_id);
+CREATE INDEX IF NOT EXISTS idx_refunds_return_request_id ON refunds (return_request_id);
+
+CREATE TABLE IF NOT EXISTS coupons (
+    id char(36),
+    code text NOT NULL,
+    type text NOT NULL,
+    value decimal NOT NULL,
+    min_order_value decimal NOT NULL DEFAULT 0,
+    product_id text,
+    category text,
+    starts_at timestamptz,
+    expires_at timestamptz,
+    usage_limit bigint NOT NULL DEFAULT 0,
+    per_user_limit bigint NOT NULL DEFAULT 0,
+    times_used bigint NOT NULL DEFAULT 0,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_coupons_code ON coupons (code);
+CREATE INDEX IF NOT EXISTS idx_coupons_product_id ON coupons (product_id);
+
+CREATE TABLE IF NOT EXISTS coupon_redemptions (
+    id char(36),
+    coupon_id text NOT NULL,
+    user_id text NOT NULL,
+    order_id text NOT NULL,
+    discount decimal NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_coupon_id ON coupon_redemptions (coupon_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_coupon_redemptions_order_id ON coupon_redemptions (order_id);
+CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_user_id ON coupon_redemptions (user_id);
+
+CREATE TABLE IF NOT EXISTS shipping_methods (
+    id char(36),
+    name text NOT NULL,
+    code text NOT NULL,
+    carrier text,
+    rate_type text NOT NULL,
+    flat_rate decimal NOT NULL DEFAULT 0,
+    base_rate decimal NOT NULL DEFAULT 0,
+    rate_per_kg decimal NOT NULL DEFAULT 0,
+    free_over decimal NOT NULL DEFAULT 0,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shipping_methods_code ON shipping_methods (code);
+
+CREATE TABLE IF NOT EXISTS shipments (
+    id char(36),
+    order_id char(36) NOT NULL,
+    carrier text NOT NULL,
+    tracking_number text NOT NULL,
+    tracking_url text,
+    shipped_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_orders_shipments FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+CREATE INDEX IF NOT EXISTS idx_shipments_order_id ON shipments (order_id);
+
+CREATE TABLE IF NOT EXISTS idempotency_records (
+    id char(36),
+    key varchar(255) NOT NULL,
+    user_id text NOT NULL,
+    request_hash text NOT NULL,
+    status_code bigint NOT NULL DEFAULT 0,
+    content_type text,
+    response_body text,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_idempotency_user_key ON idempotency_records (key,user_id);
+CREATE INDEX IF NOT EXISTS idx_idempotency_records_expires_at ON idempotency_records (expires_at);
+
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id char(36),
+    product_id text NOT NULL,
+    warehouse_id text,
+    type text NOT NULL,
+    quantity bigint NOT NULL,
+    balance_after bigint NOT NULL,
+    reason text,
+    actor_id text,
+    order_id text,
+    return_request_id text,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_order_id ON stock_movements (order_id);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_warehouse_id ON stock_movements (warehouse_id);
+CREATE INDEX IF NOT EXISTS idx_stock_movements_product_created ON stock_movements (product_id,created_at);
+
+CREATE TABLE IF NOT EXISTS warehouses (
+    id char(36),
+    name text NOT NULL,
+    code text NOT NULL,
+    address_line1 text,
+    address_line2 text,
+    address_city text,
+    address_region text,
+    address_postal_code text,
+    address_country text,
+    priority bigint NOT NULL DEFAULT 0,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_warehouses_code ON warehouses (code);
+
+CREATE TABLE IF NOT EXISTS stock_levels (
+    id char(36),
+    product_id text NOT NULL,
+    warehouse_id char(36) NOT NULL,
+    quantity bigint NOT NULL DEFAULT 0,
+    updated_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_stock_levels_warehouse FOREIGN KEY (warehouse_id) REFERENCES warehouses(id)
+);
+CREATE INDEX IF NOT EXISTS idx_stock_levels_warehouse_id ON stock_levels (warehouse_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_levels_product_warehouse ON stock_levels (product_id,warehouse_id);
+
+CREATE TABLE IF NOT EXISTS reviews (
+    id char(36),
+    product_id text NOT NULL,
+    user_id text NOT NULL,
+    rating bigint NOT NULL,
+    title text NOT NULL,
+    body text,
+    status text NOT NULL DEFAULT 'Pending',
+    admin_note text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reviews_product_user ON reviews (product_id,user_id);
+CREATE INDEX IF NOT EXISTS idx_reviews_status ON reviews (status);
+CREATE INDEX IF NOT EXISTS idx_reviews_user_id ON reviews (user_id);
+
+CREATE TABLE IF NOT EXISTS wishlist_items (
+    id char(36),
+    user_id text NOT NULL,
+    product_id char(36) NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_wishlist_items_product FOREIGN KEY (product_id) REFERENCES products(id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wishlist_items_user_product ON wishlist_items (user_id,product_id);
+
+CREATE TABLE IF NOT EXISTS stock_alerts (
+    id char(36),
+    user_id text NOT NULL,
+    product_id text NOT NULL,
+    notified_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_stock_alerts_product_id ON stock_alerts (product_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_alerts_user_product ON stock_alerts (user_id,product_id) WHERE notified_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id char(36),
+    user_id text NOT NULL,
+    type text NOT NULL,
+    subject text NOT NULL,
+    body text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    attempts bigint NOT NULL DEFAULT 0,
+    last_error text,
+    next_attempt_at timestamptz NOT NULL,
+    sent_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_due ON notifications (status,next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications (user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id char(36),
+    url text NOT NULL,
+    description text,
+    event_types text NOT NULL,
+    secret text NOT NULL,
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id char(36),
+    subscription_id text NOT NULL,
+    event_id text NOT NULL,
+    event_type text NOT NULL,
+    payload text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    attempts bigint NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL,
+    last_status_code bigint,
+    last_error text,
+    delivered_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status,next_attempt_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON webhook_deliveries (subscription_id,event_id);
+
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    id char(36),
+    delivery_id char(36) NOT NULL,
+    status_code bigint,
+    error text,
+    duration_ms bigint,
+    created_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_webhook_deliveries_attempt_log FOREIGN KEY (delivery_id) REFERENCES webhook_deliveries(id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_attempts_delivery_id ON webhook_attempts (delivery_id);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id char(36),
+    position bigserial NOT NULL,
+    aggregate_type text NOT NULL,
+    aggregate_id text NOT NULL,
+    event_type text NOT NULL,
+    payload text NOT NULL,
+    attempts bigint NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL,
+    last_error text,
+    published_at timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_aggregate ON outbox_events (aggregate_type,aggregate_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_events_position ON outbox_events (position);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_published_at ON outbox_events (published_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_next_attempt_at ON outbox_events (next_attempt_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_refunds_due;
+ALTER TABLE refunds DROP COLUMN IF EXISTS next_attempt_at;
+ALTER TABLE refunds DROP COLUMN IF EXISTS last_error;
+ALTER TABLE refunds DROP COLUMN IF EXISTS attempts;
+ALTER TABLE refunds DROP COLUMN IF EXISTS status;
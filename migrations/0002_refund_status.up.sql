@@ -0,0 +1,9 @@
+-- Refunds are recorded as pending before the provider is called, and
+-- retried until they succeed. Refunds made before this were already sent.
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'succeeded';
+ALTER TABLE refunds ALTER COLUMN status SET DEFAULT 'pending';
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS attempts bigint NOT NULL DEFAULT 0;
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS last_error text;
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS next_attempt_at timestamptz NOT NULL DEFAULT now();
+ALTER TABLE refunds ALTER COLUMN next_attempt_at DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_refunds_due ON refunds (status,next_attempt_at);
@@ -0,0 +1,3 @@
+ALTER TABLE stock_alerts ALTER COLUMN product_id TYPE text;
+ALTER TABLE stock_alerts ALTER COLUMN user_id TYPE text;
+ALTER TABLE wishlist_items ALTER COLUMN user_id TYPE text;
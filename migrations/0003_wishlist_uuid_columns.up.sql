@@ -0,0 +1,5 @@
+-- Wishlist items and stock alerts store their user and product IDs as
+-- char(36), like every other table.
+ALTER TABLE wishlist_items ALTER COLUMN user_id TYPE char(36);
+ALTER TABLE stock_alerts ALTER COLUMN user_id TYPE char(36);
+ALTER TABLE stock_alerts ALTER COLUMN product_id TYPE char(36);
@@ -0,0 +1,342 @@
+// Package migrations manages the database schema with versioned SQL files.
+// Each migration is a pair of files, NNNN_name.up.sql and NNNN_name.down.sql,
+// embedded in the binary. Applied versions are recorded in the
+// schema_migrations table, and every run holds a Postgres advisory lock so
+// that only one instance migrates at a time. SQLite databases are built
+// from the models instead; see sqlite.go.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// lockKey identifies the advisory lock held while migrating.
+const lockKey int64 = 0x65636f6d6d657263 // "ecommerc"
+
+// fileName matches migration file names, e.g. 0002_add_gift_cards.up.sql,
+// and migrationName the names given to Create.
+var (
+	fileName      = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+	migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+// ErrOutdated is returned by Check when migrations are pending.
+var ErrOutdated = errors.New("database schema is out of date")
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// State is a migration and when it was applied, if it was. Migrations found
+// in the database but not in the binary have no SQL.
+type State struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// record is a row of schema_migrations.
+type record struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (record) TableName() string {
+	return "schema_migrations"
+}
+
+// Load returns the embedded migrations in version order.
+func Load() ([]Migration, error) {
+	return load(files)
+}
+
+// load reads the migrations in fsys. Every version needs an up and a down
+// file.
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files named %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up applies pending migrations in version order, at most steps of them
+// when steps is positive, and returns the ones applied. Each migration runs
+// in its own transaction. On SQLite, Up creates or updates the tables of
+// every model and applies no migrations.
+func Up(db *gorm.DB, steps int) ([]Migration, error) {
+	if isSQLite(db) {
+		return nil, db.AutoMigrate(schemaModels...)
+	}
+
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	err = withLock(db, func(conn *gorm.DB) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if steps > 0 && len(applied) == steps {
+				break
+			}
+
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&record{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the latest applied migrations, steps of them (at least
+// one), and returns the ones rolled back.
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	if isSQLite(db) {
+		return nil, errSQLite
+	}
+
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	if steps < 1 {
+		steps = 1
+	}
+
+	var reverted []Migration
+	err = withLock(db, func(conn *gorm.DB) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Down).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&record{}, "version = ?", migration.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration with when it was applied, followed by
+// applied versions this binary does not know.
+func Status(db *gorm.DB) ([]State, error) {
+	if isSQLite(db) {
+		return nil, errSQLite
+	}
+
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []record
+	if db.Migrator().HasTable(&record{}) {
+		if err := db.Order("version").Find(&records).Error; err != nil {
+			return nil, err
+		}
+	}
+	byVersion := make(map[int64]record, len(records))
+	for _, r := range records {
+		byVersion[r.Version] = r
+	}
+
+	states := make([]State, 0, len(migrations))
+	for _, migration := range migrations {
+		state := State{Migration: migration}
+		if r, ok := byVersion[migration.Version]; ok {
+			appliedAt := r.AppliedAt
+			state.AppliedAt = &appliedAt
+			delete(byVersion, migration.Version)
+		}
+		states = append(states, state)
+	}
+	for _, r := range records {
+		if _, ok := byVersion[r.Version]; ok {
+			appliedAt := r.AppliedAt
+			states = append(states, State{Migration: Migration{Version: r.Version, Name: r.Name}, AppliedAt: &appliedAt})
+		}
+	}
+	return states, nil
+}
+
+// Check returns ErrOutdated when any migration in the binary has not been
+// applied. Versions applied by a newer binary are allowed, so an older
+// release keeps running during a rolling deploy. On SQLite, Check only
+// requires a table for every model.
+func Check(db *gorm.DB) error {
+	if isSQLite(db) {
+		return checkSQLite(db)
+	}
+
+	states, err := Status(db)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	var first *State
+	for i := range states {
+		if states[i].AppliedAt == nil {
+			if first == nil {
+				first = &states[i]
+			}
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w: %d pending migration(s) starting at %d_%s; run \"migrate up\"",
+			ErrOutdated, pending, first.Version, first.Name)
+	}
+	return nil
+}
+
+// Create writes empty up and down files for a new migration in dir, numbered
+// after the highest version there, and returns their paths.
+func Create(dir, name string) (string, string, error) {
+	if !migrationName.MatchString(name) {
+		return "", "", fmt.Errorf("invalid migration name %q: use lowercase letters, digits and underscores", name)
+	}
+
+	existing, err := load(os.DirFS(dir))
+	if err != nil {
+		return "", "", err
+	}
+	version := int64(1)
+	if len(existing) > 0 {
+		version = existing[len(existing)-1].Version + 1
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%04d_%s", version, name))
+	up, down := base+".up.sql", base+".down.sql"
+	if err := os.WriteFile(up, []byte("-- Write the schema change here.\n"), 0o644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(down, []byte("-- Undo the change in the up file here.\n"), 0o644); err != nil {
+		return "", "", err
+	}
+	return up, down, nil
+}
+
+// withLock runs fn on a single connection holding the migration advisory
+// lock, creating the schema_migrations table first. Other instances wait
+// for the lock, then see the migrations already applied.
+func withLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error; err != nil {
+			return err
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", lockKey)
+
+		if err := conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version bigint PRIMARY KEY,
+			name text NOT NULL,
+			applied_at timestamptz NOT NULL
+		)`).Error; err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+// appliedVersions returns the versions recorded in schema_migrations.
+func appliedVersions(db *gorm.DB) (map[int64]struct{}, error) {
+	var versions []int64
+	if err := db.Model(&record{}).Pluck("version", &versions).Error; err != nil {
+		return nil, err
+	}
+
+	done := make(map[int64]struct{}, len(versions))
+	for _, version := range versions {
+		done[version] = struct{}{}
+	}
+	return done, nil
+}
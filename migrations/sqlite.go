@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// The SQL migrations are written for Postgres. SQLite databases, used for
+// local development and tests, are built from the models instead, so they
+// have no versions to report or roll back.
+var errSQLite = errors.New("SQLite databases are built from the models by \"migrate up\" and have no versions; delete the database file to start over")
+
+// schemaModels lists every model with a table, in dependency order.
+var schemaModels = []interface{}{
+	&models.User{},
+	&models.Product{},
+	&models.Order{},
+	&models.OrderItem{},
+	&models.OrderAllocation{},
+	&models.Payment{},
+	&models.PaymentEvent{},
+	&models.ReturnRequest{},
+	&models.ReturnItem{},
+	&models.Refund{},
+	&models.Coupon{},
+	&models.CouponRedemption{},
+	&models.ShippingMethod{},
+	&models.Shipment{},
+	&models.IdempotencyRecord{},
+	&models.StockMovement{},
+	&models.Warehouse{},
+	&models.StockLevel{},
+	&models.Review{},
+	&models.WishlistItem{},
+	&models.StockAlert{},
+	&models.Notification{},
+	&models.WebhookSubscription{},
+	&models.WebhookDelivery{},
+	&models.WebhookAttempt{},
+	&models.OutboxEvent{},
+}
+
+// isSQLite reports whether db is an SQLite database.
+func isSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == "sqlite"
+}
+
+// checkSQLite returns ErrOutdated when a model has no table.
+func checkSQLite(db *gorm.DB) error {
+	for _, model := range schemaModels {
+		if !db.Migrator().HasTable(model) {
+			return fmt.Errorf("%w: missing table for %T; run \"migrate up\"", ErrOutdated, model)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package models
+
+// Address is a postal address embedded in the records that use it.
+type Address struct {
+    Line1      string `json:"line1"`
+    Line2      string `json:"line2,omitempty"`
+    City       string `json:"city"`
+    Region     string `json:"region,omitempty"`
+    PostalCode string `json:"postal_code,omitempty"`
+    Country    string `json:"country"`
+}
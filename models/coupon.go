@@ -0,0 +1,98 @@
+package models
+
+import (
+    "math"
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// Coupon discount types.
+const (
+    CouponTypePercentage = "percentage"
+    CouponTypeFixed      = "fixed"
+)
+
+// Coupon is a discount code redeemable when placing an order. A coupon
+// scoped to a product or category only discounts matching order lines.
+type Coupon struct {
+    ID            uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+    Code          string     `gorm:"uniqueIndex;not null" json:"code"`
+    Type          string     `gorm:"not null" json:"type"`
+    Value         float64    `gorm:"not null" json:"value"`
+    MinOrderValue float64    `gorm:"not null;default:0" json:"min_order_value"`
+    ProductID     *uuid.UUID `gorm:"index" json:"product_id,omitempty"`
+    Category      string     `json:"category,omitempty"`
+    StartsAt      *time.Time `json:"starts_at,omitempty"`
+    ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+    UsageLimit    int        `gorm:"not null;default:0" json:"usage_limit"`
+    PerUserLimit  int        `gorm:"not null;default:0" json:"per_user_limit"`
+    TimesUsed     int        `gorm:"not null;default:0" json:"times_used"`
+    Active        bool       `gorm:"not null" json:"active"`
+    CreatedAt     time.Time  `json:"created_at"`
+    UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the coupon
+func (c *Coupon) BeforeCreate(tx *gorm.DB) (err error) {
+    if c.ID == uuid.Nil {
+        c.ID = uuid.New()
+    }
+    return
+}
+
+// IsValidAt reports whether the coupon is active and inside its validity window at t.
+func (c *Coupon) IsValidAt(t time.Time) bool {
+    if !c.Active {
+        return false
+    }
+    if c.StartsAt != nil && t.Before(*c.StartsAt) {
+        return false
+    }
+    if c.ExpiresAt != nil && !t.Before(*c.ExpiresAt) {
+        return false
+    }
+    return true
+}
+
+// AppliesTo reports whether the coupon's scope covers the product.
+func (c *Coupon) AppliesTo(product Product) bool {
+    if c.ProductID != nil && *c.ProductID != product.ID {
+        return false
+    }
+    if c.Category != "" && c.Category != product.Category {
+        return false
+    }
+    return true
+}
+
+// Discount returns the discount for an eligible amount, never more than the amount itself.
+func (c *Coupon) Discount(eligible float64) float64 {
+    var discount float64
+    switch c.Type {
+    case CouponTypePercentage:
+        discount = eligible * c.Value / 100
+    case CouponTypeFixed:
+        discount = c.Value
+    }
+    return math.Min(math.Round(discount*100)/100, eligible)
+}
+
+// CouponRedemption records a coupon used on an order.
+type CouponRedemption struct {
+    ID        uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+    CouponID  uuid.UUID `gorm:"index;not null" json:"coupon_id"`
+    UserID    uuid.UUID `gorm:"index;not null" json:"user_id"`
+    OrderID   uuid.UUID `gorm:"uniqueIndex;not null" json:"order_id"`
+    Discount  float64   `gorm:"not null" json:"discount"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the redemption
+func (r *CouponRedemption) BeforeCreate(tx *gorm.DB) (err error) {
+    if r.ID == uuid.Nil {
+        r.ID = uuid.New()
+    }
+    return
+}
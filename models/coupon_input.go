@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// CouponInput represents the payload for creating or updating a coupon.
+type CouponInput struct {
+    Code          string     `json:"code" binding:"required"`
+    Type          string     `json:"type" binding:"required,oneof=percentage fixed"`
+    Value         float64    `json:"value" binding:"required,gt=0"`
+    MinOrderValue float64    `json:"min_order_value" binding:"omitempty,min=0"`
+    ProductID     string     `json:"product_id" binding:"omitempty,uuid"`
+    Category      string     `json:"category" binding:"omitempty"`
+    StartsAt      *time.Time `json:"starts_at" binding:"omitempty"`
+    ExpiresAt     *time.Time `json:"expires_at" binding:"omitempty"`
+    UsageLimit    int        `json:"usage_limit" binding:"omitempty,min=0"`
+    PerUserLimit  int        `json:"per_user_limit" binding:"omitempty,min=0"`
+    Active        *bool      `json:"active" binding:"omitempty"`
+}
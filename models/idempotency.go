@@ -0,0 +1,31 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// IdempotencyRecord stores the outcome of a request made with an
+// Idempotency-Key so retries replay it instead of repeating the request.
+// StatusCode is zero while the original request is still in progress.
+type IdempotencyRecord struct {
+    ID           uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+    Key          string    `gorm:"uniqueIndex:idx_idempotency_user_key;size:255;not null" json:"key"`
+    UserID       uuid.UUID `gorm:"uniqueIndex:idx_idempotency_user_key;not null" json:"user_id"`
+    RequestHash  string    `gorm:"not null" json:"request_hash"`
+    StatusCode   int       `gorm:"not null;default:0" json:"status_code"`
+    ContentType  string    `json:"content_type"`
+    ResponseBody string    `gorm:"type:text" json:"response_body"`
+    ExpiresAt    time.Time `gorm:"index;not null" json:"expires_at"`
+    CreatedAt    time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the record
+func (r *IdempotencyRecord) BeforeCreate(tx *gorm.DB) (err error) {
+    if r.ID == uuid.Nil {
+        r.ID = uuid.New()
+    }
+    return
+}
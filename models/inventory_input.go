@@ -0,0 +1,12 @@
+package models
+
+// StockAdjustmentInput represents a manual stock change. Quantity is signed:
+// positive values add stock and negative values remove it. Without a
+// warehouse the default warehouse is used.
+type StockAdjustmentInput struct {
+    ProductID   string `json:"product_id" binding:"required,uuid"`
+    WarehouseID string `json:"warehouse_id" binding:"omitempty,uuid"`
+    Quantity    int    `json:"quantity" binding:"required,ne=0"`
+    Type        string `json:"type" binding:"omitempty,oneof=receipt adjustment"`
+    Reason      string `json:"reason" binding:"required"`
+}
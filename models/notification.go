@@ -0,0 +1,46 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// Notification types.
+const (
+    NotificationBackInStock = "back_in_stock"
+)
+
+// Notification delivery statuses.
+const (
+    NotificationStatusPending = "pending"
+    NotificationStatusSent    = "sent"
+    NotificationStatusFailed  = "failed"
+)
+
+// Notification is a message queued for delivery to a user. The table is
+// the delivery queue: pending notifications are sent by a background
+// worker and retried with backoff until they are sent or fail for good.
+type Notification struct {
+    ID            uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+    UserID        uuid.UUID  `gorm:"index;not null" json:"user_id"`
+    Type          string     `gorm:"not null" json:"type"`
+    Subject       string     `gorm:"not null" json:"subject"`
+    Body          string     `gorm:"not null" json:"body"`
+    Status        string     `gorm:"index:idx_notifications_due,priority:1;not null;default:pending" json:"status"`
+    Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+    LastError     string     `json:"last_error,omitempty"`
+    NextAttemptAt time.Time  `gorm:"index:idx_notifications_due,priority:2;not null" json:"next_attempt_at"`
+    SentAt        *time.Time `json:"sent_at,omitempty"`
+    CreatedAt     time.Time  `json:"created_at"`
+    UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the notification
+func (n *Notification) BeforeCreate(tx *gorm.DB) (err error) {
+    if n.ID == uuid.Nil {
+        n.ID = uuid.New()
+    }
+    return
+}
@@ -9,13 +9,25 @@ import (
 
 // Order represents a user's order. Contains multiple products via OrderItems.
 type Order struct {
-    ID        uuid.UUID   `gorm:"type:char(36);primaryKey" json:"id"`
-    UserID    uuid.UUID   `json:"user_id"`
-    User      User        `gorm:"foreignKey:UserID" json:"user"` 
-    Items     []OrderItem `gorm:"foreignKey:OrderID" json:"items"`
-    Status    string      `gorm:"default:Pending" json:"status"`
-    CreatedAt time.Time   `json:"created_at"`
-    UpdatedAt time.Time   `json:"updated_at"`
+    ID               uuid.UUID   `gorm:"type:char(36);primaryKey" json:"id"`
+    UserID           uuid.UUID   `json:"user_id"`
+    User             User        `gorm:"foreignKey:UserID" json:"user"`
+    Items            []OrderItem `gorm:"foreignKey:OrderID" json:"items"`
+    Status           string      `gorm:"default:Pending" json:"status"`
+    Subtotal         float64     `gorm:"not null;default:0" json:"subtotal"`
+    CouponCode       string      `json:"coupon_code,omitempty"`
+    DiscountAmount   float64     `gorm:"not null;default:0" json:"discount"`
+    TaxTotal         float64     `gorm:"not null;default:0" json:"tax_total"`
+    PricesIncludeTax bool        `gorm:"not null;default:false" json:"prices_include_tax"`
+    ShippingAddress  Address     `gorm:"embedded;embeddedPrefix:shipping_" json:"shipping_address"`
+    ShippingMethodID *uuid.UUID  `json:"shipping_method_id,omitempty"`
+    ShippingCost     float64     `gorm:"not null;default:0" json:"shipping_cost"`
+    Shipments        []Shipment  `gorm:"foreignKey:OrderID" json:"shipments"`
+    Total            float64     `gorm:"not null;default:0" json:"total"`
+    RefundedAmount   float64     `gorm:"not null;default:0" json:"refunded_amount"`
+    Version          int         `gorm:"not null;default:1" json:"version"`
+    CreatedAt        time.Time   `json:"created_at"`
+    UpdatedAt        time.Time   `json:"updated_at"`
 }
 
 // BeforeCreate hook to generate a UUID for the user
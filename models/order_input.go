@@ -6,9 +6,23 @@ type OrderItemInput struct {
     Quantity  int    `json:"quantity" binding:"required,min=1"`
 }
 
-// PlaceOrderInput represents the payload for placing an order.
+// AddressInput represents a postal address in a request payload.
+type AddressInput struct {
+    Line1      string `json:"line1" binding:"required"`
+    Line2      string `json:"line2" binding:"omitempty"`
+    City       string `json:"city" binding:"required"`
+    Region     string `json:"region" binding:"omitempty"`
+    PostalCode string `json:"postal_code" binding:"omitempty"`
+    Country    string `json:"country" binding:"required,iso3166_1_alpha2"`
+}
+
+// PlaceOrderInput represents the payload for placing an order. The
+// shipping address is required on every order, as tax is calculated for it.
 type PlaceOrderInput struct {
-    Items []OrderItemInput `json:"items" binding:"required,dive"`
+    Items            []OrderItemInput `json:"items" binding:"required,dive"`
+    ShippingAddress  *AddressInput    `json:"shipping_address" binding:"required"`
+    ShippingMethodID string           `json:"shipping_method_id" binding:"omitempty,uuid"`
+    CouponCode       string           `json:"coupon_code" binding:"omitempty"`
 }
 
 // UpdateOrderStatusInput represents the payload for updating the order status.
@@ -6,12 +6,20 @@ import (
 )
 
 // OrderItem represents a single product within an Order. 
+// BackorderedQuantity is the part of Quantity still waiting for stock.
 type OrderItem struct {
-    ID        uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
-    OrderID   uuid.UUID `json:"order_id"`
-    ProductID uuid.UUID `json:"product_id"`
-    Product   Product   `gorm:"foreignKey:ProductID" json:"product"`
-    Quantity  int       `json:"quantity"`
+    ID                  uuid.UUID         `gorm:"type:char(36);primaryKey" json:"id"`
+    OrderID             uuid.UUID         `json:"order_id"`
+    ProductID           uuid.UUID         `json:"product_id"`
+    Product             Product           `gorm:"foreignKey:ProductID" json:"product"`
+    Quantity            int               `json:"quantity"`
+    UnitPrice           float64           `gorm:"not null;default:0" json:"unit_price"`
+    DiscountAmount      float64           `gorm:"not null;default:0" json:"discount"`
+    TaxRate             float64           `gorm:"not null;default:0" json:"tax_rate"`
+    TaxAmount           float64           `gorm:"not null;default:0" json:"tax"`
+    BackorderedQuantity int               `gorm:"not null;default:0" json:"backordered"`
+    Preorder            bool              `gorm:"not null;default:false" json:"preorder"`
+    Allocations         []OrderAllocation `gorm:"foreignKey:OrderItemID" json:"allocations,omitempty"`
 }
 
 // BeforeCreate hook to generate a UUID for the user
@@ -21,3 +29,19 @@ func (oi *OrderItem) BeforeCreate(tx *gorm.DB) (err error) {
     }
     return
 }
+
+// OrderAllocation records how much of an order item was taken from a warehouse.
+type OrderAllocation struct {
+    ID          uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+    OrderItemID uuid.UUID `gorm:"index;not null" json:"order_item_id"`
+    WarehouseID uuid.UUID `gorm:"not null" json:"warehouse_id"`
+    Quantity    int       `gorm:"not null" json:"quantity"`
+}
+
+// BeforeCreate hook to generate a UUID for the allocation
+func (a *OrderAllocation) BeforeCreate(tx *gorm.DB) (err error) {
+    if a.ID == uuid.Nil {
+        a.ID = uuid.New()
+    }
+    return
+}
@@ -0,0 +1,44 @@
+package models
+
+// Order statuses used throughout the order lifecycle.
+const (
+    OrderStatusPending       = "Pending"
+    OrderStatusPaid          = "Paid"
+    OrderStatusPaymentFailed = "PaymentFailed"
+    OrderStatusShipped       = "Shipped"
+    OrderStatusDelivered     = "Delivered"
+    OrderStatusCanceled      = "Canceled"
+    OrderStatusRefunded      = "Refunded"
+)
+
+// orderStatusTransitions lists the statuses an order may move to from each status.
+var orderStatusTransitions = map[string][]string{
+    OrderStatusPending:       {OrderStatusPaid, OrderStatusPaymentFailed, OrderStatusCanceled},
+    OrderStatusPaymentFailed: {OrderStatusPaid, OrderStatusCanceled},
+    OrderStatusPaid:          {OrderStatusShipped, OrderStatusCanceled},
+    OrderStatusShipped:       {OrderStatusDelivered},
+    OrderStatusDelivered:     {OrderStatusRefunded},
+}
+
+// CanTransitionOrderStatus reports whether an order in status "from" may move to status "to".
+func CanTransitionOrderStatus(from, to string) bool {
+    for _, next := range orderStatusTransitions[from] {
+        if next == to {
+            return true
+        }
+    }
+    return false
+}
+
+// CanAdminSetOrderStatus reports whether an admin may move an order from
+// status "from" to "to" through the status endpoint. Paid and PaymentFailed
+// follow the order's payments, so a paid order always has a payment to
+// refund. Refunded is set only when approved returns have refunded the whole
+// order, so the provider refund and the restock always happen.
+func CanAdminSetOrderStatus(from, to string) bool {
+    switch to {
+    case OrderStatusPaid, OrderStatusPaymentFailed, OrderStatusRefunded:
+        return false
+    }
+    return CanTransitionOrderStatus(from, to)
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// OutboxEvent is a domain event recorded in the same transaction as the
+// change it describes. A background relay publishes unpublished events in
+// Position order, one at a time per aggregate, and retries until every
+// sink accepts them.
+type OutboxEvent struct {
+    ID            uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+    Position      int64      `gorm:"autoIncrement;uniqueIndex;not null" json:"position"`
+    AggregateType string     `gorm:"index:idx_outbox_events_aggregate,priority:1;not null" json:"aggregate_type"`
+    AggregateID   uuid.UUID  `gorm:"index:idx_outbox_events_aggregate,priority:2;not null" json:"aggregate_id"`
+    EventType     string     `gorm:"not null" json:"event_type"`
+    Payload       string     `gorm:"not null" json:"payload"`
+    Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+    NextAttemptAt time.Time  `gorm:"index;not null" json:"next_attempt_at"`
+    LastError     string     `json:"last_error,omitempty"`
+    PublishedAt   *time.Time `gorm:"index" json:"published_at,omitempty"`
+    CreatedAt     time.Time  `json:"created_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the outbox event
+func (e *OutboxEvent) BeforeCreate(tx *gorm.DB) (err error) {
+    if e.ID == uuid.Nil {
+        e.ID = uuid.New()
+    }
+    return
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// Payment statuses mirrored from the payment provider.
+const (
+    PaymentStatusRequiresPayment = "requires_payment"
+    PaymentStatusRequiresCapture = "requires_capture"
+    PaymentStatusSucceeded       = "succeeded"
+    PaymentStatusFailed          = "failed"
+    PaymentStatusCanceled        = "canceled"
+)
+
+// PaymentStatusIsFinal reports whether a payment in status can no longer
+// change. A failed payment can still succeed when the customer retries it.
+func PaymentStatusIsFinal(status string) bool {
+    return status == PaymentStatusSucceeded || status == PaymentStatusCanceled
+}
+
+// Payment tracks a payment intent created with the provider for an order.
+type Payment struct {
+    ID             uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+    OrderID        uuid.UUID `gorm:"index;not null" json:"order_id"`
+    Provider       string    `gorm:"not null" json:"provider"`
+    ProviderRef    string    `gorm:"uniqueIndex;not null" json:"provider_ref"`
+    Amount         float64   `gorm:"not null" json:"amount"`
+    Currency       string    `gorm:"not null" json:"currency"`
+    Status         string    `gorm:"not null;default:requires_payment" json:"status"`
+    AmountRefunded float64   `gorm:"not null;default:0" json:"amount_refunded"`
+    FailureMessage string    `json:"failure_message,omitempty"`
+    CreatedAt      time.Time `json:"created_at"`
+    UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the payment
+func (p *Payment) BeforeCreate(tx *gorm.DB) (err error) {
+    if p.ID == uuid.Nil {
+        p.ID = uuid.New()
+    }
+    return
+}
+
+// PaymentEvent records a processed provider webhook event so redeliveries are ignored.
+type PaymentEvent struct {
+    ID        string    `gorm:"primaryKey" json:"id"`
+    Provider  string    `gorm:"not null" json:"provider"`
+    Type      string    `gorm:"not null" json:"type"`
+    CreatedAt time.Time `json:"created_at"`
+}
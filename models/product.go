@@ -7,15 +7,38 @@ import (
     "gorm.io/gorm"
 )
 
+// Backorder modes control whether a product can be ordered without stock.
+const (
+    BackorderNone     = "none"
+    BackorderAllowed  = "backorder"
+    BackorderPreorder = "preorder"
+)
+
 // Product holds information about items available in the store.
 type Product struct {
-    ID          uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
-    Name        string    `gorm:"not null" json:"name"`
-    Description string    `json:"description"`
-    Price       float64   `gorm:"not null" json:"price"`
-    Stock       int       `gorm:"not null" json:"stock"`
-    CreatedAt   time.Time `json:"created_at"`
-    UpdatedAt   time.Time `json:"updated_at"`
+    ID                uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+    SKU               string     `gorm:"uniqueIndex:idx_products_sku,where:sku <> ''" json:"sku,omitempty"`
+    Name              string     `gorm:"not null" json:"name"`
+    Description       string     `json:"description"`
+    Category          string     `gorm:"index" json:"category"`
+    TaxClass          string     `gorm:"not null;default:standard" json:"tax_class"`
+    Price             float64    `gorm:"not null" json:"price"`
+    Stock             int        `gorm:"not null" json:"stock"`
+    LowStockThreshold int        `gorm:"not null;default:0" json:"low_stock_threshold"`
+    BackorderMode     string     `gorm:"not null;default:none" json:"backorder_mode"`
+    ReleaseDate       *time.Time `json:"release_date,omitempty"`
+    MaxBackorder      int        `gorm:"not null;default:0" json:"max_backorder"`
+    Backordered       int        `gorm:"not null;default:0" json:"backordered"`
+    Weight            float64    `gorm:"not null;default:0" json:"weight"`
+    Length            float64    `gorm:"not null;default:0" json:"length"`
+    Width             float64    `gorm:"not null;default:0" json:"width"`
+    Height            float64    `gorm:"not null;default:0" json:"height"`
+    RatingAverage     float64    `gorm:"not null;default:0" json:"rating_average"`
+    RatingCount       int        `gorm:"not null;default:0" json:"rating_count"`
+    RatingTotal       int        `gorm:"not null;default:0" json:"-"`
+    Version           int        `gorm:"not null;default:1" json:"version"`
+    CreatedAt         time.Time  `json:"created_at"`
+    UpdatedAt         time.Time  `json:"updated_at"`
 }
 
 // BeforeCreate hook to generate a UUID for the user
@@ -25,3 +48,28 @@ func (p *Product) BeforeCreate(tx *gorm.DB) (err error) {
     }
     return
 }
+
+// volumetricDivisor converts cubic centimetres to kilograms of dimensional weight.
+const volumetricDivisor = 5000
+
+// ShippingWeight returns the billable weight in kilograms of one unit: the
+// greater of its actual weight (kg) and its dimensional weight from its
+// length, width and height (cm).
+func (p *Product) ShippingWeight() float64 {
+    volumetric := p.Length * p.Width * p.Height / volumetricDivisor
+    if volumetric > p.Weight {
+        return volumetric
+    }
+    return p.Weight
+}
+
+// AcceptsBackorders reports whether the product can be ordered beyond its stock.
+func (p *Product) AcceptsBackorders() bool {
+    return p.BackorderMode == BackorderAllowed || p.BackorderMode == BackorderPreorder
+}
+
+// IsPreorder reports whether the product is on pre-order at the given time:
+// its mode is pre-order and its release date, if any, has not passed.
+func (p *Product) IsPreorder(at time.Time) bool {
+    return p.BackorderMode == BackorderPreorder && (p.ReleaseDate == nil || p.ReleaseDate.After(at))
+}
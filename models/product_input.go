@@ -1,9 +1,43 @@
 package models
 
+import "time"
+
 // ProductInput represents the payload for creating or updating a product.
+// MaxBackorder limits outstanding backordered units; 0 means no limit.
 type ProductInput struct {
-    Name        string  `json:"name" binding:"required"`
-    Description string  `json:"description" binding:"omitempty"`
-    Price       float64 `json:"price" binding:"required,gt=0"`
-    Stock       int     `json:"stock" binding:"required,min=0"`
+    SKU               string     `json:"sku" binding:"omitempty,max=64"`
+    Name              string     `json:"name" binding:"required"`
+    Description       string     `json:"description" binding:"omitempty"`
+    Category          string     `json:"category" binding:"omitempty"`
+    TaxClass          string     `json:"tax_class" binding:"omitempty"`
+    Price             float64    `json:"price" binding:"required,gt=0"`
+    Stock             int        `json:"stock" binding:"min=0"`
+    LowStockThreshold int        `json:"low_stock_threshold" binding:"omitempty,min=0"`
+    BackorderMode     string     `json:"backorder_mode" binding:"omitempty,oneof=none backorder preorder"`
+    ReleaseDate       *time.Time `json:"release_date" binding:"omitempty"`
+    MaxBackorder      int        `json:"max_backorder" binding:"omitempty,min=0"`
+    Weight            float64    `json:"weight" binding:"omitempty,min=0"`
+    Length            float64    `json:"length" binding:"omitempty,min=0"`
+    Width             float64    `json:"width" binding:"omitempty,min=0"`
+    Height            float64    `json:"height" binding:"omitempty,min=0"`
+}
+
+// ProductPatchInput represents a JSON Merge Patch for a product. Fields
+// left nil were not sent and keep their current value.
+type ProductPatchInput struct {
+    SKU               *string    `json:"sku" binding:"omitempty,max=64"`
+    Name              *string    `json:"name" binding:"omitempty,min=1"`
+    Description       *string    `json:"description" binding:"omitempty"`
+    Category          *string    `json:"category" binding:"omitempty"`
+    TaxClass          *string    `json:"tax_class" binding:"omitempty"`
+    Price             *float64   `json:"price" binding:"omitempty,gt=0"`
+    Stock             *int       `json:"stock" binding:"omitempty,min=0"`
+    LowStockThreshold *int       `json:"low_stock_threshold" binding:"omitempty,min=0"`
+    BackorderMode     *string    `json:"backorder_mode" binding:"omitempty,oneof=none backorder preorder"`
+    ReleaseDate       *time.Time `json:"release_date" binding:"omitempty"`
+    MaxBackorder      *int       `json:"max_backorder" binding:"omitempty,min=0"`
+    Weight            *float64   `json:"weight" binding:"omitempty,min=0"`
+    Length            *float64   `json:"length" binding:"omitempty,min=0"`
+    Width             *float64   `json:"width" binding:"omitempty,min=0"`
+    Height            *float64   `json:"height" binding:"omitempty,min=0"`
 }
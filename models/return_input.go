@@ -0,0 +1,18 @@
+package models
+
+// ReturnItemInput represents one order item and quantity being returned.
+type ReturnItemInput struct {
+    OrderItemID string `json:"order_item_id" binding:"required"`
+    Quantity    int    `json:"quantity" binding:"required,min=1"`
+}
+
+// CreateReturnInput represents the payload for opening a return request.
+type CreateReturnInput struct {
+    Reason string            `json:"reason" binding:"required"`
+    Items  []ReturnItemInput `json:"items" binding:"required,min=1,dive"`
+}
+
+// ReviewReturnInput represents the payload for approving or rejecting a return.
+type ReviewReturnInput struct {
+    Note string `json:"note" binding:"omitempty"`
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// Return request statuses.
+const (
+    ReturnStatusRequested = "Requested"
+    ReturnStatusApproved  = "Approved"
+    ReturnStatusRejected  = "Rejected"
+)
+
+// ReturnRequest is a customer's request to return items from a delivered order.
+type ReturnRequest struct {
+    ID           uuid.UUID    `gorm:"type:char(36);primaryKey" json:"id"`
+    OrderID      uuid.UUID    `gorm:"index;not null" json:"order_id"`
+    UserID       uuid.UUID    `gorm:"index;not null" json:"user_id"`
+    Items        []ReturnItem `gorm:"foreignKey:ReturnRequestID" json:"items"`
+    Reason       string       `gorm:"not null" json:"reason"`
+    Status       string       `gorm:"not null;default:Requested" json:"status"`
+    AdminNote    string       `json:"admin_note,omitempty"`
+    RefundAmount float64      `gorm:"not null;default:0" json:"refund_amount"`
+    CreatedAt    time.Time    `json:"created_at"`
+    UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the return request
+func (r *ReturnRequest) BeforeCreate(tx *gorm.DB) (err error) {
+    if r.ID == uuid.Nil {
+        r.ID = uuid.New()
+    }
+    return
+}
+
+// ReturnItem is a quantity of one order item being returned.
+type ReturnItem struct {
+    ID              uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+    ReturnRequestID uuid.UUID `gorm:"index;not null" json:"return_request_id"`
+    OrderItemID     uuid.UUID `gorm:"index;not null" json:"order_item_id"`
+    OrderItem       OrderItem `gorm:"foreignKey:OrderItemID" json:"order_item"`
+    Quantity        int       `gorm:"not null" json:"quantity"`
+}
+
+// BeforeCreate hook to generate a UUID for the return item
+func (ri *ReturnItem) BeforeCreate(tx *gorm.DB) (err error) {
+    if ri.ID == uuid.Nil {
+        ri.ID = uuid.New()
+    }
+    return
+}
+
+// Refund statuses.
+const (
+    RefundStatusPending   = "pending"
+    RefundStatusSucceeded = "succeeded"
+    RefundStatusFailed    = "failed"
+)
+
+// Refund records money returned to the customer through the payment
+// provider. A refund is recorded as pending in the same transaction as the
+// change that causes it, and only then sent to the provider, so a refund
+// the provider made is never lost to a rolled-back transaction. Pending
+// refunds are retried with backoff until they succeed or fail for good.
+type Refund struct {
+    ID              uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+    PaymentID       uuid.UUID  `gorm:"index;not null" json:"payment_id"`
+    ReturnRequestID *uuid.UUID `gorm:"index" json:"return_request_id,omitempty"`
+    ProviderRef     string     `gorm:"not null" json:"provider_ref"`
+    Amount          float64    `gorm:"not null" json:"amount"`
+    Status          string     `gorm:"index:idx_refunds_due,priority:1;not null;default:pending" json:"status"`
+    Attempts        int        `gorm:"not null;default:0" json:"attempts"`
+    LastError       string     `json:"last_error,omitempty"`
+    NextAttemptAt   time.Time  `gorm:"index:idx_refunds_due,priority:2;not null" json:"next_attempt_at"`
+    CreatedAt       time.Time  `json:"created_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the refund
+func (r *Refund) BeforeCreate(tx *gorm.DB) (err error) {
+    if r.ID == uuid.Nil {
+        r.ID = uuid.New()
+    }
+    return
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// Review statuses. Only approved reviews are public and count towards a
+// product's rating.
+const (
+    ReviewStatusPending  = "Pending"
+    ReviewStatusApproved = "Approved"
+    ReviewStatusRejected = "Rejected"
+)
+
+// Review is a customer's rating of a product they received.
+type Review struct {
+    ID        uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+    ProductID uuid.UUID `gorm:"uniqueIndex:idx_reviews_product_user;not null" json:"product_id"`
+    UserID    uuid.UUID `gorm:"uniqueIndex:idx_reviews_product_user;index;not null" json:"user_id"`
+    Rating    int       `gorm:"not null" json:"rating"`
+    Title     string    `gorm:"not null" json:"title"`
+    Body      string    `json:"body"`
+    Status    string    `gorm:"index;not null;default:Pending" json:"status"`
+    AdminNote string    `json:"admin_note,omitempty"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the review
+func (r *Review) BeforeCreate(tx *gorm.DB) (err error) {
+    if r.ID == uuid.Nil {
+        r.ID = uuid.New()
+    }
+    return
+}
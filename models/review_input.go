@@ -0,0 +1,13 @@
+package models
+
+// ReviewInput represents a review written by a customer.
+type ReviewInput struct {
+    Rating int    `json:"rating" binding:"required,min=1,max=5"`
+    Title  string `json:"title" binding:"required,max=200"`
+    Body   string `json:"body" binding:"max=5000"`
+}
+
+// ModerateReviewInput carries an optional note when an admin moderates a review.
+type ModerateReviewInput struct {
+    Note string `json:"note"`
+}
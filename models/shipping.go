@@ -0,0 +1,76 @@
+package models
+
+import (
+    "math"
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// Shipping rate types.
+const (
+    ShippingRateFlat        = "flat"
+    ShippingRateWeightBased = "weight_based"
+)
+
+// ShippingMethod is a delivery option with its rate rule. A flat method
+// charges FlatRate; a weight-based method charges BaseRate plus RatePerKg
+// for each kilogram. Orders at or above FreeOver ship free when it is set.
+type ShippingMethod struct {
+    ID        uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+    Name      string    `gorm:"not null" json:"name"`
+    Code      string    `gorm:"uniqueIndex;not null" json:"code"`
+    Carrier   string    `json:"carrier"`
+    RateType  string    `gorm:"not null" json:"rate_type"`
+    FlatRate  float64   `gorm:"not null;default:0" json:"flat_rate"`
+    BaseRate  float64   `gorm:"not null;default:0" json:"base_rate"`
+    RatePerKg float64   `gorm:"not null;default:0" json:"rate_per_kg"`
+    FreeOver  float64   `gorm:"not null;default:0" json:"free_over"`
+    Active    bool      `gorm:"not null" json:"active"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the shipping method
+func (m *ShippingMethod) BeforeCreate(tx *gorm.DB) (err error) {
+    if m.ID == uuid.Nil {
+        m.ID = uuid.New()
+    }
+    return
+}
+
+// Quote returns the shipping cost for an order subtotal and billable weight in kilograms.
+func (m *ShippingMethod) Quote(subtotal, weight float64) float64 {
+    if m.FreeOver > 0 && subtotal >= m.FreeOver {
+        return 0
+    }
+
+    var cost float64
+    switch m.RateType {
+    case ShippingRateFlat:
+        cost = m.FlatRate
+    case ShippingRateWeightBased:
+        cost = m.BaseRate + m.RatePerKg*weight
+    }
+    return math.Round(cost*100) / 100
+}
+
+// Shipment is a parcel sent for an order, with its carrier tracking details.
+type Shipment struct {
+    ID             uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+    OrderID        uuid.UUID `gorm:"index;not null" json:"order_id"`
+    Carrier        string    `gorm:"not null" json:"carrier"`
+    TrackingNumber string    `gorm:"not null" json:"tracking_number"`
+    TrackingURL    string    `json:"tracking_url,omitempty"`
+    ShippedAt      time.Time `json:"shipped_at"`
+    CreatedAt      time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the shipment
+func (s *Shipment) BeforeCreate(tx *gorm.DB) (err error) {
+    if s.ID == uuid.Nil {
+        s.ID = uuid.New()
+    }
+    return
+}
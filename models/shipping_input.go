@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// ShippingMethodInput represents the payload for creating or updating a shipping method.
+type ShippingMethodInput struct {
+    Name      string  `json:"name" binding:"required"`
+    Code      string  `json:"code" binding:"required"`
+    Carrier   string  `json:"carrier" binding:"omitempty"`
+    RateType  string  `json:"rate_type" binding:"required,oneof=flat weight_based"`
+    FlatRate  float64 `json:"flat_rate" binding:"omitempty,min=0"`
+    BaseRate  float64 `json:"base_rate" binding:"omitempty,min=0"`
+    RatePerKg float64 `json:"rate_per_kg" binding:"omitempty,min=0"`
+    FreeOver  float64 `json:"free_over" binding:"omitempty,min=0"`
+    Active    *bool   `json:"active" binding:"omitempty"`
+}
+
+// ShippingQuoteInput represents the items to quote shipping for, and the
+// coupon the order would use.
+type ShippingQuoteInput struct {
+    Items      []OrderItemInput `json:"items" binding:"required,min=1,dive"`
+    CouponCode string           `json:"coupon_code" binding:"omitempty"`
+}
+
+// CreateShipmentInput represents the payload for recording a shipment.
+type CreateShipmentInput struct {
+    Carrier        string     `json:"carrier" binding:"required"`
+    TrackingNumber string     `json:"tracking_number" binding:"required"`
+    TrackingURL    string     `json:"tracking_url" binding:"omitempty,url"`
+    ShippedAt      *time.Time `json:"shipped_at" binding:"omitempty"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// Stock movement types.
+const (
+    StockMovementReceipt        = "receipt"
+    StockMovementSale           = "sale"
+    StockMovementCancellation   = "cancellation"
+    StockMovementReturn         = "return"
+    StockMovementAdjustment     = "adjustment"
+    StockMovementReconciliation = "reconciliation"
+    StockMovementTransfer       = "transfer"
+)
+
+// StockMovement is one entry in the inventory ledger. Quantity is signed:
+// positive movements add stock and negative ones remove it. The sum of a
+// product's movements equals its stock. Transfers record one movement out
+// of the source warehouse and one into the destination.
+type StockMovement struct {
+    ID              uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+    ProductID       uuid.UUID  `gorm:"not null;index:idx_stock_movements_product_created,priority:1" json:"product_id"`
+    WarehouseID     *uuid.UUID `gorm:"index" json:"warehouse_id,omitempty"`
+    Type            string     `gorm:"not null" json:"type"`
+    Quantity        int        `gorm:"not null" json:"quantity"`
+    BalanceAfter    int        `gorm:"not null" json:"balance_after"`
+    Reason          string     `json:"reason"`
+    ActorID         *uuid.UUID `json:"actor_id,omitempty"`
+    OrderID         *uuid.UUID `gorm:"index" json:"order_id,omitempty"`
+    ReturnRequestID *uuid.UUID `json:"return_request_id,omitempty"`
+    CreatedAt       time.Time  `gorm:"index:idx_stock_movements_product_created,priority:2" json:"created_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the stock movement
+func (m *StockMovement) BeforeCreate(tx *gorm.DB) (err error) {
+    if m.ID == uuid.Nil {
+        m.ID = uuid.New()
+    }
+    return
+}
+
+// StockDiscrepancy is a product whose stock differs from its ledger balance.
+type StockDiscrepancy struct {
+    ProductID   uuid.UUID `json:"product_id"`
+    Name        string    `json:"name"`
+    Stock       int       `json:"stock"`
+    LedgerStock int       `json:"ledger_stock"`
+}
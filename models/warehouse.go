@@ -0,0 +1,47 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// Warehouse is a location that holds stock.
+type Warehouse struct {
+    ID        uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+    Name      string    `gorm:"not null" json:"name"`
+    Code      string    `gorm:"uniqueIndex;not null" json:"code"`
+    Address   Address   `gorm:"embedded;embeddedPrefix:address_" json:"address"`
+    Priority  int       `gorm:"not null;default:0" json:"priority"`
+    Active    bool      `gorm:"not null" json:"active"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the warehouse
+func (w *Warehouse) BeforeCreate(tx *gorm.DB) (err error) {
+    if w.ID == uuid.Nil {
+        w.ID = uuid.New()
+    }
+    return
+}
+
+// StockLevel is the quantity of a product held in one warehouse. A
+// product's Stock is the sum of its stock levels.
+type StockLevel struct {
+    ID          uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+    ProductID   uuid.UUID `gorm:"not null;uniqueIndex:idx_stock_levels_product_warehouse" json:"product_id"`
+    WarehouseID uuid.UUID `gorm:"not null;uniqueIndex:idx_stock_levels_product_warehouse;index" json:"warehouse_id"`
+    Warehouse   Warehouse `gorm:"foreignKey:WarehouseID" json:"warehouse"`
+    Quantity    int       `gorm:"not null;default:0" json:"quantity"`
+    UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the stock level
+func (l *StockLevel) BeforeCreate(tx *gorm.DB) (err error) {
+    if l.ID == uuid.Nil {
+        l.ID = uuid.New()
+    }
+    return
+}
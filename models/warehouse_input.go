@@ -0,0 +1,20 @@
+package models
+
+// WarehouseInput represents the payload for creating or updating a warehouse.
+// Lower priorities are allocated from first.
+type WarehouseInput struct {
+    Name     string       `json:"name" binding:"required"`
+    Code     string       `json:"code" binding:"required"`
+    Address  AddressInput `json:"address" binding:"required"`
+    Priority int          `json:"priority" binding:"omitempty,min=0"`
+    Active   *bool        `json:"active" binding:"omitempty"`
+}
+
+// StockTransferInput represents moving stock between two warehouses.
+type StockTransferInput struct {
+    ProductID       string `json:"product_id" binding:"required,uuid"`
+    FromWarehouseID string `json:"from_warehouse_id" binding:"required,uuid"`
+    ToWarehouseID   string `json:"to_warehouse_id" binding:"required,uuid,nefield=FromWarehouseID"`
+    Quantity        int    `json:"quantity" binding:"required,min=1"`
+    Reason          string `json:"reason" binding:"omitempty"`
+}
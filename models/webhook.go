@@ -0,0 +1,92 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// Webhook delivery statuses.
+const (
+    WebhookDeliveryPending   = "pending"
+    WebhookDeliverySucceeded = "succeeded"
+    WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookSubscription sends the selected event types to a URL. The secret
+// signs every delivery; it is only shown when the subscription is created.
+type WebhookSubscription struct {
+    ID          uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+    URL         string    `gorm:"not null" json:"url"`
+    Description string    `json:"description"`
+    EventTypes  []string  `gorm:"serializer:json;not null" json:"event_types"`
+    Secret      string    `gorm:"not null" json:"secret,omitempty"`
+    Active      bool      `gorm:"not null" json:"active"`
+    CreatedAt   time.Time `json:"created_at"`
+    UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the webhook subscription
+func (w *WebhookSubscription) BeforeCreate(tx *gorm.DB) (err error) {
+    if w.ID == uuid.Nil {
+        w.ID = uuid.New()
+    }
+    return
+}
+
+// Subscribes reports whether the subscription receives eventType.
+func (w *WebhookSubscription) Subscribes(eventType string) bool {
+    for _, t := range w.EventTypes {
+        if t == eventType {
+            return true
+        }
+    }
+    return false
+}
+
+// WebhookDelivery is one event queued for one subscription. The table is
+// the delivery queue: pending deliveries are sent by a background worker
+// and retried with backoff until they succeed or fail for good.
+type WebhookDelivery struct {
+    ID             uuid.UUID        `gorm:"type:char(36);primaryKey" json:"id"`
+    SubscriptionID uuid.UUID        `gorm:"uniqueIndex:idx_webhook_deliveries_event,priority:1;not null" json:"subscription_id"`
+    EventID        uuid.UUID        `gorm:"uniqueIndex:idx_webhook_deliveries_event,priority:2;not null" json:"event_id"`
+    EventType      string           `gorm:"not null" json:"event_type"`
+    Payload        string           `gorm:"not null" json:"payload"`
+    Status         string           `gorm:"index:idx_webhook_deliveries_due,priority:1;not null;default:pending" json:"status"`
+    Attempts       int              `gorm:"not null;default:0" json:"attempts"`
+    NextAttemptAt  time.Time        `gorm:"index:idx_webhook_deliveries_due,priority:2;not null" json:"next_attempt_at"`
+    LastStatusCode int              `json:"last_status_code,omitempty"`
+    LastError      string           `json:"last_error,omitempty"`
+    DeliveredAt    *time.Time       `json:"delivered_at,omitempty"`
+    AttemptLog     []WebhookAttempt `gorm:"foreignKey:DeliveryID" json:"attempt_log,omitempty"`
+    CreatedAt      time.Time        `json:"created_at"`
+    UpdatedAt      time.Time        `json:"updated_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the webhook delivery
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) (err error) {
+    if d.ID == uuid.Nil {
+        d.ID = uuid.New()
+    }
+    return
+}
+
+// WebhookAttempt logs one HTTP request made for a delivery.
+type WebhookAttempt struct {
+    ID         uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+    DeliveryID uuid.UUID `gorm:"index;not null" json:"delivery_id"`
+    StatusCode int       `json:"status_code,omitempty"`
+    Error      string    `json:"error,omitempty"`
+    DurationMS int64     `json:"duration_ms"`
+    CreatedAt  time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the webhook attempt
+func (a *WebhookAttempt) BeforeCreate(tx *gorm.DB) (err error) {
+    if a.ID == uuid.Nil {
+        a.ID = uuid.New()
+    }
+    return
+}
@@ -0,0 +1,11 @@
+package models
+
+// WebhookInput represents the payload for creating or updating a webhook
+// subscription. A secret is generated when none is given.
+type WebhookInput struct {
+    URL         string   `json:"url" binding:"required,url,max=2048"`
+    Description string   `json:"description"`
+    EventTypes  []string `json:"event_types" binding:"required,min=1,dive,oneof=order.created order.status_changed product.updated stock.low"`
+    Secret      string   `json:"secret" binding:"omitempty,min=16"`
+    Active      *bool    `json:"active"`
+}
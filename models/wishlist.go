@@ -0,0 +1,44 @@
+package models
+
+import (
+    "time"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// WishlistItem is a product a user has saved for later.
+type WishlistItem struct {
+    ID        uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
+    UserID    uuid.UUID `gorm:"type:char(36);uniqueIndex:idx_wishlist_items_user_product;not null" json:"user_id"`
+    ProductID uuid.UUID `gorm:"uniqueIndex:idx_wishlist_items_user_product;not null" json:"product_id"`
+    Product   Product   `gorm:"foreignKey:ProductID" json:"product"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the wishlist item
+func (w *WishlistItem) BeforeCreate(tx *gorm.DB) (err error) {
+    if w.ID == uuid.Nil {
+        w.ID = uuid.New()
+    }
+    return
+}
+
+// StockAlert asks for a notification when an out-of-stock product is
+// restocked. It fires once: NotifiedAt is set when the notification is
+// queued, and the user can subscribe again afterwards.
+type StockAlert struct {
+    ID         uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+    UserID     uuid.UUID  `gorm:"type:char(36);uniqueIndex:idx_stock_alerts_user_product,where:notified_at IS NULL;not null" json:"user_id"`
+    ProductID  uuid.UUID  `gorm:"type:char(36);uniqueIndex:idx_stock_alerts_user_product,where:notified_at IS NULL;index;not null" json:"product_id"`
+    NotifiedAt *time.Time `json:"notified_at,omitempty"`
+    CreatedAt  time.Time  `json:"created_at"`
+}
+
+// BeforeCreate hook to generate a UUID for the stock alert
+func (a *StockAlert) BeforeCreate(tx *gorm.DB) (err error) {
+    if a.ID == uuid.Nil {
+        a.ID = uuid.New()
+    }
+    return
+}
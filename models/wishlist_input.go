@@ -0,0 +1,6 @@
+package models
+
+// WishlistInput represents a product being added to a wishlist.
+type WishlistInput struct {
+    ProductID string `json:"product_id" binding:"required,uuid"`
+}
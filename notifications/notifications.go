@@ -0,0 +1,57 @@
+// Package notifications queues messages to users and delivers them in the
+// background through a pluggable sender. Notifications are stored in the
+// database in the same transaction as the change that causes them, so a
+// message is only sent if that change commits and survives restarts.
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/TobiAdeniji94/ecommerce_api/config"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// Message is a message to one recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers messages to users.
+type Sender interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender returns the sender named in cfg.
+func NewSender(cfg config.NotificationsConfig) (Sender, error) {
+	switch cfg.Sender {
+	case "smtp":
+		return NewSMTP(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom), nil
+	case "", "log":
+		slog.Info("Notifications are written to the log")
+		return LogSender{}, nil
+	default:
+		return nil, fmt.Errorf("unknown notification sender %q", cfg.Sender)
+	}
+}
+
+// Enqueue queues a notification to a user. Pass the transaction making the
+// change the notification is about, so it is only sent if that commits.
+func Enqueue(tx *gorm.DB, userID uuid.UUID, notificationType, subject, body string) error {
+	return tx.Create(&models.Notification{
+		UserID:        userID,
+		Type:          notificationType,
+		Subject:       subject,
+		Body:          body,
+		Status:        models.NotificationStatusPending,
+		NextAttemptAt: time.Now(),
+	}).Error
+}
@@ -0,0 +1,60 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/smtp"
+	"strconv"
+	"strings"
+)
+
+// LogSender writes messages to the server log instead of sending them. It
+// is the default, for local development.
+type LogSender struct{}
+
+// Name identifies the sender.
+func (LogSender) Name() string {
+	return "log"
+}
+
+// Send logs the message.
+func (LogSender) Send(ctx context.Context, msg Message) error {
+	slog.InfoContext(ctx, "Notification", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// SMTP sends messages as plain-text email through an SMTP server.
+type SMTP struct {
+	Addr string
+	Auth smtp.Auth
+	From string
+}
+
+// NewSMTP creates an SMTP sender. Username and password are optional; when
+// set, the server must support STARTTLS.
+func NewSMTP(host string, port int, username, password, from string) *SMTP {
+	s := &SMTP{Addr: host + ":" + strconv.Itoa(port), From: from}
+	if username != "" {
+		s.Auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+// Name identifies the sender.
+func (s *SMTP) Name() string {
+	return "smtp"
+}
+
+// Send emails the message.
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Header values must not contain line breaks
+	clean := strings.NewReplacer("\r", "", "\n", " ")
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		clean.Replace(s.From), clean.Replace(msg.To), clean.Replace(msg.Subject), msg.Body)
+	return smtp.SendMail(s.Addr, s.Auth, s.From, []string{msg.To}, []byte(body))
+}
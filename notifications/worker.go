@@ -0,0 +1,129 @@
+package notifications
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+const (
+	// defaultPollInterval applies when the worker has no PollInterval.
+	defaultPollInterval = 5 * time.Second
+	// batchSize is the number of notifications claimed per transaction.
+	batchSize = 20
+	// MaxAttempts is how often a notification is tried before it fails.
+	MaxAttempts = 5
+	// baseRetryDelay doubles after every failed attempt, up to maxRetryDelay.
+	baseRetryDelay = 30 * time.Second
+	maxRetryDelay  = time.Hour
+)
+
+// Worker delivers queued notifications through Sender.
+type Worker struct {
+	DB           *gorm.DB
+	Sender       Sender
+	PollInterval time.Duration
+}
+
+// Run delivers queued notifications until ctx is canceled.
+func (w *Worker) Run(ctx context.Context) {
+	pollInterval := w.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while full batches come back, then wait for more
+		for {
+			processed, err := w.DeliverDue(ctx)
+			if err != nil {
+				slog.Error("Failed to deliver notifications", "error", err)
+				break
+			}
+			if processed < batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue sends a batch of due notifications and returns how many were
+// attempted. Notifications are claimed with SKIP LOCKED, so several API
+// instances can run workers without sending the same one twice.
+func (w *Worker) DeliverDue(ctx context.Context) (int, error) {
+	processed := 0
+	err := w.DB.Transaction(func(tx *gorm.DB) error {
+		var due []models.Notification
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.NotificationStatusPending, time.Now()).
+			Order("next_attempt_at").Limit(batchSize).Find(&due).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range due {
+			// Leave the rest pending when shutting down
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err := w.deliver(ctx, tx, &due[i]); err != nil {
+				return err
+			}
+			processed++
+		}
+		return nil
+	})
+	return processed, err
+}
+
+// deliver sends one notification and records the outcome.
+func (w *Worker) deliver(ctx context.Context, tx *gorm.DB, notification *models.Notification) error {
+	var user models.User
+	err := tx.Select("email").First(&user, "id = ?", notification.UserID).Error
+	if err == nil {
+		err = w.Sender.Send(ctx, Message{To: user.Email, Subject: notification.Subject, Body: notification.Body})
+	}
+
+	now := time.Now()
+	notification.Attempts++
+	updates := map[string]interface{}{"attempts": notification.Attempts}
+	switch {
+	case err == nil:
+		notification.Status = models.NotificationStatusSent
+		notification.SentAt = &now
+		updates["sent_at"] = now
+		updates["last_error"] = ""
+	case notification.Attempts >= MaxAttempts:
+		notification.Status = models.NotificationStatusFailed
+		updates["last_error"] = err.Error()
+		slog.Error("Notification failed", "notification_id", notification.ID, "attempts", notification.Attempts, "error", err)
+	default:
+		updates["next_attempt_at"] = now.Add(retryDelay(notification.Attempts))
+		updates["last_error"] = err.Error()
+	}
+	updates["status"] = notification.Status
+	return tx.Model(notification).Updates(updates).Error
+}
+
+// retryDelay returns how long to wait after the given number of failed
+// attempts.
+func retryDelay(attempts int) time.Duration {
+	delay := baseRetryDelay << (attempts - 1)
+	if delay > maxRetryDelay || delay <= 0 {
+		return maxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,69 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Handler consumes events from the in-process bus. Returning an error makes
+// the relay publish the event again later, to every handler.
+type Handler func(ctx context.Context, msg Message) error
+
+// txKey is the context key of the relay's transaction.
+type txKey struct{}
+
+// Tx returns the transaction the relay is publishing an event in, if any.
+// Handlers that write to the database should write in it: their writes
+// then commit together with the event being marked as published, and they
+// need no second connection, which SQLite does not have.
+func Tx(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(txKey{}).(*gorm.DB)
+	return tx, ok
+}
+
+// withTx returns a copy of ctx carrying tx for Tx.
+func withTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// Bus delivers events to handlers in this process.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewBus returns an empty bus.
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe calls handler for every event of eventType, or for every event
+// when eventType is "*".
+func (b *Bus) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Name identifies the sink.
+func (b *Bus) Name() string {
+	return "bus"
+}
+
+// Publish calls the event's handlers in the order they subscribed and
+// stops at the first error.
+func (b *Bus) Publish(ctx context.Context, msg Message) error {
+	b.mu.RLock()
+	handlers := append(append([]Handler(nil), b.handlers[msg.Type]...), b.handlers["*"]...)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, msg); err != nil {
+			return fmt.Errorf("%s handler: %w", msg.Type, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package outbox
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// Aggregate types that events are recorded against. Events for the same
+// aggregate are published in the order they were recorded.
+const (
+	AggregateOrder   = "order"
+	AggregateProduct = "product"
+)
+
+// Event types.
+const (
+	EventOrderCreated       = "order.created"
+	EventOrderStatusChanged = "order.status_changed"
+	EventProductUpdated     = "product.updated"
+	EventStockLow           = "stock.low"
+)
+
+// OrderData is the data of order events.
+type OrderData struct {
+	ID              uuid.UUID       `json:"id"`
+	UserID          uuid.UUID       `json:"user_id"`
+	Status          string          `json:"status"`
+	PreviousStatus  string          `json:"previous_status,omitempty"`
+	Total           float64         `json:"total"`
+	Items           []OrderItemData `json:"items,omitempty"`
+	ShippingAddress models.Address  `json:"shipping_address"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+// OrderItemData is an order line in OrderData.
+type OrderItemData struct {
+	ProductID   uuid.UUID `json:"product_id"`
+	Quantity    int       `json:"quantity"`
+	UnitPrice   float64   `json:"unit_price"`
+	Backordered int       `json:"backordered"`
+}
+
+// NewOrderData builds the event data for an order. Items are included
+// when they are loaded.
+func NewOrderData(order *models.Order, previousStatus string) OrderData {
+	data := OrderData{
+		ID:              order.ID,
+		UserID:          order.UserID,
+		Status:          order.Status,
+		PreviousStatus:  previousStatus,
+		Total:           order.Total,
+		ShippingAddress: order.ShippingAddress,
+		CreatedAt:       order.CreatedAt,
+	}
+	for _, item := range order.Items {
+		data.Items = append(data.Items, OrderItemData{
+			ProductID:   item.ProductID,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Backordered: item.BackorderedQuantity,
+		})
+	}
+	return data
+}
+
+// StockData is the data of stock.low events.
+type StockData struct {
+	ProductID uuid.UUID `json:"product_id"`
+	SKU       string    `json:"sku,omitempty"`
+	Name      string    `json:"name"`
+	Stock     int       `json:"stock"`
+	Threshold int       `json:"threshold"`
+}
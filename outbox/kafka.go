@@ -0,0 +1,86 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Kafka publishes events to a Kafka topic through a Confluent-compatible
+// REST Proxy (API v2). Records are keyed by aggregate ID, so the events of
+// one aggregate land on the same partition in order.
+type Kafka struct {
+	BaseURL    string
+	Topic      string
+	HTTPClient *http.Client
+}
+
+// NewKafka creates a sink for the REST Proxy at baseURL.
+func NewKafka(baseURL, topic string) *Kafka {
+	return &Kafka{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Topic:      topic,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name identifies the sink.
+func (k *Kafka) Name() string {
+	return "kafka"
+}
+
+// kafkaRecord is a record in a REST Proxy produce request.
+type kafkaRecord struct {
+	Key   string  `json:"key"`
+	Value Message `json:"value"`
+}
+
+// Publish produces the event as a JSON record and waits for the proxy to
+// report the offset it was written at.
+func (k *Kafka) Publish(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(map[string][]kafkaRecord{
+		"records": {{Key: msg.AggregateID.String(), Value: msg}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.BaseURL+"/topics/"+url.PathEscape(k.Topic), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := k.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kafka: %s (status %d)", strings.TrimSpace(string(detail)), resp.StatusCode)
+	}
+
+	var result struct {
+		Offsets []struct {
+			Error *string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	for _, offset := range result.Offsets {
+		if offset.Error != nil && *offset.Error != "" {
+			return fmt.Errorf("kafka: %s", *offset.Error)
+		}
+	}
+	return nil
+}
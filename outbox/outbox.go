@@ -0,0 +1,79 @@
+// Package outbox publishes domain events reliably. Events are recorded in
+// an outbox table in the same transaction as the change they describe, so
+// an event exists exactly when its change commits. A background relay
+// publishes them to the in-process bus and any configured external sinks
+// (NATS, Kafka), retrying until all accept them. Delivery is at least once,
+// and events of one aggregate are published in the order they were
+// recorded.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/TobiAdeniji94/ecommerce_api/config"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+// Message is a published event. Consumers that must not act twice should
+// remember the IDs they have handled.
+type Message struct {
+	ID            uuid.UUID       `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   uuid.UUID       `json:"aggregate_id"`
+	Data          json.RawMessage `json:"data"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// Sink publishes events to a consumer.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, msg Message) error
+}
+
+// NewSinks returns the external sinks named in cfg.
+func NewSinks(cfg config.OutboxConfig) ([]Sink, error) {
+	var sinks []Sink
+	for _, name := range cfg.Sinks {
+		switch name {
+		case "nats":
+			sink, err := NewNATS(cfg.NATSURL, cfg.NATSSubjectPrefix)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		case "kafka":
+			sinks = append(sinks, NewKafka(cfg.KafkaRESTURL, cfg.KafkaTopic))
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q", name)
+		}
+		slog.Info("Outbox events are published", "sink", name)
+	}
+	return sinks, nil
+}
+
+// Record stores an event about an aggregate in the outbox. Pass the
+// transaction making the change, so the event is only published if that
+// commits.
+func Record(tx *gorm.DB, aggregateType string, aggregateID uuid.UUID, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	event := models.OutboxEvent{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       string(payload),
+		NextAttemptAt: time.Now(),
+	}
+	return tx.Create(&event).Error
+}
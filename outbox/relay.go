@@ -0,0 +1,168 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+const (
+	// defaultPollInterval applies when the relay has no PollInterval.
+	defaultPollInterval = time.Second
+	// batchSize is the number of events claimed per transaction.
+	batchSize = 50
+	// baseRetryDelay doubles after every failed attempt, up to maxRetryDelay.
+	// Events are never dropped, so a failing event holds back later events
+	// of its aggregate until a sink accepts it.
+	baseRetryDelay = 5 * time.Second
+	maxRetryDelay  = 5 * time.Minute
+	// retention is how long published events are kept before they are
+	// pruned, checked every pruneInterval.
+	retention     = 7 * 24 * time.Hour
+	pruneInterval = time.Hour
+)
+
+// Relay publishes recorded events to Bus first, then to each of Sinks.
+type Relay struct {
+	DB           *gorm.DB
+	Bus          *Bus
+	Sinks        []Sink
+	PollInterval time.Duration
+}
+
+// Run publishes recorded events until ctx is canceled.
+func (r *Relay) Run(ctx context.Context) {
+	pollInterval := r.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		// Keep going while full batches come back, then wait for more
+		for {
+			processed, err := r.RelayDue(ctx)
+			if err != nil {
+				slog.Error("Failed to relay outbox events", "error", err)
+				break
+			}
+			if processed < batchSize {
+				break
+			}
+		}
+
+		if time.Since(pruned) >= pruneInterval {
+			if err := Prune(r.DB, time.Now().Add(-retention)); err != nil {
+				slog.Error("Failed to prune outbox events", "error", err)
+			}
+			pruned = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayDue publishes a batch of due events and returns how many were
+// attempted. Only the oldest unpublished event of each aggregate is due, and
+// events are claimed with SKIP LOCKED, so several API instances can run the
+// relay without breaking the order of an aggregate's events.
+func (r *Relay) RelayDue(ctx context.Context) (int, error) {
+	processed := 0
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var due []models.OutboxEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL AND next_attempt_at <= ?", time.Now()).
+			Where(`NOT EXISTS (SELECT 1 FROM outbox_events earlier
+				WHERE earlier.aggregate_type = outbox_events.aggregate_type
+				AND earlier.aggregate_id = outbox_events.aggregate_id
+				AND earlier.published_at IS NULL
+				AND earlier.position < outbox_events.position)`).
+			Order("position").Limit(batchSize).Find(&due).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range due {
+			// Leave the rest unpublished when shutting down
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err := r.relay(ctx, tx, &due[i]); err != nil {
+				return err
+			}
+			processed++
+		}
+		return nil
+	})
+	return processed, err
+}
+
+// relay publishes one event to every sink and records the outcome. A sink
+// failure retries the event on all sinks, which is why delivery is at least
+// once.
+func (r *Relay) relay(ctx context.Context, tx *gorm.DB, event *models.OutboxEvent) error {
+	msg := Message{
+		ID:            event.ID,
+		Type:          event.EventType,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		Data:          json.RawMessage(event.Payload),
+		CreatedAt:     event.CreatedAt,
+	}
+
+	sinks := r.Sinks
+	if r.Bus != nil {
+		sinks = append([]Sink{r.Bus}, sinks...)
+	}
+
+	var err error
+	publishCtx := withTx(ctx, tx)
+	for _, sink := range sinks {
+		if err = sink.Publish(publishCtx, msg); err != nil {
+			err = fmt.Errorf("%s: %w", sink.Name(), err)
+			break
+		}
+	}
+
+	now := time.Now()
+	event.Attempts++
+	updates := map[string]interface{}{"attempts": event.Attempts}
+	if err == nil {
+		event.PublishedAt = &now
+		updates["published_at"] = now
+		updates["last_error"] = ""
+	} else {
+		updates["next_attempt_at"] = now.Add(retryDelay(event.Attempts))
+		updates["last_error"] = err.Error()
+		slog.Warn("Outbox event failed", "event_id", event.ID, "event_type", event.EventType, "attempts", event.Attempts, "error", err)
+	}
+	return tx.Model(event).Updates(updates).Error
+}
+
+// Prune deletes events published before cutoff.
+func Prune(db *gorm.DB, cutoff time.Time) error {
+	return db.Where("published_at < ?", cutoff).Delete(&models.OutboxEvent{}).Error
+}
+
+// retryDelay returns how long to wait after the given number of failed
+// attempts.
+func retryDelay(attempts int) time.Duration {
+	delay := baseRetryDelay << (attempts - 1)
+	if delay > maxRetryDelay || delay <= 0 {
+		return maxRetryDelay
+	}
+	return delay
+}
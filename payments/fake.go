@@ -0,0 +1,179 @@
+package payments
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Fake is an in-process provider for local development and tests. Intents
+// are authorized immediately and succeed once captured.
+type Fake struct {
+	WebhookSecret string
+
+	mu      sync.Mutex
+	intents map[string]*Intent
+	refunds map[string]int64
+	keys    map[string]string
+	// refundKeys holds refunds by idempotency key.
+	refundKeys map[string]*Refund
+	// refundErr is returned by Refund while set.
+	refundErr error
+}
+
+// NewFake creates a fake provider that signs and verifies webhooks with
+// secret. With no secret, every webhook is rejected.
+func NewFake(webhookSecret string) *Fake {
+	return &Fake{
+		WebhookSecret: webhookSecret,
+		intents:       make(map[string]*Intent),
+		refunds:       make(map[string]int64),
+		keys:          make(map[string]string),
+		refundKeys:    make(map[string]*Refund),
+	}
+}
+
+// Name identifies the provider on stored payments.
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+// CreateIntent creates an authorized intent awaiting capture.
+func (f *Fake) CreateIntent(ctx context.Context, params CreateIntentParams) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if id, ok := f.keys[params.IdempotencyKey]; ok && params.IdempotencyKey != "" {
+		intent := *f.intents[id]
+		return &intent, nil
+	}
+
+	id := "pi_fake_" + randomID()
+	intent := &Intent{
+		ID:           id,
+		ClientSecret: id + "_secret",
+		Amount:       params.Amount,
+		Currency:     params.Currency,
+		Status:       IntentRequiresCapture,
+	}
+	f.intents[id] = intent
+	if params.IdempotencyKey != "" {
+		f.keys[params.IdempotencyKey] = id
+	}
+
+	copied := *intent
+	return &copied, nil
+}
+
+// Capture marks an authorized intent as succeeded.
+func (f *Fake) Capture(ctx context.Context, intentID string) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[intentID]
+	if !ok {
+		return nil, fmt.Errorf("fake: no such payment intent %q", intentID)
+	}
+	if intent.Status != IntentRequiresCapture {
+		return nil, fmt.Errorf("fake: payment intent %q cannot be captured in status %s", intentID, intent.Status)
+	}
+	intent.Status = IntentSucceeded
+
+	copied := *intent
+	return &copied, nil
+}
+
+// Cancel marks an intent that has not been captured as canceled.
+func (f *Fake) Cancel(ctx context.Context, intentID string) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[intentID]
+	if !ok {
+		return nil, fmt.Errorf("fake: no such payment intent %q", intentID)
+	}
+	if intent.Status == IntentSucceeded {
+		return nil, fmt.Errorf("fake: payment intent %q has been captured", intentID)
+	}
+	intent.Status = IntentCanceled
+
+	copied := *intent
+	return &copied, nil
+}
+
+// FailRefunds makes Refund return err until it is called with nil, to
+// simulate a provider outage.
+func (f *Fake) FailRefunds(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.refundErr = err
+}
+
+// Refund refunds part or all of a captured intent.
+func (f *Fake) Refund(ctx context.Context, params RefundParams) (*Refund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.refundErr != nil {
+		return nil, f.refundErr
+	}
+	if refund, ok := f.refundKeys[params.IdempotencyKey]; ok && params.IdempotencyKey != "" {
+		copied := *refund
+		return &copied, nil
+	}
+
+	intentID, amount := params.IntentID, params.Amount
+	intent, ok := f.intents[intentID]
+	if !ok {
+		return nil, fmt.Errorf("fake: no such payment intent %q", intentID)
+	}
+	if intent.Status != IntentSucceeded {
+		return nil, fmt.Errorf("fake: payment intent %q has not been captured", intentID)
+	}
+	if amount <= 0 || f.refunds[intentID]+amount > intent.Amount {
+		return nil, fmt.Errorf("fake: refund of %d exceeds the refundable amount", amount)
+	}
+	f.refunds[intentID] += amount
+
+	refund := &Refund{ID: "re_fake_" + randomID(), IntentID: intentID, Amount: amount, Status: "succeeded"}
+	if params.IdempotencyKey != "" {
+		f.refundKeys[params.IdempotencyKey] = refund
+	}
+	copied := *refund
+	return &copied, nil
+}
+
+// ParseWebhook verifies and decodes an event signed with the fake's secret.
+func (f *Fake) ParseWebhook(payload []byte, signatureHeader string) (*Event, error) {
+	if err := verifySignature(payload, signatureHeader, f.WebhookSecret, time.Now()); err != nil {
+		return nil, err
+	}
+	return parseEvent(payload)
+}
+
+// SignedEvent builds a Stripe-shaped webhook payload for intentID and its
+// signature header, as the provider would deliver it.
+func (f *Fake) SignedEvent(eventID, eventType, intentID string) (payload []byte, signature string) {
+	payload, _ = json.Marshal(map[string]interface{}{
+		"id":   eventID,
+		"type": eventType,
+		"data": map[string]interface{}{
+			"object": map[string]interface{}{"id": intentID},
+		},
+	})
+	return payload, SignPayload(payload, f.WebhookSecret, time.Now())
+}
+
+// randomID returns a random hex ID, so fake intents cannot be guessed and
+// paid for with a forged webhook.
+func randomID() string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
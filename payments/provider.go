@@ -0,0 +1,111 @@
+// Package payments abstracts the payment provider used to charge orders.
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+
+	"github.com/TobiAdeniji94/ecommerce_api/config"
+)
+
+// Intent statuses reported by providers.
+const (
+	IntentRequiresPaymentMethod = "requires_payment_method"
+	IntentRequiresCapture       = "requires_capture"
+	IntentSucceeded             = "succeeded"
+	IntentCanceled              = "canceled"
+)
+
+// Webhook event types the API reacts to.
+const (
+	EventPaymentSucceeded = "payment_intent.succeeded"
+	EventPaymentFailed    = "payment_intent.payment_failed"
+)
+
+// ErrInvalidSignature is returned when a webhook payload fails signature verification.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Intent is a provider-side payment intent. Amounts are in minor units (e.g. cents).
+type Intent struct {
+	ID           string
+	ClientSecret string
+	Amount       int64
+	Currency     string
+	Status       string
+}
+
+// CreateIntentParams describes the payment intent to create for an order.
+type CreateIntentParams struct {
+	OrderID        string
+	Amount         int64
+	Currency       string
+	IdempotencyKey string
+}
+
+// RefundParams describes a refund of a captured intent. Retries with the
+// same IdempotencyKey return the first refund instead of refunding again.
+type RefundParams struct {
+	IntentID       string
+	Amount         int64
+	IdempotencyKey string
+}
+
+// Refund is a provider-side refund against a captured intent.
+type Refund struct {
+	ID       string
+	IntentID string
+	Amount   int64
+	Status   string
+}
+
+// Event is a verified webhook event.
+type Event struct {
+	ID             string
+	Type           string
+	IntentID       string
+	FailureMessage string
+}
+
+// Provider creates, captures, cancels and refunds payments and verifies
+// provider webhooks.
+type Provider interface {
+	Name() string
+	CreateIntent(ctx context.Context, params CreateIntentParams) (*Intent, error)
+	Capture(ctx context.Context, intentID string) (*Intent, error)
+	// Cancel voids an intent that has not been captured, so it can no
+	// longer be paid or captured.
+	Cancel(ctx context.Context, intentID string) (*Intent, error)
+	Refund(ctx context.Context, params RefundParams) (*Refund, error)
+	ParseWebhook(payload []byte, signatureHeader string) (*Event, error)
+}
+
+// New returns the provider named in cfg.
+func New(cfg config.PaymentsConfig) (Provider, error) {
+	switch cfg.Provider {
+	case "stripe":
+		stripe := NewStripe(cfg.StripeAPIKey, cfg.StripeWebhookSecret)
+		if cfg.StripeAPIURL != "" {
+			stripe.BaseURL = strings.TrimRight(cfg.StripeAPIURL, "/")
+		}
+		return stripe, nil
+	case "", "fake":
+		slog.Info("Using the in-process fake payment provider")
+		return NewFake(cfg.WebhookSecret), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", cfg.Provider)
+	}
+}
+
+// ToMinorUnits converts a decimal amount to minor units.
+func ToMinorUnits(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// FromMinorUnits converts minor units to a decimal amount.
+func FromMinorUnits(amount int64) float64 {
+	return float64(amount) / 100
+}
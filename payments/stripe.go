@@ -0,0 +1,171 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stripe talks to the Stripe API, or any server compatible with it, over HTTP.
+type Stripe struct {
+	APIKey        string
+	WebhookSecret string
+	BaseURL       string
+	HTTPClient    *http.Client
+}
+
+// NewStripe creates a Stripe provider for the public Stripe API.
+func NewStripe(apiKey, webhookSecret string) *Stripe {
+	return &Stripe{
+		APIKey:        apiKey,
+		WebhookSecret: webhookSecret,
+		BaseURL:       "https://api.stripe.com",
+		HTTPClient:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Name identifies the provider on stored payments.
+func (s *Stripe) Name() string {
+	return "stripe"
+}
+
+// stripeIntent is the subset of a Stripe PaymentIntent the API uses.
+type stripeIntent struct {
+	ID               string `json:"id"`
+	ClientSecret     string `json:"client_secret"`
+	Amount           int64  `json:"amount"`
+	Currency         string `json:"currency"`
+	Status           string `json:"status"`
+	LastPaymentError *struct {
+		Message string `json:"message"`
+	} `json:"last_payment_error"`
+}
+
+func (i stripeIntent) toIntent() *Intent {
+	return &Intent{
+		ID:           i.ID,
+		ClientSecret: i.ClientSecret,
+		Amount:       i.Amount,
+		Currency:     i.Currency,
+		Status:       i.Status,
+	}
+}
+
+// CreateIntent creates a manually captured PaymentIntent.
+func (s *Stripe) CreateIntent(ctx context.Context, params CreateIntentParams) (*Intent, error) {
+	form := url.Values{}
+	form.Set("amount", strconv.FormatInt(params.Amount, 10))
+	form.Set("currency", params.Currency)
+	form.Set("capture_method", "manual")
+	form.Set("metadata[order_id]", params.OrderID)
+
+	var intent stripeIntent
+	if err := s.post(ctx, "/v1/payment_intents", form, params.IdempotencyKey, &intent); err != nil {
+		return nil, err
+	}
+	return intent.toIntent(), nil
+}
+
+// Capture captures a previously authorized PaymentIntent.
+func (s *Stripe) Capture(ctx context.Context, intentID string) (*Intent, error) {
+	var intent stripeIntent
+	path := "/v1/payment_intents/" + url.PathEscape(intentID) + "/capture"
+	if err := s.post(ctx, path, url.Values{}, "", &intent); err != nil {
+		return nil, err
+	}
+	return intent.toIntent(), nil
+}
+
+// Cancel cancels a PaymentIntent that has not been captured.
+func (s *Stripe) Cancel(ctx context.Context, intentID string) (*Intent, error) {
+	var intent stripeIntent
+	path := "/v1/payment_intents/" + url.PathEscape(intentID) + "/cancel"
+	if err := s.post(ctx, path, url.Values{}, "", &intent); err != nil {
+		return nil, err
+	}
+	return intent.toIntent(), nil
+}
+
+// Refund refunds params.Amount (in minor units) of a captured PaymentIntent.
+func (s *Stripe) Refund(ctx context.Context, params RefundParams) (*Refund, error) {
+	form := url.Values{}
+	form.Set("payment_intent", params.IntentID)
+	form.Set("amount", strconv.FormatInt(params.Amount, 10))
+
+	var refund struct {
+		ID            string `json:"id"`
+		PaymentIntent string `json:"payment_intent"`
+		Amount        int64  `json:"amount"`
+		Status        string `json:"status"`
+	}
+	if err := s.post(ctx, "/v1/refunds", form, params.IdempotencyKey, &refund); err != nil {
+		return nil, err
+	}
+	return &Refund{ID: refund.ID, IntentID: refund.PaymentIntent, Amount: refund.Amount, Status: refund.Status}, nil
+}
+
+// ParseWebhook verifies the Stripe-Signature header and decodes the event.
+func (s *Stripe) ParseWebhook(payload []byte, signatureHeader string) (*Event, error) {
+	if err := verifySignature(payload, signatureHeader, s.WebhookSecret, time.Now()); err != nil {
+		return nil, err
+	}
+	return parseEvent(payload)
+}
+
+// post sends a form-encoded request and decodes the JSON response into out.
+func (s *Stripe) post(ctx context.Context, path string, form url.Values, idempotencyKey string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.BaseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.APIKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("stripe: %s (status %d)", apiErr.Error.Message, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// parseEvent decodes a Stripe-shaped payment_intent event.
+func parseEvent(payload []byte) (*Event, error) {
+	var raw struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object stripeIntent `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	if raw.ID == "" || raw.Type == "" {
+		return nil, fmt.Errorf("invalid webhook payload: missing event id or type")
+	}
+
+	event := &Event{ID: raw.ID, Type: raw.Type, IntentID: raw.Data.Object.ID}
+	if raw.Data.Object.LastPaymentError != nil {
+		event.FailureMessage = raw.Data.Object.LastPaymentError.Message
+	}
+	return event, nil
+}
@@ -0,0 +1,164 @@
+// Package refunds sends refunds to the payment provider. Handlers record a
+// refund as pending in the transaction that causes it, then Send it once
+// that transaction has committed. A refund the provider made can thus
+// never be lost to a rollback. Worker retries the refunds that could not
+// be sent, with the same idempotency key, so the provider refunds once.
+package refunds
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/TobiAdeniji94/ecommerce_api/logging"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/payments"
+)
+
+const (
+	// defaultPollInterval applies when the worker has no PollInterval.
+	defaultPollInterval = 5 * time.Second
+	// batchSize is the number of refunds claimed per transaction.
+	batchSize = 20
+	// MaxAttempts is how often a refund is tried before it fails.
+	MaxAttempts = 5
+	// sendGrace keeps the worker off a new refund while the request that
+	// recorded it sends it.
+	sendGrace = time.Minute
+	// baseRetryDelay doubles after every failed attempt, up to maxRetryDelay.
+	baseRetryDelay = 30 * time.Second
+	maxRetryDelay  = time.Hour
+)
+
+// Pending returns a pending refund of amount against payment, to be
+// created in the caller's transaction and sent after it commits.
+func Pending(payment models.Payment, amount float64, returnID *uuid.UUID, now time.Time) *models.Refund {
+	return &models.Refund{
+		PaymentID:       payment.ID,
+		ReturnRequestID: returnID,
+		Amount:          amount,
+		Status:          models.RefundStatusPending,
+		NextAttemptAt:   now.Add(sendGrace),
+	}
+}
+
+// Send asks the provider for a pending refund and records the outcome on
+// it. A provider error leaves the refund pending for the worker to retry,
+// or failed after MaxAttempts; only database errors are returned.
+func Send(ctx context.Context, db *gorm.DB, provider payments.Provider, refund *models.Refund, now time.Time) error {
+	var payment models.Payment
+	err := db.Select("id", "provider_ref").First(&payment, "id = ?", refund.PaymentID).Error
+	var sent *payments.Refund
+	if err == nil {
+		sent, err = provider.Refund(ctx, payments.RefundParams{
+			IntentID:       payment.ProviderRef,
+			Amount:         payments.ToMinorUnits(refund.Amount),
+			IdempotencyKey: "refund-" + refund.ID.String(),
+		})
+	}
+
+	refund.Attempts++
+	updates := map[string]interface{}{"attempts": refund.Attempts}
+	switch {
+	case err == nil:
+		refund.Status = models.RefundStatusSucceeded
+		refund.ProviderRef = sent.ID
+		refund.LastError = ""
+		updates["provider_ref"] = sent.ID
+	case refund.Attempts >= MaxAttempts:
+		refund.Status = models.RefundStatusFailed
+		refund.LastError = err.Error()
+		logging.FromContext(ctx).Error("Refund failed; the customer has not been paid back",
+			"refund_id", refund.ID, "payment_id", refund.PaymentID, "attempts", refund.Attempts, "error", err)
+	default:
+		refund.LastError = err.Error()
+		refund.NextAttemptAt = now.Add(retryDelay(refund.Attempts))
+		updates["next_attempt_at"] = refund.NextAttemptAt
+		logging.FromContext(ctx).Warn("Refund will be retried",
+			"refund_id", refund.ID, "payment_id", refund.PaymentID, "attempts", refund.Attempts, "error", err)
+	}
+	updates["status"] = refund.Status
+	updates["last_error"] = refund.LastError
+	return db.Model(refund).Updates(updates).Error
+}
+
+// Worker retries pending refunds through Provider.
+type Worker struct {
+	DB           *gorm.DB
+	Provider     payments.Provider
+	PollInterval time.Duration
+}
+
+// Run retries pending refunds until ctx is canceled.
+func (w *Worker) Run(ctx context.Context) {
+	pollInterval := w.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while full batches come back, then wait for more
+		for {
+			processed, err := w.SendDue(ctx)
+			if err != nil {
+				slog.Error("Failed to send refunds", "error", err)
+				break
+			}
+			if processed < batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue sends a batch of due refunds and returns how many were
+// attempted. Refunds are claimed with SKIP LOCKED, so several API
+// instances can run workers without sending the same one twice.
+func (w *Worker) SendDue(ctx context.Context) (int, error) {
+	processed := 0
+	err := w.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		var due []models.Refund
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.RefundStatusPending, now).
+			Order("next_attempt_at").Limit(batchSize).Find(&due).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range due {
+			// Leave the rest pending when shutting down
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err := Send(ctx, tx, w.Provider, &due[i], now); err != nil {
+				return err
+			}
+			processed++
+		}
+		return nil
+	})
+	return processed, err
+}
+
+// retryDelay returns how long to wait after the given number of failed
+// attempts.
+func retryDelay(attempts int) time.Duration {
+	delay := baseRetryDelay << (attempts - 1)
+	if delay > maxRetryDelay || delay <= 0 {
+		return maxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,332 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/catalog"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/repository"
+)
+
+// testConformance runs the behaviour every backend must share. newStore
+// returns a Store on an empty, migrated database.
+func testConformance(t *testing.T, newStore func(t *testing.T) *repository.Store) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, s *repository.Store)
+	}{
+		{"UsersByIDAndEmail", testUsersByIDAndEmail},
+		{"UsersDuplicateEmail", testUsersDuplicateEmail},
+		{"ProductsDuplicateSKU", testProductsDuplicateSKU},
+		{"ProductsList", testProductsList},
+		{"ProductsUpdate", testProductsUpdate},
+		{"ProductsDelete", testProductsDelete},
+		{"OrdersByIDAndList", testOrdersByIDAndList},
+		{"OrdersStatus", testOrdersStatus},
+		{"TransactionRollback", testTransactionRollback},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, newStore(t))
+		})
+	}
+}
+
+func testUsersByIDAndEmail(t *testing.T, s *repository.Store) {
+	ctx := context.Background()
+	user := &models.User{Email: "ada@example.com", Password: "hash", Role: "user"}
+	if err := s.Users.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	byID, err := s.Users.ByID(ctx, user.ID)
+	if err != nil || byID.Email != user.Email {
+		t.Fatalf("ByID = %+v, %v; want %s", byID, err, user.Email)
+	}
+	byEmail, err := s.Users.ByEmail(ctx, user.Email)
+	if err != nil || byEmail.ID != user.ID {
+		t.Fatalf("ByEmail = %+v, %v; want %s", byEmail, err, user.ID)
+	}
+
+	if _, err := s.Users.ByID(ctx, uuid.New()); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("ByID(unknown) error = %v, want ErrNotFound", err)
+	}
+	if _, err := s.Users.ByEmail(ctx, "nobody@example.com"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("ByEmail(unknown) error = %v, want ErrNotFound", err)
+	}
+}
+
+func testUsersDuplicateEmail(t *testing.T, s *repository.Store) {
+	ctx := context.Background()
+	mustCreateUser(t, s, "ada@example.com")
+
+	err := s.Users.Create(ctx, &models.User{Email: "ada@example.com", Password: "hash"})
+	if !errors.Is(err, repository.ErrDuplicate) {
+		t.Errorf("Create(duplicate email) error = %v, want ErrDuplicate", err)
+	}
+}
+
+func testProductsDuplicateSKU(t *testing.T, s *repository.Store) {
+	ctx := context.Background()
+	first := mustCreateProduct(t, s, models.Product{SKU: "MUG-1", Name: "Mug", Price: 8})
+
+	err := s.Products.Create(ctx, &models.Product{SKU: "MUG-1", Name: "Other mug", Price: 9})
+	if !errors.Is(err, repository.ErrDuplicate) {
+		t.Errorf("Create(duplicate SKU) error = %v, want ErrDuplicate", err)
+	}
+
+	// Products without a SKU never clash
+	mustCreateProduct(t, s, models.Product{Name: "Plain mug", Price: 5})
+	mustCreateProduct(t, s, models.Product{Name: "Plainer mug", Price: 4})
+
+	for _, tc := range []struct {
+		sku    string
+		except uuid.UUID
+		want   bool
+	}{
+		{"MUG-1", uuid.Nil, true},
+		{"MUG-1", first.ID, false},
+		{"MUG-2", uuid.Nil, false},
+		{"", uuid.Nil, false},
+	} {
+		taken, err := s.Products.SKUTaken(ctx, tc.sku, tc.except)
+		if err != nil || taken != tc.want {
+			t.Errorf("SKUTaken(%q, %s) = %v, %v; want %v", tc.sku, tc.except, taken, err, tc.want)
+		}
+	}
+}
+
+func testProductsList(t *testing.T, s *repository.Store) {
+	ctx := context.Background()
+	mustCreateProduct(t, s, models.Product{Name: "Blue Mug", Category: "kitchen", Price: 8, Stock: 3})
+	mustCreateProduct(t, s, models.Product{Name: "Red mug", Category: "kitchen", Price: 12})
+	mustCreateProduct(t, s, models.Product{Name: "Lamp", Description: "A mug-shaped lamp", Category: "lighting", Price: 30, Stock: 1})
+
+	minPrice := 10.0
+	inStock := true
+	for _, tc := range []struct {
+		name   string
+		filter catalog.Filter
+		want   int
+	}{
+		{"all", catalog.Filter{}, 3},
+		{"category", catalog.Filter{Category: "kitchen"}, 2},
+		{"query ignores case", catalog.Filter{Query: "MUG"}, 3},
+		{"min price", catalog.Filter{MinPrice: &minPrice}, 2},
+		{"in stock", catalog.Filter{InStock: &inStock}, 2},
+		{"combined", catalog.Filter{Category: "kitchen", InStock: &inStock}, 1},
+	} {
+		list, err := s.Products.List(ctx, tc.filter)
+		if err != nil || len(list) != tc.want {
+			t.Errorf("List(%s) returned %d products, %v; want %d", tc.name, len(list), err, tc.want)
+		}
+	}
+}
+
+func testProductsUpdate(t *testing.T, s *repository.Store) {
+	ctx := context.Background()
+	product := mustCreateProduct(t, s, models.Product{SKU: "LAMP-1", Name: "Lamp", Price: 30, Stock: 4})
+	if product.Version != 1 {
+		t.Fatalf("new product version = %d, want 1", product.Version)
+	}
+
+	stale := *product
+	product.Name = "Desk lamp"
+	product.Stock = 99
+	if err := s.Products.Update(ctx, product); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if product.Version != 2 {
+		t.Errorf("version after Update = %d, want 2", product.Version)
+	}
+
+	saved, err := s.Products.ByID(ctx, product.ID)
+	if err != nil {
+		t.Fatalf("ByID: %v", err)
+	}
+	if saved.Name != "Desk lamp" || saved.Version != 2 {
+		t.Errorf("saved product = %q version %d, want %q version 2", saved.Name, saved.Version, "Desk lamp")
+	}
+	if saved.Stock != 4 {
+		t.Errorf("saved stock = %d, want 4: Update must not write stock", saved.Stock)
+	}
+
+	stale.Name = "Floor lamp"
+	if err := s.Products.Update(ctx, &stale); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("Update(stale) error = %v, want ErrVersionConflict", err)
+	}
+	if stale.Version != 1 {
+		t.Errorf("version after failed Update = %d, want it left at 1", stale.Version)
+	}
+
+	other := mustCreateProduct(t, s, models.Product{SKU: "LAMP-2", Name: "Other lamp", Price: 20})
+	other.SKU = "LAMP-1"
+	if err := s.Products.Update(ctx, other); !errors.Is(err, repository.ErrDuplicate) {
+		t.Errorf("Update(duplicate SKU) error = %v, want ErrDuplicate", err)
+	}
+}
+
+func testProductsDelete(t *testing.T, s *repository.Store) {
+	ctx := context.Background()
+	product := mustCreateProduct(t, s, models.Product{Name: "Lamp", Price: 30})
+
+	if err := s.Products.Delete(ctx, product.ID, product.Version+1); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("Delete(wrong version) error = %v, want ErrVersionConflict", err)
+	}
+	if err := s.Products.Delete(ctx, product.ID, product.Version); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Products.ByID(ctx, product.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("ByID(deleted) error = %v, want ErrNotFound", err)
+	}
+	if err := s.Products.Delete(ctx, product.ID, 0); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Delete(deleted) error = %v, want ErrNotFound", err)
+	}
+	if err := s.Products.Delete(ctx, product.ID, 1); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Delete(deleted, version 1) error = %v, want ErrNotFound", err)
+	}
+
+	unconditional := mustCreateProduct(t, s, models.Product{Name: "Mug", Price: 8})
+	if err := s.Products.Delete(ctx, unconditional.ID, 0); err != nil {
+		t.Errorf("Delete(any version): %v", err)
+	}
+}
+
+func testOrdersByIDAndList(t *testing.T, s *repository.Store) {
+	ctx := context.Background()
+	ada := mustCreateUser(t, s, "ada@example.com")
+	bob := mustCreateUser(t, s, "bob@example.com")
+	mug := mustCreateProduct(t, s, models.Product{Name: "Mug", Price: 8})
+
+	first := mustCreateOrder(t, s, ada, mug)
+	second := mustCreateOrder(t, s, ada, mug)
+	mustCreateOrder(t, s, bob, mug)
+
+	order, err := s.Orders.ByID(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("ByID: %v", err)
+	}
+	if order.Version != 1 || order.Status != models.OrderStatusPending {
+		t.Errorf("new order has version %d and status %q, want 1 and %q", order.Version, order.Status, models.OrderStatusPending)
+	}
+	if order.User.Email != ada.Email {
+		t.Errorf("order user = %q, want %q", order.User.Email, ada.Email)
+	}
+	if len(order.Items) != 1 || order.Items[0].Product.Name != "Mug" || order.Items[0].Quantity != 2 {
+		t.Errorf("order items = %+v, want 2 of Mug", order.Items)
+	}
+	if order.ShippingAddress.Country != "GB" {
+		t.Errorf("shipping country = %q, want GB", order.ShippingAddress.Country)
+	}
+	if _, err := s.Orders.ByID(ctx, uuid.New()); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("ByID(unknown) error = %v, want ErrNotFound", err)
+	}
+
+	list, err := s.Orders.ListByUser(ctx, ada.ID)
+	if err != nil {
+		t.Fatalf("ListByUser: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != first.ID || list[1].ID != second.ID {
+		t.Fatalf("ListByUser returned %d orders, want ada's 2 oldest first", len(list))
+	}
+	if len(list[0].Items) != 1 || list[0].Items[0].Product.ID != mug.ID {
+		t.Errorf("listed order items = %+v, want the mug", list[0].Items)
+	}
+}
+
+func testOrdersStatus(t *testing.T, s *repository.Store) {
+	ctx := context.Background()
+	ada := mustCreateUser(t, s, "ada@example.com")
+	order := mustCreateOrder(t, s, ada, mustCreateProduct(t, s, models.Product{Name: "Mug", Price: 8}))
+	stale := *order
+
+	err := s.Transaction(ctx, func(tx *repository.Store) error {
+		if err := tx.Orders.Lock(ctx, order); err != nil {
+			return err
+		}
+		return tx.Orders.SetStatus(ctx, order, models.OrderStatusPaid)
+	})
+	if err != nil {
+		t.Fatalf("Lock and SetStatus: %v", err)
+	}
+	if order.Status != models.OrderStatusPaid || order.Version != 2 {
+		t.Errorf("order has status %q version %d, want %q version 2", order.Status, order.Version, models.OrderStatusPaid)
+	}
+
+	saved, err := s.Orders.ByID(ctx, order.ID)
+	if err != nil || saved.Status != models.OrderStatusPaid || saved.Version != 2 {
+		t.Errorf("saved order = %+v, %v; want %q version 2", saved, err, models.OrderStatusPaid)
+	}
+
+	err = s.Transaction(ctx, func(tx *repository.Store) error {
+		return tx.Orders.Lock(ctx, &stale)
+	})
+	if !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("Lock(stale) error = %v, want ErrVersionConflict", err)
+	}
+	if err := s.Orders.SetStatus(ctx, &stale, models.OrderStatusCanceled); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("SetStatus(stale) error = %v, want ErrVersionConflict", err)
+	}
+	if stale.Status != models.OrderStatusPending || stale.Version != 1 {
+		t.Errorf("stale order changed to %q version %d by a failed SetStatus", stale.Status, stale.Version)
+	}
+}
+
+func testTransactionRollback(t *testing.T, s *repository.Store) {
+	ctx := context.Background()
+	errAbort := errors.New("abort")
+
+	err := s.Transaction(ctx, func(tx *repository.Store) error {
+		if err := tx.Users.Create(ctx, &models.User{Email: "ada@example.com", Password: "hash"}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("Transaction error = %v, want %v", err, errAbort)
+	}
+	if _, err := s.Users.ByEmail(ctx, "ada@example.com"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("ByEmail after rollback error = %v, want ErrNotFound", err)
+	}
+}
+
+func mustCreateUser(t *testing.T, s *repository.Store, email string) *models.User {
+	t.Helper()
+	user := &models.User{Email: email, Password: "hash", Role: "user"}
+	if err := s.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("creating user %s: %v", email, err)
+	}
+	return user
+}
+
+func mustCreateProduct(t *testing.T, s *repository.Store, product models.Product) *models.Product {
+	t.Helper()
+	if err := s.Products.Create(context.Background(), &product); err != nil {
+		t.Fatalf("creating product %s: %v", product.Name, err)
+	}
+	return &product
+}
+
+// mustCreateOrder creates a pending order for 2 of product. Orders are a
+// millisecond apart so they list in creation order.
+func mustCreateOrder(t *testing.T, s *repository.Store, user *models.User, product *models.Product) *models.Order {
+	t.Helper()
+	time.Sleep(time.Millisecond)
+	order := &models.Order{
+		UserID:          user.ID,
+		Status:          models.OrderStatusPending,
+		ShippingAddress: models.Address{Line1: "1 High Street", City: "London", Country: "GB"},
+		Items:           []models.OrderItem{{ProductID: product.ID, Quantity: 2, UnitPrice: product.Price}},
+		Subtotal:        product.Price * 2,
+		Total:           product.Price * 2,
+	}
+	if err := s.Orders.Create(context.Background(), order); err != nil {
+		t.Fatalf("creating order: %v", err)
+	}
+	return order
+}
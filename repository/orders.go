@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+type orders struct {
+	store *Store
+}
+
+func (r orders) Create(ctx context.Context, order *models.Order) error {
+	return r.store.translate(r.store.db(ctx).Create(order).Error)
+}
+
+func (r orders) ByID(ctx context.Context, id uuid.UUID) (*models.Order, error) {
+	var order models.Order
+	err := r.store.db(ctx).Preload("User").Preload("Items.Product").Preload("Items.Allocations").Preload("Shipments").
+		First(&order, "id = ?", id).Error
+	if err != nil {
+		return nil, r.store.translate(err)
+	}
+	return &order, nil
+}
+
+func (r orders) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Order, error) {
+	var list []models.Order
+	err := r.store.db(ctx).Preload("User").Preload("Items.Product").Preload("Shipments").
+		Where("user_id = ?", userID).Order("created_at, id").Find(&list).Error
+	if err != nil {
+		return nil, r.store.translate(err)
+	}
+	return list, nil
+}
+
+func (r orders) Lock(ctx context.Context, order *models.Order) error {
+	var current models.Order
+	// SQLite drops the lock; its single connection already serialises transactions
+	err := r.store.db(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Select("version").First(&current, "id = ?", order.ID).Error
+	if err != nil {
+		return r.store.translate(err)
+	}
+	if current.Version != order.Version {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (r orders) SetStatus(ctx context.Context, order *models.Order, status string) error {
+	// Updates copies the new values onto order, so keep what was read
+	read := *order
+	result := r.store.db(ctx).Model(order).Where("version = ?", order.Version).Updates(map[string]interface{}{
+		"status":  status,
+		"version": gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		*order = read
+		return r.store.translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		*order = read
+		return ErrVersionConflict
+	}
+	order.Status = status
+	order.Version++
+	return nil
+}
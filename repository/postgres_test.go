@@ -0,0 +1,40 @@
+package repository_test
+
+import (
+	"os"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/TobiAdeniji94/ecommerce_api/repository"
+)
+
+// TestPostgres runs against the database in TEST_POSTGRES_DSN, e.g.
+// "host=localhost user=postgres password=postgres dbname=ecommerce_test
+// sslmode=disable". Every test empties the tables, so never point it at a
+// database holding data you want to keep.
+func TestPostgres(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("opening Postgres: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	testConformance(t, func(t *testing.T) *repository.Store {
+		store := migratedStore(t, db)
+		if err := store.DB.Exec("TRUNCATE users, products, orders CASCADE").Error; err != nil {
+			t.Fatalf("emptying tables: %v", err)
+		}
+		return store
+	})
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/catalog"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+type products struct {
+	store *Store
+}
+
+func (r products) Create(ctx context.Context, product *models.Product) error {
+	return r.store.translate(r.store.db(ctx).Create(product).Error)
+}
+
+func (r products) ByID(ctx context.Context, id uuid.UUID) (*models.Product, error) {
+	var product models.Product
+	if err := r.store.db(ctx).First(&product, "id = ?", id).Error; err != nil {
+		return nil, r.store.translate(err)
+	}
+	return &product, nil
+}
+
+func (r products) List(ctx context.Context, filter catalog.Filter) ([]models.Product, error) {
+	var list []models.Product
+	if err := filter.Apply(r.store.db(ctx)).Find(&list).Error; err != nil {
+		return nil, r.store.translate(err)
+	}
+	return list, nil
+}
+
+func (r products) SKUTaken(ctx context.Context, sku string, except uuid.UUID) (bool, error) {
+	if sku == "" {
+		return false, nil
+	}
+	var count int64
+	err := r.store.db(ctx).Model(&models.Product{}).Where("sku = ? AND id <> ?", sku, except).Count(&count).Error
+	return count > 0, r.store.translate(err)
+}
+
+func (r products) Update(ctx context.Context, product *models.Product) error {
+	readVersion := product.Version
+	product.Version++
+	result := r.store.db(ctx).Model(product).Where("version = ?", readVersion).
+		Select("*").Omit("id", "stock", "rating_average", "rating_count", "rating_total", "created_at").Updates(product)
+	if result.Error != nil {
+		product.Version = readVersion
+		return r.store.translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		product.Version = readVersion
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (r products) Delete(ctx context.Context, id uuid.UUID, version int) error {
+	err := r.store.Transaction(ctx, func(tx *Store) error {
+		// Saved and watched products go with the product
+		if err := tx.db(ctx).Where("product_id = ?", id).Delete(&models.WishlistItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.db(ctx).Where("product_id = ?", id).Delete(&models.StockAlert{}).Error; err != nil {
+			return err
+		}
+		query := tx.db(ctx).Where("id = ?", id)
+		if version != 0 {
+			query = query.Where("version = ?", version)
+		}
+		result := query.Delete(&models.Product{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if !errors.Is(err, ErrNotFound) || version == 0 {
+		return r.store.translate(err)
+	}
+
+	if _, err := r.ByID(ctx, id); err != nil {
+		return err
+	}
+	return ErrVersionConflict
+}
@@ -0,0 +1,134 @@
+// Package repository stores users, products and orders behind interfaces,
+// so handlers do not build queries themselves. A Store runs on Postgres in
+// production and on SQLite for local development and hermetic tests; both
+// must pass the conformance suite in this package's tests.
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/TobiAdeniji94/ecommerce_api/catalog"
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+var (
+	// ErrNotFound is returned when no row matches.
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when a write breaks a unique constraint.
+	ErrDuplicate = errors.New("duplicate")
+	// ErrVersionConflict is returned when a row changed after it was read.
+	ErrVersionConflict = errors.New("version conflict")
+)
+
+// Users stores user accounts.
+type Users interface {
+	// Create inserts user, failing with ErrDuplicate if the email is taken.
+	Create(ctx context.Context, user *models.User) error
+	ByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	ByEmail(ctx context.Context, email string) (*models.User, error)
+}
+
+// Products stores the catalog. Stock is changed through the inventory
+// ledger, never by Update.
+type Products interface {
+	Create(ctx context.Context, product *models.Product) error
+	ByID(ctx context.Context, id uuid.UUID) (*models.Product, error)
+	List(ctx context.Context, filter catalog.Filter) ([]models.Product, error)
+	// SKUTaken reports whether a product other than except uses sku.
+	// Products without a SKU never clash.
+	SKUTaken(ctx context.Context, sku string, except uuid.UUID) (bool, error)
+	// Update saves the editable fields if the product is still at
+	// product.Version, then bumps the version. It fails with
+	// ErrVersionConflict if another write got there first.
+	Update(ctx context.Context, product *models.Product) error
+	// Delete removes the product if it is at version, or whatever its
+	// version when version is 0, together with the wishlist items and
+	// stock alerts that refer to it.
+	Delete(ctx context.Context, id uuid.UUID, version int) error
+}
+
+// Orders stores orders with their items.
+type Orders interface {
+	// Create inserts order with its items and their allocations.
+	Create(ctx context.Context, order *models.Order) error
+	// ByID returns the order with its user, items, item products and
+	// allocations, and shipments.
+	ByID(ctx context.Context, id uuid.UUID) (*models.Order, error)
+	// ListByUser returns the user's orders, oldest first, with their user,
+	// items, item products and shipments.
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Order, error)
+	// Lock locks the order for the rest of the transaction and fails with
+	// ErrVersionConflict if it changed since it was read.
+	Lock(ctx context.Context, order *models.Order) error
+	// SetStatus saves a new status and bumps the order version, failing
+	// with ErrVersionConflict if the order changed since it was read.
+	SetStatus(ctx context.Context, order *models.Order, status string) error
+}
+
+// Store holds the repositories of one database, or of one transaction.
+type Store struct {
+	// DB is the underlying database, for the queries that have no
+	// repository yet.
+	DB       *gorm.DB
+	Users    Users
+	Products Products
+	Orders   Orders
+}
+
+// New returns a Store on db, which must be a Postgres or SQLite database.
+func New(db *gorm.DB) (*Store, error) {
+	switch name := db.Dialector.Name(); name {
+	case "postgres", "sqlite":
+		return newStore(db), nil
+	default:
+		return nil, fmt.Errorf("unsupported database %q", name)
+	}
+}
+
+func newStore(db *gorm.DB) *Store {
+	s := &Store{DB: db}
+	s.Users = users{s}
+	s.Products = products{s}
+	s.Orders = orders{s}
+	return s
+}
+
+// Transaction runs fn with a Store on a transaction, committing if fn
+// returns nil and rolling back otherwise.
+func (s *Store) Transaction(ctx context.Context, fn func(tx *Store) error) error {
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(s.On(tx))
+	})
+}
+
+// On returns a Store on tx, a transaction started on the Store's database.
+func (s *Store) On(tx *gorm.DB) *Store {
+	return newStore(tx)
+}
+
+// db returns the Store's database bound to ctx.
+func (s *Store) db(ctx context.Context) *gorm.DB {
+	return s.DB.WithContext(ctx)
+}
+
+// translate maps driver errors to the package's errors.
+func (s *Store) translate(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if translator, ok := s.DB.Dialector.(gorm.ErrorTranslator); ok {
+		err = translator.Translate(err)
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDuplicate
+	}
+	return err
+}
@@ -0,0 +1,42 @@
+package repository_test
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/TobiAdeniji94/ecommerce_api/config"
+	"github.com/TobiAdeniji94/ecommerce_api/migrations"
+	"github.com/TobiAdeniji94/ecommerce_api/repository"
+)
+
+func TestSQLite(t *testing.T) {
+	testConformance(t, func(t *testing.T) *repository.Store {
+		db, err := config.ConnectDatabase(config.DatabaseConfig{Driver: "sqlite", Path: ":memory:"})
+		if err != nil {
+			t.Fatalf("opening SQLite: %v", err)
+		}
+		t.Cleanup(func() {
+			if sqlDB, err := db.DB(); err == nil {
+				sqlDB.Close()
+			}
+		})
+		return migratedStore(t, db)
+	})
+}
+
+// migratedStore migrates db and returns a Store on it that does not log
+// the errors the tests provoke.
+func migratedStore(t *testing.T, db *gorm.DB) *repository.Store {
+	t.Helper()
+	db = db.Session(&gorm.Session{Logger: logger.Discard})
+	if _, err := migrations.Up(db, 0); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	store, err := repository.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
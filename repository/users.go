@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+type users struct {
+	store *Store
+}
+
+func (r users) Create(ctx context.Context, user *models.User) error {
+	return r.store.translate(r.store.db(ctx).Create(user).Error)
+}
+
+func (r users) ByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	var user models.User
+	if err := r.store.db(ctx).First(&user, "id = ?", id).Error; err != nil {
+		return nil, r.store.translate(err)
+	}
+	return &user, nil
+}
+
+func (r users) ByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := r.store.db(ctx).First(&user, "email = ?", email).Error; err != nil {
+		return nil, r.store.translate(err)
+	}
+	return &user, nil
+}
@@ -4,11 +4,13 @@ import (
 	"net/http"
     "github.com/gin-gonic/gin"
 
+    "github.com/TobiAdeniji94/ecommerce_api/config"
     "github.com/TobiAdeniji94/ecommerce_api/controllers"
     "github.com/TobiAdeniji94/ecommerce_api/middleware"
 )
 
-func InitializeRoutes(r *gin.Engine) {
+// InitializeRoutes registers the API's routes, served by h.
+func InitializeRoutes(r *gin.Engine, cfg *config.Config, h *controllers.Handler) {
 
 	// Welcome message
 	r.GET("/", func(c *gin.Context) {
@@ -23,31 +25,150 @@ func InitializeRoutes(r *gin.Engine) {
         // Public Routes: User registration and login
         userGroup := api.Group("/users")
         {
-            userGroup.POST("/register", controllers.RegisterUser)
-            userGroup.POST("/login", controllers.LoginUser)
+            userGroup.POST("/register", h.RegisterUser)
+            userGroup.POST("/login", h.LoginUser)
         }
 
+        // Public Routes: Payment provider webhooks are authenticated by signature
+        api.POST("/payments/webhook", h.HandlePaymentWebhook)
+
         // Protected Routes: Requires Authentication
         protected := api.Group("/")
-        protected.Use(middleware.AuthMiddleware) // JWT authentication middleware
+        protected.Use(middleware.Auth(h.Tokens)) // JWT authentication middleware
+
+        // Idempotency-Key support for endpoints clients retry
+        idempotent := middleware.Idempotency(h.DB, cfg.Idempotency.TTL, h.Clock)
 
         // Product Routes: Admin-only for create, update, delete
         productGroup := protected.Group("/products")
         {
-            productGroup.POST("", middleware.AdminMiddleware, controllers.CreateProduct)  // Create a product
-            productGroup.GET("", controllers.GetProducts)                                // List all products
-            productGroup.GET("/:id", controllers.GetProductByID)                         // Get product by ID
-            productGroup.PUT("/:id", middleware.AdminMiddleware, controllers.UpdateProduct) // Update a product
-            productGroup.DELETE("/:id", middleware.AdminMiddleware, controllers.DeleteProduct) // Delete a product
+            productGroup.POST("", middleware.AdminMiddleware, h.CreateProduct)  // Create a product
+            productGroup.GET("", h.GetProducts)                                // List all products
+            productGroup.POST("/import", middleware.AdminMiddleware, h.ImportProducts) // Bulk import from CSV or NDJSON (Admin)
+            productGroup.GET("/export", middleware.AdminMiddleware, h.ExportProducts)  // Stream the catalog as CSV or NDJSON (Admin)
+            productGroup.GET("/:id", h.GetProductByID)                         // Get product by ID
+            productGroup.PUT("/:id", middleware.AdminMiddleware, h.UpdateProduct) // Update a product
+            productGroup.PATCH("/:id", middleware.AdminMiddleware, h.PatchProduct) // Partially update a product
+            productGroup.DELETE("/:id", middleware.AdminMiddleware, h.DeleteProduct) // Delete a product
+            productGroup.GET("/:id/movements", middleware.AdminMiddleware, h.GetStockMovements) // Stock movement history (Admin)
+            productGroup.GET("/:id/stock", middleware.AdminMiddleware, h.GetProductStock)       // Stock by warehouse (Admin)
+            productGroup.POST("/:id/reviews", h.CreateReview)                                 // Review a delivered product
+            productGroup.GET("/:id/reviews", h.GetProductReviews)                             // List approved reviews
+            productGroup.POST("/:id/stock-alerts", h.SubscribeStockAlert)                     // Get notified when back in stock
+            productGroup.DELETE("/:id/stock-alerts", h.UnsubscribeStockAlert)                 // Cancel a back-in-stock alert
+        }
+
+        // Inventory Routes: Admin-only stock adjustments and reports
+        inventoryGroup := protected.Group("/inventory")
+        inventoryGroup.Use(middleware.AdminMiddleware)
+        {
+            inventoryGroup.POST("/adjustments", h.AdjustStock)           // Adjust stock
+            inventoryGroup.POST("/transfers", h.TransferStock)           // Move stock between warehouses
+            inventoryGroup.GET("/low-stock", h.GetLowStockProducts)     // List low-stock products
+            inventoryGroup.GET("/backorders", h.GetBackorders)          // List backorders in fulfilment order
+            inventoryGroup.GET("/reconciliation", h.GetStockDiscrepancies) // Compare stock with the ledger
+            inventoryGroup.POST("/reconciliation", h.ReconcileStock)     // Reconcile the ledger with stock
         }
 
         // Order Routes: Authenticated users and admin access
         orderGroup := protected.Group("/orders")
         {
-            orderGroup.POST("", controllers.PlaceOrder)                          // Place a new order
-            orderGroup.GET("", controllers.GetUserOrders)                        // List user orders
-            orderGroup.PUT("/:id/cancel", controllers.CancelOrder)               // Cancel an order
-            orderGroup.PUT("/:id/status", middleware.AdminMiddleware, controllers.UpdateOrderStatus) // Update order status (Admin)
+            orderGroup.POST("", idempotent, h.PlaceOrder)              // Place a new order
+            orderGroup.GET("", h.GetUserOrders)                        // List user orders
+            orderGroup.GET("/:id", h.GetOrderByID)                     // Get an order by ID
+            orderGroup.PUT("/:id/cancel", h.CancelOrder)               // Cancel an order
+            orderGroup.PUT("/:id/status", middleware.AdminMiddleware, h.UpdateOrderStatus) // Update order status (Admin)
+            orderGroup.POST("/:id/payments", idempotent, h.CreatePayment) // Start a payment for an order
+            orderGroup.POST("/:id/returns", h.CreateReturn)            // Request a return
+            orderGroup.POST("/:id/shipments", middleware.AdminMiddleware, h.CreateShipment) // Record a shipment (Admin)
+        }
+
+        // Warehouse Routes: Admin-only stock locations
+        warehouseGroup := protected.Group("/warehouses")
+        warehouseGroup.Use(middleware.AdminMiddleware)
+        {
+            warehouseGroup.POST("", h.CreateWarehouse)            // Create a warehouse
+            warehouseGroup.GET("", h.GetWarehouses)               // List warehouses
+            warehouseGroup.GET("/:id/stock", h.GetWarehouseStock) // Stock held in a warehouse
+            warehouseGroup.PUT("/:id", h.UpdateWarehouse)         // Update a warehouse
+            warehouseGroup.DELETE("/:id", h.DeleteWarehouse)      // Delete an empty warehouse
+        }
+
+        // Coupon Routes: Admin-only management of discount codes
+        couponGroup := protected.Group("/coupons")
+        couponGroup.Use(middleware.AdminMiddleware)
+        {
+            couponGroup.POST("", h.CreateCoupon)       // Create a coupon
+            couponGroup.GET("", h.GetCoupons)          // List all coupons
+            couponGroup.GET("/:id", h.GetCouponByID)   // Get coupon by ID
+            couponGroup.PUT("/:id", h.UpdateCoupon)    // Update a coupon
+            couponGroup.DELETE("/:id", h.DeleteCoupon) // Delete a coupon
+        }
+
+        // Shipping Routes: Customers list and quote methods, admins manage them
+        shippingGroup := protected.Group("/shipping-methods")
+        {
+            shippingGroup.GET("", h.GetShippingMethods)                                          // List shipping methods
+            shippingGroup.POST("/quote", h.QuoteShipping)                                        // Quote shipping for items
+            shippingGroup.POST("", middleware.AdminMiddleware, h.CreateShippingMethod)           // Create a shipping method (Admin)
+            shippingGroup.PUT("/:id", middleware.AdminMiddleware, h.UpdateShippingMethod)        // Update a shipping method (Admin)
+            shippingGroup.DELETE("/:id", middleware.AdminMiddleware, h.DeleteShippingMethod)     // Delete a shipping method (Admin)
+        }
+
+        // Wishlist Routes: Each user manages their own wishlist and alerts
+        wishlistGroup := protected.Group("/wishlist")
+        {
+            wishlistGroup.GET("", h.GetWishlist)                         // List saved products
+            wishlistGroup.POST("", h.AddToWishlist)                      // Save a product
+            wishlistGroup.DELETE("/:product_id", h.RemoveFromWishlist)   // Remove a saved product
+        }
+        protected.GET("/stock-alerts", h.GetStockAlerts)                // List back-in-stock alerts
+        protected.GET("/notifications", h.GetNotifications)             // List own notifications, or all (Admin)
+
+        // Review Routes: Authors manage their reviews, admins moderate them
+        reviewGroup := protected.Group("/reviews")
+        {
+            reviewGroup.GET("", h.GetReviews)                                              // List own reviews, or all (Admin)
+            reviewGroup.PUT("/:id", h.UpdateReview)                                        // Edit own review
+            reviewGroup.DELETE("/:id", h.DeleteReview)                                     // Delete own review, or any (Admin)
+            reviewGroup.PUT("/:id/approve", middleware.AdminMiddleware, h.ApproveReview)   // Publish a review (Admin)
+            reviewGroup.PUT("/:id/reject", middleware.AdminMiddleware, h.RejectReview)     // Reject a review (Admin)
+        }
+
+        // Webhook Routes: Admin-only outbound event subscriptions
+        if cfg.Features.Webhooks {
+            webhookGroup := protected.Group("/webhooks")
+            webhookGroup.Use(middleware.AdminMiddleware)
+            {
+                webhookGroup.POST("", h.CreateWebhook)                      // Subscribe a URL to events
+                webhookGroup.GET("", h.GetWebhooks)                         // List subscriptions
+                webhookGroup.PUT("/:id", h.UpdateWebhook)                   // Update a subscription
+                webhookGroup.DELETE("/:id", h.DeleteWebhook)                // Delete a subscription
+                webhookGroup.GET("/:id/deliveries", h.GetWebhookDeliveries) // Delivery log
+            }
+            deliveryGroup := protected.Group("/webhook-deliveries")
+            deliveryGroup.Use(middleware.AdminMiddleware)
+            {
+                deliveryGroup.GET("/:id", h.GetWebhookDelivery)            // Delivery with its attempts
+                deliveryGroup.POST("/:id/replay", h.ReplayWebhookDelivery) // Send a delivery again
+            }
+        }
+
+        // Return Routes: Users see their own returns, admins review them
+        returnGroup := protected.Group("/returns")
+        {
+            returnGroup.GET("", h.GetReturns)                                                 // List return requests
+            returnGroup.PUT("/:id/approve", middleware.AdminMiddleware, h.ApproveReturn)      // Approve a return (Admin)
+            returnGroup.PUT("/:id/reject", middleware.AdminMiddleware, h.RejectReturn)        // Reject a return (Admin)
+        }
+
+        // Health Routes: Admin-only check details
+        protected.GET("/health/details", middleware.AdminMiddleware, h.GetHealthDetails) // Status and latency of every check (Admin)
+
+        // Payment Routes: Admin-only capture
+        paymentGroup := protected.Group("/payments")
+        {
+            paymentGroup.POST("/:id/capture", middleware.AdminMiddleware, idempotent, h.CapturePayment) // Capture a payment (Admin)
         }
     }
 }
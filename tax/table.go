@@ -0,0 +1,74 @@
+package tax
+
+import (
+	"context"
+	"strings"
+)
+
+// Rate is the tax rate for a country, optionally narrowed to a region and a
+// product tax class. An empty Region or TaxClass matches any value.
+type Rate struct {
+	Country  string  `json:"country"`
+	Region   string  `json:"region,omitempty"`
+	TaxClass string  `json:"tax_class,omitempty"`
+	Rate     float64 `json:"rate"`
+}
+
+// Table is a Calculator backed by a static list of rates.
+type Table struct {
+	Rates            []Rate `json:"rates"`
+	PricesIncludeTax bool   `json:"prices_include_tax"`
+}
+
+// Calculate taxes each line at the most specific matching rate. Lines with
+// no matching rate are not taxed.
+func (t *Table) Calculate(ctx context.Context, req Request) (*Result, error) {
+	result := &Result{PricesIncludeTax: t.PricesIncludeTax}
+	for _, line := range req.Lines {
+		rate := t.lookup(req.Address, line.TaxClass)
+
+		var owed float64
+		if t.PricesIncludeTax {
+			owed = round(line.Amount - line.Amount/(1+rate))
+		} else {
+			owed = round(line.Amount * rate)
+		}
+
+		result.Lines = append(result.Lines, LineResult{ID: line.ID, Rate: rate, Tax: owed})
+		result.TotalTax += owed
+	}
+	result.TotalTax = round(result.TotalTax)
+	return result, nil
+}
+
+// lookup returns the rate of the most specific entry matching the address
+// and tax class. A region match outranks a tax class match.
+func (t *Table) lookup(addr Address, taxClass string) float64 {
+	if taxClass == "" {
+		taxClass = DefaultTaxClass
+	}
+
+	best, bestScore := 0.0, -1
+	for _, r := range t.Rates {
+		if !strings.EqualFold(r.Country, addr.Country) {
+			continue
+		}
+		score := 0
+		if r.Region != "" {
+			if !strings.EqualFold(r.Region, addr.Region) {
+				continue
+			}
+			score += 2
+		}
+		if r.TaxClass != "" {
+			if r.TaxClass != taxClass {
+				continue
+			}
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = r.Rate, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,81 @@
+// Package tax calculates the tax owed on order lines.
+package tax
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/TobiAdeniji94/ecommerce_api/config"
+)
+
+// DefaultTaxClass is used for products without a tax class.
+const DefaultTaxClass = "standard"
+
+// Address locates the buyer for tax purposes.
+type Address struct {
+	Country string
+	Region  string
+}
+
+// Line is an order line to tax. Amount is the line total after discounts.
+type Line struct {
+	ID       string
+	Amount   float64
+	TaxClass string
+}
+
+// Request is a set of lines shipped to one address.
+type Request struct {
+	Address Address
+	Lines   []Line
+}
+
+// LineResult is the tax owed on a single line.
+type LineResult struct {
+	ID   string
+	Rate float64
+	Tax  float64
+}
+
+// Result holds per-line and total tax. When PricesIncludeTax is set the tax
+// is already part of the line amounts and must not be added to the total.
+type Result struct {
+	Lines            []LineResult
+	TotalTax         float64
+	PricesIncludeTax bool
+}
+
+// Calculator computes tax for a request. Implementations may call out to
+// an external tax service.
+type Calculator interface {
+	Calculate(ctx context.Context, req Request) (*Result, error)
+}
+
+// New returns the calculator described by cfg. RatesFile points to a JSON
+// rate table; PricesIncludeTax, when set, overrides whether catalog prices
+// include tax.
+func New(cfg config.TaxConfig) (Calculator, error) {
+	table := &Table{}
+	if cfg.RatesFile != "" {
+		data, err := os.ReadFile(cfg.RatesFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading tax rates: %w", err)
+		}
+		if err := json.Unmarshal(data, table); err != nil {
+			return nil, fmt.Errorf("parsing tax rates: %w", err)
+		}
+	}
+
+	if cfg.PricesIncludeTax != nil {
+		table.PricesIncludeTax = *cfg.PricesIncludeTax
+	}
+	return table, nil
+}
+
+// round rounds an amount to cents.
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
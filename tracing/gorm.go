@@ -0,0 +1,140 @@
+package tracing
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey holds a statement's span between its before and after callbacks.
+const spanKey = "tracing:span"
+
+// maxQueryLength bounds the query text recorded on a span.
+const maxQueryLength = 2048
+
+var (
+	// stringLiteral matches a quoted SQL string, with '' as an escaped quote.
+	stringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	// numberLiteral matches a number that is not part of an identifier or
+	// a $1 placeholder; the preceding character is kept in group 1.
+	numberLiteral = regexp.MustCompile(`(^|[^\w$.])-?\d+(?:\.\d+)?`)
+)
+
+// GormPlugin records the queries of traced requests as client spans. Only
+// statements whose context is part of a trace get a span, so background
+// workers polling the database do not start a trace per query. Queries
+// are recorded by SanitizeSQL, never with their values.
+type GormPlugin struct{}
+
+// Name implements gorm.Plugin.
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize implements gorm.Plugin by wrapping GORM's callbacks.
+func (p GormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	hooks := []struct {
+		operation     string
+		before, after func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	}
+	for _, hook := range hooks {
+		if err := hook.before("tracing:before_"+hook.operation, p.before); err != nil {
+			return err
+		}
+		if err := hook.after("tracing:after_"+hook.operation, p.after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// before starts a span for the statement if its context is traced.
+func (GormPlugin) before(db *gorm.DB) {
+	tracer, ok := childTracer(db.Statement.Context)
+	if !ok {
+		return
+	}
+	_, span := tracer.Start(db.Statement.Context, "db", trace.WithSpanKind(trace.SpanKindClient))
+	db.InstanceSet(spanKey, span)
+}
+
+// after names the statement's span after the executed SQL and ends it.
+func (GormPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	query := SanitizeSQL(db.Statement.SQL.String())
+	operation := strings.ToUpper(firstWord(query))
+	name := operation
+	if table := db.Statement.Table; table != "" {
+		name += " " + table
+		span.SetAttributes(semconv.DBCollectionName(table))
+	}
+	if name != "" {
+		span.SetName(name)
+	}
+	span.SetAttributes(
+		dbSystem(db.Dialector.Name()),
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(query),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
+
+// SanitizeSQL strips values from a query: GORM already sends values as
+// placeholders, and any string or number literals written into the SQL
+// are replaced with ? too. Long queries are truncated.
+func SanitizeSQL(query string) string {
+	query = stringLiteral.ReplaceAllString(query, "?")
+	query = numberLiteral.ReplaceAllString(query, "$1?")
+	if len(query) > maxQueryLength {
+		query = query[:maxQueryLength] + "..."
+	}
+	return query
+}
+
+// dbSystem names the database for the db.system.name attribute.
+func dbSystem(dialector string) attribute.KeyValue {
+	switch dialector {
+	case "postgres":
+		return semconv.DBSystemNamePostgreSQL
+	case "sqlite":
+		return semconv.DBSystemNameSQLite
+	}
+	return semconv.DBSystemNameKey.String(dialector)
+}
+
+// firstWord returns the SQL verb a query starts with.
+func firstWord(query string) string {
+	query = strings.TrimSpace(query)
+	if i := strings.IndexFunc(query, func(r rune) bool { return r == ' ' || r == '\n' || r == '\t' }); i >= 0 {
+		return query[:i]
+	}
+	return query
+}
@@ -0,0 +1,27 @@
+package tracing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := []struct {
+		query, want string
+	}{
+		{`SELECT * FROM "users" WHERE email = $1 LIMIT 1`, `SELECT * FROM "users" WHERE email = $1 LIMIT ?`},
+		{`SELECT * FROM users WHERE email = 'a@b.c' AND note = 'it''s'`, `SELECT * FROM users WHERE email = ? AND note = ?`},
+		{`UPDATE products SET stock = stock - 2, price = 9.99 WHERE id = ?`, `UPDATE products SET stock = stock - ?, price = ? WHERE id = ?`},
+		{`SELECT t1.col2 FROM table1 t1 WHERE x = -5`, `SELECT t1.col2 FROM table1 t1 WHERE x = ?`},
+	}
+	for _, test := range tests {
+		if got := SanitizeSQL(test.query); got != test.want {
+			t.Errorf("SanitizeSQL(%q) = %q, want %q", test.query, got, test.want)
+		}
+	}
+
+	long := SanitizeSQL("SELECT " + strings.Repeat("a", 3*maxQueryLength))
+	if len(long) != maxQueryLength+len("...") {
+		t.Errorf("long query kept %d bytes, want %d", len(long), maxQueryLength+len("..."))
+	}
+}
@@ -0,0 +1,110 @@
+// Package tracing sets up OpenTelemetry tracing. New builds a tracer
+// provider that exports spans to stdout or to an OTLP collector.
+// Middleware starts a server span for every request, continuing any trace
+// the caller sent in a W3C traceparent header. GormPlugin and Transport
+// record database queries and outgoing HTTP calls as child spans.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/TobiAdeniji94/ecommerce_api/config"
+)
+
+// instrumentationName identifies the spans this package creates.
+const instrumentationName = "github.com/TobiAdeniji94/ecommerce_api/tracing"
+
+// propagator reads and writes the W3C traceparent, tracestate and baggage
+// headers.
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Provider creates tracers and flushes buffered spans on Shutdown.
+type Provider interface {
+	trace.TracerProvider
+	Shutdown(ctx context.Context) error
+}
+
+// New returns the tracer provider selected by cfg.Exporter. With no
+// exporter, requests still get trace IDs, for logs and propagation, but
+// spans are neither recorded nor exported.
+func New(cfg config.TracingConfig) (Provider, error) {
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	options := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+
+	switch cfg.Exporter {
+	case "stdout":
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdktrace.WithSyncer(exporter), sdktrace.WithSampler(sampler(cfg.SampleRatio)))
+	case "otlp":
+		exporter, err := otlptracehttp.New(context.Background(), otlpOptions(cfg)...)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, sdktrace.WithBatcher(exporter), sdktrace.WithSampler(sampler(cfg.SampleRatio)))
+	case "", "none":
+		options = append(options, sdktrace.WithSampler(sdktrace.NeverSample()))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+
+	return sdktrace.NewTracerProvider(options...), nil
+}
+
+// sampler follows the caller's sampling decision, and samples ratio of
+// the traces that start here.
+func sampler(ratio float64) sdktrace.Sampler {
+	return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
+}
+
+// otlpOptions configures the OTLP/HTTP exporter. An http:// endpoint
+// sends spans without TLS.
+func otlpOptions(cfg config.TracingConfig) []otlptracehttp.Option {
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(cfg.OTLPEndpoint)}
+	if len(cfg.OTLPHeaders) > 0 {
+		headers := make(map[string]string, len(cfg.OTLPHeaders))
+		for _, header := range cfg.OTLPHeaders {
+			name, value, _ := strings.Cut(header, "=")
+			headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+		options = append(options, otlptracehttp.WithHeaders(headers))
+	}
+	return options
+}
+
+// TraceID returns the ID of the trace ctx belongs to, or "" when it is
+// not traced.
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// childTracer returns a tracer from the provider of ctx's span, so child
+// spans go wherever their parent goes. ok is false when ctx is not part
+// of a trace; callers then skip the span rather than start a new trace.
+func childTracer(ctx context.Context) (tracer trace.Tracer, ok bool) {
+	span := trace.SpanFromContext(ctx)
+	if !span.SpanContext().IsValid() {
+		return nil, false
+	}
+	return span.TracerProvider().Tracer(instrumentationName), true
+}
@@ -0,0 +1,17 @@
+package utils
+
+import "time"
+
+// Clock tells the time. Handlers and services take a Clock instead of
+// calling time.Now, so tests can fix the time.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the real clock.
+type SystemClock struct{}
+
+// Now returns the current time.
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
@@ -1,41 +1,81 @@
 package utils
 
 import (
-	"fmt"
-	"os"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/TobiAdeniji94/ecommerce_api/config"
 )
 
-// jwtKey to be initialized in the init() function
-var jwtKey []byte
+// ErrInvalidToken is returned for tokens that are malformed, expired or not
+// signed with the service's key.
+var ErrInvalidToken = errors.New("invalid token")
 
-// init called when this package is imported.
-func init() {
-    keyFromEnv := os.Getenv("JWT_SECRET")
-    if keyFromEnv == "" {
-        panic("JWT_SECRET environment variable is not set or is empty")
-    }
-    jwtKey = []byte(keyFromEnv)
-    fmt.Println("JWT_SECRET loaded successfully")
+// Claims identify the user a token was issued to.
+type Claims struct {
+    UserID uuid.UUID
+    Role   string
+}
+
+// TokenService issues and verifies access tokens.
+type TokenService interface {
+    Issue(userID uuid.UUID, role string) (string, error)
+    Verify(token string) (*Claims, error)
+}
+
+// JWT issues and verifies HMAC-SHA256 signed JSON Web Tokens.
+type JWT struct {
+    key   []byte
+    ttl   time.Duration
+    clock Clock
+}
+
+// NewJWT returns a token service that signs with cfg.Secret and issues
+// tokens lasting cfg.TTL.
+func NewJWT(cfg config.JWTConfig, clock Clock) *JWT {
+    return &JWT{key: []byte(cfg.Secret), ttl: cfg.TTL, clock: clock}
 }
 
-// GenerateJWT creates a new JWT token.
-func GenerateJWT(userID string, role string) (string, error) {
+// Issue creates a new token for a user.
+func (j *JWT) Issue(userID uuid.UUID, role string) (string, error) {
+    now := j.clock.Now()
+
     // Create the token with claims
     token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-        "user_id": userID,
+        "user_id": userID.String(),
         "role":    role,
-        "exp":     time.Now().Add(24 * time.Hour).Unix(),
-        "iat":     time.Now().Unix(),
+        "exp":     now.Add(j.ttl).Unix(),
+        "iat":     now.Unix(),
     })
 
     // Sign the token with our secret key
-    return token.SignedString(jwtKey)
+    return token.SignedString(j.key)
 }
 
-// GetJWTKey returns the loaded JWT key so other packages can use it.
-func GetJWTKey() []byte {
-    return jwtKey
+// Verify checks a token's signature and expiry and returns its claims.
+func (j *JWT) Verify(tokenString string) (*Claims, error) {
+    token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+        return j.key, nil
+    }, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithTimeFunc(j.clock.Now))
+    if err != nil || !token.Valid {
+        return nil, ErrInvalidToken
+    }
+
+    claims, ok := token.Claims.(jwt.MapClaims)
+    if !ok {
+        return nil, ErrInvalidToken
+    }
+    userIDStr, _ := claims["user_id"].(string)
+    userID, err := uuid.Parse(userIDStr)
+    if err != nil {
+        return nil, ErrInvalidToken
+    }
+    role, ok := claims["role"].(string)
+    if !ok {
+        return nil, ErrInvalidToken
+    }
+    return &Claims{UserID: userID, Role: role}, nil
 }
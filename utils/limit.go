@@ -7,10 +7,13 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
+
+	"github.com/TobiAdeniji94/ecommerce_api/config"
 )
 
-// PerClientRateLimiter provides rate limiting per client IP.
-func PerClientRateLimiter() gin.HandlerFunc {
+// PerClientRateLimiter provides rate limiting per client IP. rejected, if
+// not nil, is called for every request turned away.
+func PerClientRateLimiter(cfg config.RateLimitConfig, rejected func()) gin.HandlerFunc {
 	type client struct {
 		limiter  *rate.Limiter
 		lastSeen time.Time
@@ -40,12 +43,15 @@ func PerClientRateLimiter() gin.HandlerFunc {
 
 		mu.Lock()
 		if _, found := clients[ip]; !found {
-			clients[ip] = &client{limiter: rate.NewLimiter(10, 20)}
+			clients[ip] = &client{limiter: rate.NewLimiter(rate.Limit(cfg.RequestsPerSecond), cfg.Burst)}
 		}
 		clients[ip].lastSeen = time.Now()
 
 		if !clients[ip].limiter.Allow() {
 			mu.Unlock()
+			if rejected != nil {
+				rejected()
+			}
 
 			// Return 429 Too Many Requests response
 			c.JSON(http.StatusTooManyRequests, gin.H{
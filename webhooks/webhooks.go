@@ -0,0 +1,113 @@
+// Package webhooks sends signed event notifications to URLs registered by
+// admins. It consumes events from the outbox, queues a delivery for every
+// subscription in the database, and delivers them with a background worker
+// that retries failures with exponential backoff and logs every attempt.
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+	"github.com/TobiAdeniji94/ecommerce_api/outbox"
+)
+
+// Request headers sent with every delivery.
+const (
+	// SignatureHeader carries "t=<unix>,v1=<hex>", the HMAC-SHA256 of
+	// "<unix>.<body>" keyed with the subscription secret.
+	SignatureHeader = "X-Webhook-Signature"
+	// EventHeader carries the event type.
+	EventHeader = "X-Webhook-Event"
+	// DeliveryHeader carries the delivery ID, which receivers can use to
+	// ignore retries they have already processed.
+	DeliveryHeader = "X-Webhook-Delivery"
+)
+
+// Event is the JSON body of a delivery.
+type Event struct {
+	ID        uuid.UUID   `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Register queues a delivery to every active subscription for each event
+// the outbox relay publishes to bus. Deliveries are keyed by event ID, so an event the
+// outbox publishes again is not queued twice. They are queued in the relay's
+// transaction, in a savepoint so that a failure leaves it usable, and in db
+// for events published outside the relay.
+func Register(bus *outbox.Bus, db *gorm.DB) {
+	bus.Subscribe("*", func(ctx context.Context, msg outbox.Message) error {
+		if tx, ok := outbox.Tx(ctx); ok {
+			return tx.Transaction(func(savepoint *gorm.DB) error {
+				return enqueue(savepoint, msg)
+			})
+		}
+		return enqueue(db.WithContext(ctx), msg)
+	})
+}
+
+// enqueue creates the deliveries for one event.
+func enqueue(db *gorm.DB, msg outbox.Message) error {
+	var subscriptions []models.WebhookSubscription
+	if err := db.Where("active = ?", true).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	event := Event{ID: msg.ID, Type: msg.Type, CreatedAt: msg.CreatedAt.UTC(), Data: msg.Data}
+	var payload []byte
+	for _, subscription := range subscriptions {
+		if !subscription.Subscribes(msg.Type) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(event); err != nil {
+				return err
+			}
+		}
+
+		delivery := models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        string(payload),
+			Status:         models.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+		}
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&delivery).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sign returns the signature header value for payload sent at the given time.
+func Sign(payload []byte, secret string, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret returns a random signing secret.
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
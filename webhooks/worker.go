@@ -0,0 +1,219 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/TobiAdeniji94/ecommerce_api/models"
+)
+
+const (
+	// defaultPollInterval applies when the worker has no PollInterval.
+	defaultPollInterval = 5 * time.Second
+	// batchSize is the number of deliveries claimed at once.
+	batchSize = 20
+	// MaxAttempts is how often a delivery is tried before it fails.
+	MaxAttempts = 10
+	// baseRetryDelay doubles after every failed attempt, up to maxRetryDelay.
+	baseRetryDelay = 30 * time.Second
+	maxRetryDelay  = 6 * time.Hour
+)
+
+// defaultClient sends deliveries for workers without a Client.
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// Worker delivers queued webhooks. Client's timeout bounds each delivery.
+type Worker struct {
+	DB           *gorm.DB
+	Client       *http.Client
+	PollInterval time.Duration
+}
+
+// errSubscriptionInactive fails deliveries whose subscription was disabled
+// or deleted after they were queued.
+var errSubscriptionInactive = errors.New("subscription is inactive or deleted")
+
+// Run delivers queued webhooks until ctx is canceled.
+func (w *Worker) Run(ctx context.Context) {
+	pollInterval := w.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep going while full batches come back, then wait for more
+		for {
+			processed, err := w.DeliverDue(ctx)
+			if err != nil {
+				slog.Error("Failed to deliver webhooks", "error", err)
+				break
+			}
+			if processed < batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue sends a batch of due deliveries and returns how many were
+// attempted. Deliveries are claimed by pushing their next attempt past the
+// request timeout, so several API instances can run workers and a delivery
+// interrupted by a crash is retried once the claim lapses.
+func (w *Worker) DeliverDue(ctx context.Context) (int, error) {
+	var due []models.WebhookDelivery
+	err := w.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+			Order("next_attempt_at").Limit(batchSize).Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		ids := make([]interface{}, len(due))
+		for i := range due {
+			ids[i] = due[i].ID
+		}
+		claim := now.Add(w.client().Timeout + time.Minute)
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", claim).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	subscriptions := make(map[string]*models.WebhookSubscription)
+	for i := range due {
+		// Claimed deliveries left behind are retried when the claim lapses
+		if ctx.Err() != nil {
+			return i, nil
+		}
+
+		delivery := &due[i]
+		key := delivery.SubscriptionID.String()
+		subscription, ok := subscriptions[key]
+		if !ok {
+			var loaded models.WebhookSubscription
+			if err := w.DB.Where("id = ? AND active = ?", delivery.SubscriptionID, true).Limit(1).Find(&loaded).Error; err != nil {
+				return i, err
+			}
+			if loaded.URL != "" {
+				subscription = &loaded
+			}
+			subscriptions[key] = subscription
+		}
+
+		if err := w.deliver(ctx, delivery, subscription); err != nil {
+			return i, err
+		}
+	}
+	return len(due), nil
+}
+
+// deliver sends one delivery to its subscription and records the attempt
+// and its outcome. A nil subscription fails the delivery.
+func (w *Worker) deliver(ctx context.Context, delivery *models.WebhookDelivery, subscription *models.WebhookSubscription) error {
+	attempt := models.WebhookAttempt{DeliveryID: delivery.ID}
+	started := time.Now()
+	var err error
+	if subscription == nil {
+		err = errSubscriptionInactive
+	} else {
+		attempt.StatusCode, err = w.send(ctx, delivery, subscription)
+	}
+	attempt.DurationMS = time.Since(started).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastStatusCode = attempt.StatusCode
+	delivery.LastError = attempt.Error
+	updates := map[string]interface{}{
+		"attempts":         delivery.Attempts,
+		"last_status_code": delivery.LastStatusCode,
+		"last_error":       delivery.LastError,
+	}
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+		updates["delivered_at"] = now
+	case delivery.Attempts >= MaxAttempts || errors.Is(err, errSubscriptionInactive):
+		delivery.Status = models.WebhookDeliveryFailed
+		slog.Error("Webhook delivery failed", "delivery_id", delivery.ID, "attempts", delivery.Attempts, "error", err)
+	default:
+		delivery.NextAttemptAt = now.Add(retryDelay(delivery.Attempts))
+		updates["next_attempt_at"] = delivery.NextAttemptAt
+	}
+	updates["status"] = delivery.Status
+
+	return w.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&attempt).Error; err != nil {
+			return err
+		}
+		return tx.Model(delivery).Updates(updates).Error
+	})
+}
+
+// send posts the signed payload and returns the response status. Any
+// status other than 2xx is an error.
+func (w *Worker) send(ctx context.Context, delivery *models.WebhookDelivery, subscription *models.WebhookSubscription) (int, error) {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ecommerce-api-webhooks/1.0")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(SignatureHeader, Sign(payload, subscription.Secret, time.Now()))
+
+	resp, err := w.client().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, errors.New("receiver responded with status " + strconv.Itoa(resp.StatusCode))
+	}
+	return resp.StatusCode, nil
+}
+
+// client returns the HTTP client deliveries are sent with.
+func (w *Worker) client() *http.Client {
+	if w.Client != nil {
+		return w.Client
+	}
+	return defaultClient
+}
+
+// retryDelay returns how long to wait after the given number of failed
+// attempts.
+func retryDelay(attempts int) time.Duration {
+	delay := baseRetryDelay << (attempts - 1)
+	if delay > maxRetryDelay || delay <= 0 {
+		return maxRetryDelay
+	}
+	return delay
+}